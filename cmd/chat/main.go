@@ -1,13 +1,25 @@
 package main
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
 	"log"
+	"os"
+	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/kontentski/chat/internal/auth"
+	"github.com/kontentski/chat/internal/config"
 	"github.com/kontentski/chat/internal/database"
+	"github.com/kontentski/chat/internal/handlers"
+	"github.com/kontentski/chat/internal/jobs"
+	"github.com/kontentski/chat/internal/mailer"
+	"github.com/kontentski/chat/internal/ratelimit"
 	"github.com/kontentski/chat/internal/router"
 	"github.com/kontentski/chat/internal/services"
+	"github.com/kontentski/chat/internal/signedtoken"
 	"github.com/kontentski/chat/internal/storage"
 )
 
@@ -21,6 +33,10 @@ import (
 //@in header
 //@name Cookie
 //@description Session cookie for authentication. you need to manually add the session to the Cookie storage in your browser
+//@securityDefinitions.apikey BearerAuth
+//@in header
+//@name Authorization
+//@description Personal access token, sent as "Bearer chat_pat_..."
 
 func main() {
 	//dependencies
@@ -30,12 +46,52 @@ func main() {
 
 	//repositories and services
 	userRepo := &storage.PostgresRepository{DB: database.DB}
-	authRepo := &storage.RealAuth{}
+	authRepo := &storage.RealAuth{Store: auth.Store}
 	bucketStorage := &storage.GoogleUpload{}
-	userService := services.NewUserChatRoomService(userRepo, authRepo, bucketStorage)
+	sendLimiter := ratelimit.New(config.Float("SEND_RATE_PER_SECOND", 1), config.Int("SEND_RATE_BURST", 5))
+	loginGuard := services.LoginGuard{
+		Accounts: ratelimit.NewLockout(config.Int("LOGIN_MAX_FAILURES", 5), config.Duration("LOGIN_LOCKOUT", time.Minute), config.Duration("LOGIN_LOCKOUT_MAX", time.Hour)),
+		IPs:      ratelimit.NewLockout(config.Int("LOGIN_IP_MAX_FAILURES", 20), config.Duration("LOGIN_LOCKOUT", time.Minute), config.Duration("LOGIN_LOCKOUT_MAX", time.Hour)),
+		Codes:    ratelimit.NewLockout(config.Int("TOTP_MAX_FAILURES", 5), config.Duration("LOGIN_LOCKOUT", time.Minute), config.Duration("LOGIN_LOCKOUT_MAX", time.Hour)),
+	}
+	twoFactor := services.TwoFactorConfig{
+		Issuer:   config.String("TOTP_ISSUER", "Chat"),
+		Required: config.Bool("REQUIRE_2FA", false),
+	}
+	accountMail := services.AccountMailConfig{
+		Mailer:    newMailer(),
+		Signer:    signedtoken.New(tokenSecret()),
+		BaseURL:   config.String("APP_BASE_URL", "http://localhost:8080"),
+		ResetTTL:  config.Duration("PASSWORD_RESET_TTL", time.Hour),
+		VerifyTTL: config.Duration("EMAIL_VERIFY_TTL", 24*time.Hour),
+		Limiter:   ratelimit.New(config.Float("MAIL_RATE_PER_SECOND", 1.0/60), config.Int("MAIL_RATE_BURST", 3)),
+	}
+	userService := services.NewUserChatRoomService(userRepo, authRepo, bucketStorage, sendLimiter, loginGuard, twoFactor, accountMail)
+
+	//background jobs
+	go handlers.HandleMessages(userService)
+	retentionDays := config.Int("MESSAGE_RETENTION_DAYS", 0)
+	go jobs.Every(context.Background(), config.Duration("RETENTION_INTERVAL", time.Hour), "retention", func(ctx context.Context) error {
+		_, err := userService.PurgeExpiredMessages(ctx, retentionDays)
+		return err
+	})
+	go jobs.Every(context.Background(), config.Duration("SCHEDULED_DISPATCH_INTERVAL", 5*time.Second), "scheduled-messages", func(ctx context.Context) error {
+		return handlers.DispatchScheduledMessages(ctx, userRepo)
+	})
+	go jobs.Every(context.Background(), config.Duration("MESSAGE_EXPIRY_INTERVAL", 10*time.Second), "message-expiry", func(ctx context.Context) error {
+		return handlers.ExpireMessages(ctx, userService)
+	})
+	go jobs.Every(context.Background(), config.Duration("SESSION_CLEANUP_INTERVAL", time.Hour), "session-cleanup", func(ctx context.Context) error {
+		_, err := userRepo.DeleteExpiredSessions(ctx)
+		return err
+	})
 
 	//router
+	handlers.AllowedOrigins = config.List("ALLOWED_ORIGINS", nil)
 	r := router.NewRouter(userService)
+	if err := r.SetTrustedProxies(config.List("TRUSTED_PROXIES", nil)); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 	r.SetupRoutes()
 
 	// Start the server
@@ -44,3 +100,40 @@ func main() {
 		log.Fatalf("Could not start server: %v", err)
 	}
 }
+
+// newMailer sends email through SMTP_ADDR when it is set, and otherwise writes it to files in MAIL_DIR.
+func newMailer() mailer.Mailer {
+	from := config.String("MAIL_FROM", "chat@localhost")
+	if addr := config.String("SMTP_ADDR", ""); addr != "" {
+		return &mailer.SMTPMailer{
+			Addr:     addr,
+			From:     from,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		}
+	}
+	dir := config.String("MAIL_DIR", "mail")
+	log.Printf("SMTP_ADDR is not set, emails are written to %s", dir)
+	return &mailer.FileMailer{Dir: dir, From: from}
+}
+
+// tokenSecret returns the key email links are signed with. Without TOKEN_SECRET the key is derived
+// from CookieSecret with a labelled HMAC, so a signature made for email links is never valid as
+// anything signed with the cookie secret itself. Without either a random key is used, and links
+// stop working when the server restarts.
+func tokenSecret() []byte {
+	if secret := config.String("TOKEN_SECRET", ""); secret != "" {
+		return []byte(secret)
+	}
+	if cookieSecret := os.Getenv("CookieSecret"); cookieSecret != "" {
+		mac := hmac.New(sha256.New, []byte(cookieSecret))
+		mac.Write([]byte("chat email link tokens v1"))
+		return mac.Sum(nil)
+	}
+	log.Println("Warning: TOKEN_SECRET is not set, using a random key for email links")
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		log.Fatalf("Could not generate a token key: %v", err)
+	}
+	return key
+}
@@ -0,0 +1,32 @@
+document.addEventListener("DOMContentLoaded", function () {
+  const token = new URLSearchParams(window.location.search).get("token");
+  const errorMessageDiv = document.getElementById("error-message");
+
+  document
+    .getElementById("reset-form")
+    .addEventListener("submit", async function (event) {
+      event.preventDefault();
+      errorMessageDiv.textContent = "";
+
+      const password = document.getElementById("password").value;
+
+      try {
+        const response = await fetch("/auth/reset-password", {
+          method: "POST",
+          body: JSON.stringify({ token: token, password: password }),
+          headers: { "Content-Type": "application/json" },
+        });
+
+        if (response.ok) {
+          // Every session was signed out, so sign in again with the new password
+          window.location.href = "/homepage";
+          return;
+        }
+        const data = await response.json();
+        errorMessageDiv.textContent = data.error;
+      } catch (error) {
+        console.error("Error:", error);
+        errorMessageDiv.textContent = "An error occurred. Please try again.";
+      }
+    });
+});
@@ -0,0 +1,22 @@
+document.addEventListener("DOMContentLoaded", async function () {
+  const token = new URLSearchParams(window.location.search).get("token");
+  const status = document.getElementById("status");
+
+  try {
+    const response = await fetch("/auth/verify-email", {
+      method: "POST",
+      body: JSON.stringify({ token: token }),
+      headers: { "Content-Type": "application/json" },
+    });
+
+    if (response.ok) {
+      status.textContent = "Your email address is verified.";
+      return;
+    }
+    const data = await response.json();
+    status.textContent = data.error;
+  } catch (error) {
+    console.error("Error:", error);
+    status.textContent = "An error occurred. Please try again.";
+  }
+});
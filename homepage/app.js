@@ -4,6 +4,7 @@ let socket;
 let currentChatRoomID = null;
 let pingInterval = 60000; // 60 seconds
 let titleInterval = null;
+let sessionRevoked = false;
 
 const notificationSound = new Audio("assets/notification.mp3");
 const chatRoomList = document.getElementById("chat-room-list-items");
@@ -81,7 +82,18 @@ function connectWebSocket() {
 			handleUserID(data);
 		} else if (Array.isArray(data)) {
 			handleChatRooms(data);
-		} else if (data.type === "delete") {
+		} else if (data.type === "session_revoked") {
+			// Signed out from another device, do not reconnect with the dead session
+			sessionRevoked = true;
+			window.location.href = "/homepage";
+		} else if (data.type === "profile_updated") {
+			handleProfileUpdated(data.data);
+		} else if (data.type === "status_updated") {
+			console.log("Status updated:", data.data);
+		} else if (data.type === "notification") {
+			// The server leaves these out while the user is in do-not-disturb
+			handleNotification(data);
+		} else if (data.type === "delete" || data.type === "expired") {
 			handleDeleteMessage(data.message_id, data.chat_room_id);
 		} else if (data.type === "image" || data.type === "video") {
 			handleMediaMessage(data);
@@ -95,7 +107,9 @@ function connectWebSocket() {
 	socket.onclose = function (event) {
 		console.log("WebSocket connection closed", event);
 		clearInterval(pingInterval); // Clear ping interval
-		reconnectWebSocket(); // Attempt to reconnect
+		if (!sessionRevoked) {
+			reconnectWebSocket(); // Attempt to reconnect
+		}
 	};
 
 	socket.onerror = function (error) {
@@ -148,6 +162,22 @@ function handleUserID(data) {
 	fetchChatRooms();
 }
 
+// Show the new name of a user whose profile changed
+function handleProfileUpdated(profile) {
+	if (profile.id === userID) {
+		document.getElementById(
+			"user-username"
+		).textContent = `Username: ${profile.username}`;
+		document.getElementById("user-name").textContent = `Name: ${profile.name}`;
+		return;
+	}
+	document
+		.querySelectorAll(`.other-message[data-sender-id="${profile.id}"] .sender-name`)
+		.forEach((element) => {
+			element.textContent = profile.name;
+		});
+}
+
 // Handle chat rooms data
 function handleChatRooms(chatRooms) {
 	chatRoomList.innerHTML = "";
@@ -248,15 +278,18 @@ function handleChatRooms(chatRooms) {
 // Handle incoming message for the current chat room
 function handleIncomingMessage(message) {
 	appendMessageToChatBox(message);
-	if (document.hidden) {
-		playNotificationSound();
-		startFlashingTitle();
-	}
 }
 
 // Handle incoming messages for other chat rooms
 function handleOtherChatRoomMessages(message) {
 	console.log("Message received for another chat room:", message);
+}
+
+// Alert the user about a direct message or a mention
+function handleNotification(notification) {
+	if (notification.chat_room_id === currentChatRoomID && !document.hidden) {
+		return;
+	}
 	playNotificationSound();
 	if (document.hidden) {
 		startFlashingTitle();
@@ -432,6 +465,7 @@ function appendMessageToChatBox(message) {
     messageElement.className = "message";
     messageElement.dataset.messageId = message.message_id;
     messageElement.dataset.readAt = message.read_at;
+    messageElement.dataset.senderId = message.sender_id;
 
     // Check if the message is sent by the user
     const isUserMessage = message.sender_id === userID;
@@ -469,7 +503,10 @@ function appendMessageToChatBox(message) {
     } else {
         // Only show the sender's name for messages not sent by the user
         if (!isUserMessage) {
-            messageElement.textContent = `${message.sender.name}: ${message.content}`;
+            const senderName = document.createElement("span");
+            senderName.className = "sender-name";
+            senderName.textContent = message.sender.name;
+            messageElement.append(senderName, `: ${message.content}`);
         } else {
             messageElement.textContent = message.content; // Just show the content for user messages
         }
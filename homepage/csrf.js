@@ -0,0 +1,43 @@
+// Adds the session's CSRF token to every state-changing fetch. The server rejects POST, PUT,
+// PATCH and DELETE requests made with the session cookie unless they carry the token.
+(function () {
+  const unsafeMethods = ["POST", "PUT", "PATCH", "DELETE"];
+  const originalFetch = window.fetch.bind(window);
+
+  function readCookie(name) {
+    const match = document.cookie.match(new RegExp("(?:^|; )" + name + "=([^;]*)"));
+    return match ? decodeURIComponent(match[1]) : "";
+  }
+
+  async function csrfToken() {
+    const token = readCookie("csrf_token");
+    if (token) {
+      return token;
+    }
+    // Sessions from before CSRF protection have no token cookie yet
+    const response = await originalFetch("/auth/csrf", { credentials: "same-origin" });
+    if (!response.ok) {
+      return "";
+    }
+    const data = await response.json();
+    return data.csrf_token || "";
+  }
+
+  window.fetch = async function (input, init = {}) {
+    const method = (init.method || (input instanceof Request ? input.method : "GET")).toUpperCase();
+    if (!unsafeMethods.includes(method)) {
+      return originalFetch(input, init);
+    }
+    const url = new URL(input instanceof Request ? input.url : input, window.location.href);
+    if (url.origin !== window.location.origin) {
+      return originalFetch(input, init);
+    }
+    const token = await csrfToken();
+    if (token) {
+      const headers = new Headers(init.headers || (input instanceof Request ? input.headers : undefined));
+      headers.set("X-CSRF-Token", token);
+      init = { ...init, headers };
+    }
+    return originalFetch(input, init);
+  };
+})();
@@ -0,0 +1,58 @@
+document.addEventListener("DOMContentLoaded", async function () {
+  const setup = new URLSearchParams(window.location.search).has("setup");
+  const errorMessageDiv = document.getElementById("error-message");
+
+  if (setup) {
+    // Start enrolling an authenticator
+    try {
+      const response = await fetch("/api/account/2fa/enroll", { method: "POST" });
+      const data = await response.json();
+      if (!response.ok) {
+        errorMessageDiv.textContent = data.error;
+        return;
+      }
+      document.getElementById("setup-uri").href = data.uri;
+      document.getElementById("setup-secret").textContent = data.secret;
+      document.getElementById("setup").style.display = "block";
+    } catch (error) {
+      console.error("Error:", error);
+      errorMessageDiv.textContent = "An error occurred. Please try again.";
+    }
+  }
+
+  document
+    .getElementById("code-form")
+    .addEventListener("submit", async function (event) {
+      event.preventDefault();
+      errorMessageDiv.textContent = "";
+
+      const code = document.getElementById("code").value;
+      const url = setup ? "/api/account/2fa/confirm" : "/auth/2fa";
+
+      try {
+        const response = await fetch(url, {
+          method: "POST",
+          body: JSON.stringify({ code: code }),
+          headers: { "Content-Type": "application/json" },
+        });
+        const data = await response.json();
+
+        if (!response.ok) {
+          errorMessageDiv.textContent = data.error;
+          return;
+        }
+        if (setup) {
+          // Show the recovery codes once before continuing
+          document.getElementById("code-form").style.display = "none";
+          document.getElementById("setup").style.display = "none";
+          document.getElementById("recovery-codes").textContent = data.recovery_codes.join("\n");
+          document.getElementById("recovery").style.display = "block";
+          return;
+        }
+        window.location.href = "/homepage";
+      } catch (error) {
+        console.error("Error:", error);
+        errorMessageDiv.textContent = "An error occurred. Please try again.";
+      }
+    });
+});
@@ -6,19 +6,24 @@ import (
 	"os"
 
 	"github.com/joho/godotenv"
+	"github.com/kontentski/chat/internal/config"
+	"github.com/kontentski/chat/internal/database"
 	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/storage"
 
-	"github.com/gorilla/sessions"
 	"github.com/markbates/goth"
 	"github.com/markbates/goth/gothic"
+	"github.com/markbates/goth/providers/github"
+	"github.com/markbates/goth/providers/gitlab"
 	"github.com/markbates/goth/providers/google"
+	"github.com/markbates/goth/providers/openidConnect"
 )
 
 const (
 	MaxAge = 86400 * 30
 )
 
-var Store *sessions.CookieStore
+var Store *storage.SessionStore
 
 func Init() {
 	err := godotenv.Load()
@@ -33,13 +38,13 @@ func Init() {
 
 	gob.Register(models.Users{})
 
-	Store = sessions.NewCookieStore([]byte(CookieSecret))
+	Store = storage.NewSessionStore(&storage.PostgresRepository{DB: database.DB}, []byte(CookieSecret))
 	Store.MaxAge(MaxAge)
 	Store.Options.Path = "/"
 
 	gothic.Store = Store
 
-	goth.UseProviders(
+	providers := []goth.Provider{
 		google.New(
 			googleClientId,
 			googleClientSecret,
@@ -47,5 +52,41 @@ func Init() {
 			"profile",
 			"email",
 		),
-	)
+	}
+	goth.UseProviders(append(providers, optionalProviders()...)...)
+}
+
+// optionalProviders returns the GitHub, GitLab and OpenID Connect providers that have a client ID configured.
+func optionalProviders() []goth.Provider {
+	var providers []goth.Provider
+
+	if clientID := os.Getenv("GITHUB_CLIENT_ID"); clientID != "" {
+		providers = append(providers, github.New(clientID, os.Getenv("GITHUB_CLIENT_SECRET"), os.Getenv("GITHUB_CALLBACK_URL"), "read:user", "user:email"))
+	}
+
+	if clientID := os.Getenv("GITLAB_CLIENT_ID"); clientID != "" {
+		secret, callback := os.Getenv("GITLAB_CLIENT_SECRET"), os.Getenv("GITLAB_CALLBACK_URL")
+		// GITLAB_URL points at a self-hosted instance, gitlab.com is used when it is unset
+		if baseURL := os.Getenv("GITLAB_URL"); baseURL != "" {
+			providers = append(providers, gitlab.NewCustomisedURL(clientID, secret, callback,
+				baseURL+"/oauth/authorize", baseURL+"/oauth/token", baseURL+"/api/v4/user", "read_user"))
+		} else {
+			providers = append(providers, gitlab.New(clientID, secret, callback, "read_user"))
+		}
+	}
+
+	if clientID := os.Getenv("OIDC_CLIENT_ID"); clientID != "" {
+		// The provider fetches its endpoints from the discovery URL, so a misconfigured or unreachable
+		// issuer only disables this provider.
+		provider, err := openidConnect.NewNamed(config.String("OIDC_NAME", "openid-connect"), clientID,
+			os.Getenv("OIDC_CLIENT_SECRET"), os.Getenv("OIDC_CALLBACK_URL"), os.Getenv("OIDC_DISCOVERY_URL"),
+			"openid", "profile", "email")
+		if err != nil {
+			log.Printf("OpenID Connect provider disabled: %v", err)
+		} else {
+			providers = append(providers, provider)
+		}
+	}
+
+	return providers
 }
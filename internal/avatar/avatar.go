@@ -0,0 +1,102 @@
+// Package avatar turns uploaded pictures into square avatars of standard sizes.
+package avatar
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif" // register the decoders of the accepted formats
+	_ "image/jpeg"
+	"image/png"
+	"io"
+)
+
+// Sizes are the edge lengths, in pixels, of the avatars made from each upload.
+var Sizes = []int{32, 64, 128, 256}
+
+// MaxPixels is the largest picture Decode accepts, so a small file cannot expand into a huge image.
+const MaxPixels = 16 << 20
+
+var (
+	ErrUnsupportedFormat = errors.New("avatars must be JPEG, PNG or GIF images")
+	ErrTooManyPixels     = errors.New("avatar image dimensions are too large")
+)
+
+// Decode reads a JPEG, PNG or GIF picture, the first frame of animated GIFs.
+func Decode(r io.Reader) (image.Image, error) {
+	var buf bytes.Buffer
+	config, _, err := image.DecodeConfig(io.TeeReader(r, &buf))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+	img, _, err := image.Decode(io.MultiReader(&buf, r))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+	return img, nil
+}
+
+// Square crops the largest centered square out of img.
+func Square(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	origin := image.Pt(bounds.Min.X+(bounds.Dx()-side)/2, bounds.Min.Y+(bounds.Dy()-side)/2)
+
+	square := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(square, square.Bounds(), img, origin, draw.Src)
+	return square
+}
+
+// Resize scales the square src to size × size pixels. Each pixel of the result is the average of
+// the pixels it covers in src, which keeps downscaled avatars smooth.
+func Resize(src *image.RGBA, size int) *image.RGBA {
+	side := src.Bounds().Dx()
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		y0, y1 := span(y, side, size)
+		for x := 0; x < size; x++ {
+			x0, x1 := span(x, side, size)
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					for i := range sum {
+						sum[i] += int(row[sx*4+i])
+					}
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			offset := y*dst.Stride + x*4
+			for i := range sum {
+				dst.Pix[offset+i] = uint8(sum[i] / n)
+			}
+		}
+	}
+	return dst
+}
+
+// span returns the range of source pixels that destination pixel i covers, at least one pixel wide.
+func span(i, side, size int) (int, int) {
+	start := i * side / size
+	end := (i + 1) * side / size
+	if end <= start {
+		end = start + 1
+	}
+	return start, end
+}
+
+// Encode writes img as a PNG.
+func Encode(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,75 @@
+package avatar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	decoded, err := Decode(bytes.NewReader(encodePNG(t, img)))
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 3, 2), decoded.Bounds())
+
+	_, err = Decode(strings.NewReader("<svg></svg>"))
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestDecodeRejectsHugeDimensions(t *testing.T) {
+	// Only the header is read, so the pixel data can be missing
+	data := encodePNG(t, image.NewGray(image.Rect(0, 0, 1, 1)))
+	// Patch the IHDR width and height to 8192 x 8192 and fix up its checksum
+	copy(data[16:24], []byte{0, 0, 0x20, 0, 0, 0, 0x20, 0})
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+	_, err := Decode(bytes.NewReader(data))
+	assert.ErrorIs(t, err, ErrTooManyPixels)
+}
+
+func TestSquareCropsCenter(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	red := color.RGBA{R: 255, A: 255}
+	img.Set(1, 0, red)
+	img.Set(2, 1, red)
+
+	square := Square(img)
+	assert.Equal(t, image.Rect(0, 0, 2, 2), square.Bounds())
+	assert.Equal(t, red, square.RGBAAt(0, 0))
+	assert.Equal(t, red, square.RGBAAt(1, 1))
+	assert.Equal(t, color.RGBA{}, square.RGBAAt(1, 0))
+}
+
+func TestResizeAverages(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			if (x+y)%2 == 0 {
+				src.Set(x, y, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+			} else {
+				src.Set(x, y, color.RGBA{A: 255})
+			}
+		}
+	}
+
+	small := Resize(src, 2)
+	assert.Equal(t, image.Rect(0, 0, 2, 2), small.Bounds())
+	assert.Equal(t, color.RGBA{R: 100, G: 50, B: 25, A: 255}, small.RGBAAt(1, 1))
+
+	large := Resize(src, 8)
+	assert.Equal(t, image.Rect(0, 0, 8, 8), large.Bounds())
+	assert.Equal(t, src.RGBAAt(0, 0), large.RGBAAt(1, 1))
+	assert.Equal(t, src.RGBAAt(3, 3), large.RGBAAt(7, 7))
+}
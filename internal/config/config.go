@@ -0,0 +1,90 @@
+// Package config reads optional settings from the environment, falling back to defaults.
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// String returns the value of the environment variable key, or fallback if it is unset.
+func String(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+// Int returns the environment variable key parsed as an int, or fallback if it is unset or invalid.
+func Int(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid value for %s: %v, using %d", key, err, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// Float returns the environment variable key parsed as a float64, or fallback if it is unset or invalid.
+func Float(key string, fallback float64) float64 {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Invalid value for %s: %v, using %v", key, err, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// Duration returns the environment variable key parsed with time.ParseDuration, or fallback if it is unset or invalid.
+func Duration(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid value for %s: %v, using %s", key, err, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// Bool returns the environment variable key parsed with strconv.ParseBool, or fallback if it is unset or invalid.
+func Bool(key string, fallback bool) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid value for %s: %v, using %t", key, err, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// List returns the comma-separated values of the environment variable key, trimmed and without
+// empty entries, or fallback if it is unset.
+func List(key string, fallback []string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -3,12 +3,11 @@ package handlers
 import (
 	"log"
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kontentski/chat/internal/auth"
 	"github.com/kontentski/chat/internal/models"
-	"github.com/kontentski/chat/internal/storage"
+	"github.com/kontentski/chat/internal/services"
 	"github.com/markbates/goth/gothic"
 )
 
@@ -22,61 +21,112 @@ func AuthHandler(c *gin.Context) {
 	gothic.BeginAuthHandler(c.Writer, c.Request)
 }
 
-// CallbackHandler handles the callback from the provider
-func CallbackHandler(c *gin.Context) {
-	userGoth, err := gothic.CompleteUserAuth(c.Writer, c.Request)
-	if err != nil {
-		log.Println("Authentication failed:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
+// completeUserAuth finishes the provider round trip, it is replaced in tests.
+var completeUserAuth = gothic.CompleteUserAuth
 
-	user := &models.Users{
-		Username:       userGoth.NickName,
-		Name:           userGoth.Name,
-		Email:          userGoth.Email,
-		ProfilePicture: userGoth.AvatarURL,
-		LastSeen:       time.Now(),
-	}
+// CallbackHandler handles the callback from the provider. It either signs the user in with the
+// provider identity or, when the round trip was started by LinkProviderHandler, links it to the
+// signed-in user.
+func CallbackHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userGoth, err := completeUserAuth(c.Writer, c.Request)
+		if err != nil {
+			log.Println("Authentication failed:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 
-	// Save or update the user in the database
-	savedUser, err := storage.SaveUser(user)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save user to the database"})
-		return
-	}
+		identity := &models.UserIdentity{
+			Provider:       userGoth.Provider,
+			ProviderUserID: userGoth.UserID,
+			Email:          userGoth.Email,
+		}
 
-	// Create the session if the user has a proper password
-	session, err := auth.Store.Get(c.Request, "auth-session")
-	if err != nil {
-		log.Println("Error getting session:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Session error"})
-		return
-	}
+		session, err := auth.Store.Get(c.Request, "auth-session")
+		if err != nil {
+			log.Println("Error getting session:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Session error"})
+			return
+		}
 
-	session.Values["userID"] = savedUser.ID
-	session.Values["username"] = savedUser.Username
-	if err := session.Save(c.Request, c.Writer); err != nil {
-		log.Println("Error saving session:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Session save error"})
-		return
-	}
+		if linkUserID, ok := session.Values[linkUserIDKey].(uint); ok {
+			delete(session.Values, linkUserIDKey)
+			if err := session.Save(c.Request, c.Writer); err != nil {
+				log.Println("Error saving session:", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Session save error"})
+				return
+			}
+			if userID, _ := session.Values[services.UserIDKey].(uint); userID != linkUserID {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+				return
+			}
 
-	// Check if the saved user's password is "system_default"
-	if savedUser.Password == "system_default" {
-		// Redirect to /register if the user has the default password
-		c.Redirect(http.StatusFound, "/auth/register")
-		return
+			identity.UserID = linkUserID
+			if err := service.LinkIdentity(c.Request.Context(), identity); err != nil {
+				c.JSON(identityErrorStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+			c.Redirect(http.StatusFound, "/homepage")
+			return
+		}
+
+		user := &models.Users{
+			Name:           userGoth.Name,
+			Email:          userGoth.Email,
+			ProfilePicture: userGoth.AvatarURL,
+		}
+
+		// Find the user linked to the identity, or create one
+		savedUser, err := service.OAuthLogin(c.Request.Context(), identity, user)
+		if err != nil {
+			log.Println("OAuth login failed:", err)
+			c.JSON(identityErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		// Create the session, or wait for the two-factor code
+		state, err := startAuthSession(c, service, savedUser)
+		if err != nil {
+			log.Println("Error saving session:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Session save error"})
+			return
+		}
+		if state == services.TwoFactorPending {
+			c.Redirect(http.StatusFound, "/homepage/two-factor/two-factor.html")
+			return
+		}
+
+		// Check if the saved user's password is "system_default"
+		if savedUser.Password == "system_default" {
+			// Redirect to /register if the user has the default password
+			c.Redirect(http.StatusFound, "/auth/register")
+			return
+		}
+		if state == services.TwoFactorSetup {
+			c.Redirect(http.StatusFound, "/homepage/two-factor/two-factor.html?setup=1")
+			return
+		}
+		// Redirect to /home after successful login
+		c.Redirect(http.StatusFound, "/homepage")
 	}
-	// Redirect to /home after successful login
-	c.Redirect(http.StatusFound, "/homepage")
 }
 
 
 func LogoutHandler(c *gin.Context) {
 	session, _ := auth.Store.Get(c.Request, "auth-session")
+	userID, _ := session.Values[services.UserIDKey].(uint)
+	sessionID, _ := session.Values[services.SessionIDKey].(uint)
 	session.Options.MaxAge = -1 
 	session.Save(c.Request, c.Writer)
+
+	// Logging out deletes the session on the server, so its WebSocket connections are closed too
+	if userID != 0 && sessionID != 0 {
+		Broadcast <- models.Messages{
+			Type:        models.EventSessionRevoked,
+			RecipientID: userID,
+			Data:        []uint{sessionID},
+		}
+	}
 	c.Redirect(http.StatusFound, "/homepage")
 }
 
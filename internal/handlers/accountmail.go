@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+)
+
+// ForgotPasswordHandler godoc
+//	@Summary		Request a password reset email
+//	@Description	Emails a password reset link to the account with the address. The response is the same whether or not the address belongs to an account.
+//	@Tags			auth
+//	@Accept			json
+//	@Accept			x-www-form-urlencoded
+//	@Produce		json
+//	@Param			request	body		services.ForgotPasswordRequest	true	"Email address"
+//	@Success		202		{object}	map[string]interface{}
+//	@Failure		400		{object}	map[string]interface{}	"Missing email"
+//	@Failure		503		{object}	map[string]interface{}	"Email is not configured"
+//	@Failure		500		{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/auth/forgot-password [post]
+func ForgotPasswordHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := service.ForgotPassword(c); err != nil {
+			c.JSON(accountMailErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"message": "If the address belongs to an account, a reset link is on its way"})
+	}
+}
+
+// ResetPasswordHandler godoc
+//	@Summary		Reset a password
+//	@Description	Sets a new password with the token from a reset email. The link works once, every session of the user is signed out and their access tokens are revoked.
+//	@Tags			auth
+//	@Accept			json
+//	@Accept			x-www-form-urlencoded
+//	@Produce		json
+//	@Param			request	body	services.ResetPasswordRequest	true	"Token and new password"
+//	@Success		204
+//	@Failure		400	{object}	map[string]interface{}	"Invalid or expired link, or weak password"
+//	@Failure		500	{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/auth/reset-password [post]
+func ResetPasswordHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reset, err := service.ResetPassword(c)
+		if err != nil {
+			c.JSON(accountMailErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		credentialsRevoked(reset)
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// VerifyEmailHandler godoc
+//	@Summary		Verify an email address
+//	@Description	Marks the address of a verification email as verified, with the token from its link.
+//	@Tags			auth
+//	@Accept			json
+//	@Accept			x-www-form-urlencoded
+//	@Produce		json
+//	@Param			request	body	services.VerifyEmailRequest	true	"Token"
+//	@Success		204
+//	@Failure		400	{object}	map[string]interface{}	"Invalid or expired link"
+//	@Failure		500	{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/auth/verify-email [post]
+func VerifyEmailHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := service.VerifyEmail(c); err != nil {
+			c.JSON(accountMailErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// SendVerificationEmailHandler godoc
+//	@Summary		Send a verification email
+//	@Description	Emails the current user a link that verifies their address.
+//	@Tags			account
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Success		202	{object}	map[string]interface{}
+//	@Failure		409	{object}	map[string]interface{}	"Email is already verified"
+//	@Failure		429	{object}	map[string]interface{}	"Too many emails sent"
+//	@Failure		503	{object}	map[string]interface{}	"Email is not configured"
+//	@Failure		500	{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/account/verify-email [post]
+func SendVerificationEmailHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := service.SendVerificationEmail(c); err != nil {
+			var rateErr *services.RateLimitError
+			if errors.As(err, &rateErr) {
+				c.Header("Retry-After", strconv.Itoa(rateErr.RetryAfterSeconds()))
+				c.JSON(http.StatusTooManyRequests, gin.H{"error": rateErr.Error(), "retry_after": rateErr.RetryAfterSeconds()})
+				return
+			}
+			c.JSON(accountMailErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"message": "Verification email sent"})
+	}
+}
+
+// credentialsRevoked closes the WebSocket connections of the sessions and access tokens a password
+// change revoked.
+func credentialsRevoked(reset *services.PasswordReset) {
+	if len(reset.Sessions) > 0 {
+		Broadcast <- models.Messages{
+			Type:        models.EventSessionRevoked,
+			RecipientID: reset.UserID,
+			Data:        reset.Sessions,
+		}
+	}
+	if len(reset.AccessTokens) > 0 {
+		Broadcast <- models.Messages{
+			Type:        models.EventAccessTokenRevoked,
+			RecipientID: reset.UserID,
+			Data:        reset.AccessTokens,
+		}
+	}
+}
+
+func accountMailErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidAccountToken), errors.Is(err, services.ErrWeakPassword),
+		errors.Is(err, services.ErrMissingEmail):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrEmailVerified):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrMailNotConfigured):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
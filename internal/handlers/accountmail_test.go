@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/mailer"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/ratelimit"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/kontentski/chat/internal/signedtoken"
+	"github.com/kontentski/chat/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// recordingMailer hands sent messages to the test instead of delivering them.
+type recordingMailer struct {
+	sent chan mailer.Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m.sent <- msg
+	return nil
+}
+
+var linkToken = regexp.MustCompile(`\?token=(\S+)`)
+
+func tokenFromMail(t *testing.T, msg mailer.Message) string {
+	match := linkToken.FindStringSubmatch(msg.Body)
+	if match == nil {
+		t.Fatalf("no link in %q", msg.Body)
+	}
+	token, err := url.QueryUnescape(match[1])
+	assert.NoError(t, err)
+	return token
+}
+
+func accountMailService(now time.Time) (*storage.MockUser, *services.UserChatRoomServiceImpl, *recordingMailer) {
+	gin.SetMode(gin.TestMode)
+	Broadcast = make(chan models.Messages, 100)
+	_, mockRepo, service := initTest()
+	signer := signedtoken.New([]byte("test-key"))
+	signer.Now = func() time.Time { return now }
+	mail := &recordingMailer{sent: make(chan mailer.Message, 10)}
+	service.AccountMail = services.AccountMailConfig{
+		Mailer:    mail,
+		Signer:    signer,
+		BaseURL:   "https://chat.example.com/",
+		ResetTTL:  time.Hour,
+		VerifyTTL: 24 * time.Hour,
+	}
+	return mockRepo, service, mail
+}
+
+func accountMailEngine(service services.ChatRoomService) *gin.Engine {
+	engine := gin.New()
+	engine.POST("/auth/forgot-password", ForgotPasswordHandler(service))
+	engine.POST("/auth/reset-password", ResetPasswordHandler(service))
+	engine.POST("/auth/verify-email", VerifyEmailHandler(service))
+	engine.POST("/api/account/verify-email", func(c *gin.Context) {
+		c.Set(services.UserIDKey, uint(5))
+	}, SendVerificationEmailHandler(service))
+	return engine
+}
+
+func TestPasswordReset(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	mockRepo, service, mail := accountMailService(now)
+	engine := accountMailEngine(service)
+
+	oldHash, _ := bcrypt.GenerateFromPassword([]byte("Secret123"), bcrypt.MinCost)
+	alice := &models.Users{ID: 5, Username: "alice", Email: "alice@example.com", Password: string(oldHash)}
+	mockRepo.On("GetUserByEmail", mock.Anything, "alice@example.com").Return(alice, nil)
+	mockRepo.On("GetUserByEmail", mock.Anything, "nobody@example.com").Return(nil, pgx.ErrNoRows)
+
+	// Unknown addresses get the same answer, and no email
+	w := twoFactorRequest(engine, http.MethodPost, "/auth/forgot-password", "", `{"email":"nobody@example.com"}`)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	unknown := w.Body.String()
+
+	w = twoFactorRequest(engine, http.MethodPost, "/auth/forgot-password", "", `{"email":" alice@example.com "}`)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, unknown, w.Body.String())
+
+	msg := <-mail.sent
+	assert.Equal(t, "alice@example.com", msg.To)
+	assert.Contains(t, msg.Body, "https://chat.example.com/homepage/account/reset-password.html?token=")
+	assert.Len(t, mail.sent, 0)
+	token := tokenFromMail(t, msg)
+
+	mockRepo.On("GetUserByID", mock.Anything, uint(5)).Return(alice, nil).Once()
+	var newHash string
+	mockRepo.On("UpdatePassword", mock.Anything, uint(5), string(oldHash), mock.Anything).
+		Run(func(args mock.Arguments) { newHash = args.String(3) }).Return(true, nil)
+	mockRepo.On("MarkEmailVerified", mock.Anything, uint(5), "alice@example.com").Return(nil)
+	mockRepo.On("RevokeSessions", mock.Anything, uint(5), uint(0)).Return([]uint{1, 2}, nil)
+	mockRepo.On("RevokeAccessTokens", mock.Anything, uint(5)).Return([]uint{7}, nil)
+
+	w = twoFactorRequest(engine, http.MethodPost, "/auth/reset-password", "", `{"token":"`+token+`","password":"weak"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = twoFactorRequest(engine, http.MethodPost, "/auth/reset-password", "", `{"token":"`+token+`","password":"NewSecret456"}`)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(newHash), []byte("NewSecret456")))
+
+	revoked := <-Broadcast
+	assert.Equal(t, models.EventSessionRevoked, revoked.Type)
+	assert.Equal(t, uint(5), revoked.RecipientID)
+	assert.Equal(t, []uint{1, 2}, revoked.Data)
+	revoked = <-Broadcast
+	assert.Equal(t, models.EventAccessTokenRevoked, revoked.Type)
+	assert.Equal(t, uint(5), revoked.RecipientID)
+	assert.Equal(t, []uint{7}, revoked.Data)
+
+	// The token was bound to the old password hash, so it works once
+	changed := *alice
+	changed.Password = newHash
+	mockRepo.On("GetUserByID", mock.Anything, uint(5)).Return(&changed, nil)
+	w = twoFactorRequest(engine, http.MethodPost, "/auth/reset-password", "", `{"token":"`+token+`","password":"Another789"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockRepo.AssertNumberOfCalls(t, "UpdatePassword", 1)
+}
+
+func TestPasswordResetTokenExpires(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	mockRepo, service, _ := accountMailService(now)
+	engine := accountMailEngine(service)
+
+	alice := &models.Users{ID: 5, Username: "alice", Email: "alice@example.com", Password: "hash"}
+	mockRepo.On("GetUserByID", mock.Anything, uint(5)).Return(alice, nil)
+	token := service.AccountMail.Signer.Sign("reset-password", 5, "hash", -time.Minute)
+
+	w := twoFactorRequest(engine, http.MethodPost, "/auth/reset-password", "", `{"token":"`+token+`","password":"NewSecret456"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid or has expired")
+}
+
+func TestEmailVerification(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	mockRepo, service, mail := accountMailService(now)
+	limiter := ratelimit.New(0, 1)
+	limiter.Now = func() time.Time { return now }
+	service.AccountMail.Limiter = limiter
+	engine := accountMailEngine(service)
+
+	alice := &models.Users{ID: 5, Username: "alice", Email: "alice@example.com", Password: "hash"}
+	mockRepo.On("GetUserByID", mock.Anything, uint(5)).Return(alice, nil)
+	mockRepo.On("MarkEmailVerified", mock.Anything, uint(5), "alice@example.com").Return(nil)
+
+	w := twoFactorRequest(engine, http.MethodPost, "/api/account/verify-email", "", "")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	msg := <-mail.sent
+	assert.Contains(t, msg.Body, "/homepage/account/verify-email.html?token=")
+
+	w = twoFactorRequest(engine, http.MethodPost, "/api/account/verify-email", "", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// A reset token cannot verify an email
+	resetToken := service.AccountMail.Signer.Sign("reset-password", 5, "alice@example.com", time.Hour)
+	w = twoFactorRequest(engine, http.MethodPost, "/auth/verify-email", "", `{"token":"`+resetToken+`"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = twoFactorRequest(engine, http.MethodPost, "/auth/verify-email", "", `{"token":"`+tokenFromMail(t, msg)+`"}`)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockRepo.AssertCalled(t, "MarkEmailVerified", mock.Anything, uint(5), "alice@example.com")
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/services"
+)
+
+// GetBookmarksHandler godoc
+//	@Summary		List saved messages
+//	@Description	Lists the current user's bookmarks across rooms, newest first, with their room. Bookmarks whose message was deleted or expired, or whose room the user left, have available set to false, an unavailable_reason and no message.
+//	@Tags			bookmarks
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			limit	query		int	false	"Page size, 50 by default and at most 200"
+//	@Param			offset	query		int	false	"Number of bookmarks to skip"
+//	@Success		200		{array}		models.Bookmark
+//	@Failure		500		{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/bookmarks [get]
+func GetBookmarksHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bookmarks, err := service.GetBookmarks(c)
+		if err != nil {
+			c.JSON(bookmarkErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, bookmarks)
+	}
+}
+
+// SaveBookmarkHandler godoc
+//	@Summary		Save a message
+//	@Description	Saves a message of a room the user belongs to, with an optional note. Saving the same message again replaces the note.
+//	@Tags			bookmarks
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			request	body		services.BookmarkRequest	true	"Message to save"
+//	@Success		201		{object}	models.Bookmark
+//	@Failure		400		{object}	map[string]interface{}	"Invalid request"
+//	@Failure		403		{object}	map[string]interface{}	"Not a member of the chat room"
+//	@Failure		404		{object}	map[string]interface{}	"Message not found"
+//	@Failure		500		{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/bookmarks [post]
+func SaveBookmarkHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		bookmark, err := service.SaveBookmark(c)
+		if err != nil {
+			c.JSON(bookmarkErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, bookmark)
+	}
+}
+
+// DeleteBookmarkHandler godoc
+//	@Summary		Unsave a message
+//	@Description	Removes one of the current user's bookmarks
+//	@Tags			bookmarks
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			bookmarkID	path		int	true	"Bookmark ID"
+//	@Success		200			{object}	map[string]interface{}
+//	@Failure		404			{object}	map[string]interface{}	"Bookmark not found"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/bookmarks/{bookmarkID} [delete]
+func DeleteBookmarkHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := service.DeleteBookmark(c); err != nil {
+			c.JSON(bookmarkErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Bookmark deleted successfully"})
+	}
+}
+
+func bookmarkErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrBookmarkNotFound), errors.Is(err, services.ErrMessageNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidBookmarkNote):
+		return http.StatusBadRequest
+	default:
+		return moderationErrorStatus(err)
+	}
+}
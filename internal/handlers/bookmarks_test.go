@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/kontentski/chat/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetBookmarksHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, mockRepo, service := initTest()
+	mediaStorage := service.MediaStorage.(*storage.MockBucketStorage)
+
+	mockRepo.On("GetBookmarks", mock.Anything, uint(123), 50, 0).Return([]models.Bookmark{
+		{ID: 2, ChatRoomID: 7, MessageID: 12, ChatRoomName: "general", Available: true,
+			Message: &models.Messages{MessageID: 12, ChatRoomID: 7, Content: "chatrooms/7/1.png", Type: "media"}},
+		{ID: 1, ChatRoomID: 8, MessageID: 3, ChatRoomName: "old team", UnavailableReason: models.BookmarkNotMember},
+	}, nil)
+	mediaStorage.On("GenerateSignedURL", "chatrooms/7/1.png").Return("http://signed.url/1.png", nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set(services.UserIDKey, uint(123))
+	c.Request, _ = http.NewRequest(http.MethodGet, "/api/bookmarks", nil)
+	GetBookmarksHandler(service)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"content":"http://signed.url/1.png"`)
+	assert.Contains(t, w.Body.String(), `"available":false,"unavailable_reason":"not_a_member"`)
+	mockRepo.AssertExpectations(t)
+	mediaStorage.AssertExpectations(t)
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/services"
+)
+
+// GetRoomCategoriesHandler godoc
+//	@Summary		List room categories
+//	@Description	Lists the current user's room categories in their order
+//	@Tags			categories
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Success		200	{array}		models.RoomCategory
+//	@Failure		500	{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/categories [get]
+func GetRoomCategoriesHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		categories, err := service.GetRoomCategories(c)
+		if err != nil {
+			c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, categories)
+	}
+}
+
+// CreateRoomCategoryHandler godoc
+//	@Summary		Create a room category
+//	@Description	Creates a category after the user's existing ones
+//	@Tags			categories
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			request	body		services.RoomCategoryRequest	true	"Category name"
+//	@Success		200		{object}	models.RoomCategory
+//	@Failure		400		{object}	map[string]interface{}	"Invalid name"
+//	@Failure		500		{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/categories [post]
+func CreateRoomCategoryHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		category, err := service.CreateRoomCategory(c)
+		if err != nil {
+			c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, category)
+	}
+}
+
+// RenameRoomCategoryHandler godoc
+//	@Summary		Rename a room category
+//	@Tags			categories
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			categoryID	path		int								true	"Category ID"
+//	@Param			request		body		services.RoomCategoryRequest	true	"New name"
+//	@Success		200			{object}	map[string]interface{}
+//	@Failure		400,404		{object}	map[string]interface{}
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/categories/{categoryID} [patch]
+func RenameRoomCategoryHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := service.RenameRoomCategory(c); err != nil {
+			c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Category renamed successfully"})
+	}
+}
+
+// DeleteRoomCategoryHandler godoc
+//	@Summary		Delete a room category
+//	@Description	Deletes a category, its rooms move back to the uncategorized group
+//	@Tags			categories
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			categoryID	path		int	true	"Category ID"
+//	@Success		200			{object}	map[string]interface{}
+//	@Failure		404			{object}	map[string]interface{}	"Category not found"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/categories/{categoryID} [delete]
+func DeleteRoomCategoryHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := service.DeleteRoomCategory(c); err != nil {
+			c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
+	}
+}
+
+// ReorderRoomCategoriesHandler godoc
+//	@Summary		Reorder room categories
+//	@Description	Stores the order of the user's categories after a drag and drop
+//	@Tags			categories
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			request	body		services.ReorderCategoriesRequest	true	"Category IDs in their new order"
+//	@Success		200		{object}	map[string]interface{}
+//	@Failure		400,404	{object}	map[string]interface{}
+//	@Failure		500		{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/categories/order [put]
+func ReorderRoomCategoriesHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := service.ReorderRoomCategories(c); err != nil {
+			c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Categories reordered successfully"})
+	}
+}
+
+// MoveChatRoomsToCategoryHandler godoc
+//	@Summary		Move rooms into a category
+//	@Description	Puts the rooms into the category in the given order. Use category 0 for the uncategorized group.
+//	@Tags			categories
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			categoryID	path		int								true	"Category ID, 0 for uncategorized"
+//	@Param			request		body		services.MoveChatRoomsRequest	true	"Chat room IDs in their new order"
+//	@Success		200			{object}	map[string]interface{}
+//	@Failure		400,403,404	{object}	map[string]interface{}
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/categories/{categoryID}/rooms [put]
+func MoveChatRoomsToCategoryHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := service.MoveChatRoomsToCategory(c); err != nil {
+			c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Chat rooms moved successfully"})
+	}
+}
+
+func categoryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrCategoryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrNotAuthorized):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrInvalidCategoryName):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
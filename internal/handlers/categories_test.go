@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFetchGroupedChatRooms(t *testing.T) {
+	_, mockRepo, service := initTest()
+
+	userID := uint(123)
+	work := uint(1)
+	chatRooms := []models.ChatRooms{
+		{ID: 1, Name: "General", Type: "public"},
+		{ID: 2, Name: "Alice", Type: "private", IsDM: true, Settings: models.ChatRoomMemberSettings{CategoryID: &work}},
+		{ID: 3, Name: "Standup", Type: "private", Settings: models.ChatRoomMemberSettings{CategoryID: &work, Position: 1}},
+		{ID: 4, Name: "Deploys", Type: "private", Settings: models.ChatRoomMemberSettings{CategoryID: &work, Position: 0}},
+		{ID: 5, Name: "Old", Type: "public", Settings: models.ChatRoomMemberSettings{Archived: true}},
+	}
+	categories := []models.RoomCategory{{ID: work, UserID: userID, Name: "Work"}}
+
+	mockRepo.On("FetchUserChatRooms", userID).Return(chatRooms, nil)
+	mockRepo.On("GetRoomCategories", mock.Anything, userID).Return(categories, nil)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set(services.UserIDKey, userID)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/api/chatrooms?group=category", nil)
+	groups, err := service.FetchGroupedChatRooms(c)
+
+	assert.NoError(t, err)
+	assert.Len(t, groups, 3)
+
+	assert.Equal(t, services.GroupCategory, groups[0].Kind)
+	assert.Equal(t, "Work", groups[0].Name)
+	assert.Equal(t, []uint{4, 3}, getChatRoomIDs(groups[0].ChatRooms))
+
+	assert.Equal(t, services.GroupUncategorized, groups[1].Kind)
+	assert.Equal(t, []uint{1}, getChatRoomIDs(groups[1].ChatRooms))
+
+	// DMs stay in their own group even when they were put in a category
+	assert.Equal(t, services.GroupDirectMessages, groups[2].Kind)
+	assert.Equal(t, []uint{2}, getChatRoomIDs(groups[2].ChatRooms))
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
@@ -15,20 +16,27 @@ import (
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
-//	@Param			user	body	models.Users	true	"User information"
+//	@Param			user	body	services.CreateUserRequest	true	"User information"
 //	@Security		ApiKeyAuth
+//	@Security		BearerAuth
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		400	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/users [post]
 func CreateUser(service services.ChatRoomService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var user models.Users
+		var input services.CreateUserRequest
 
-		if err := c.ShouldBindJSON(&user); err != nil {
+		if err := c.ShouldBindJSON(&input); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		user := models.Users{
+			Username: input.Username,
+			Name:     input.Name,
+			Password: input.Password,
+			Email:    input.Email,
+		}
 
 		err := service.CreateUser(&user)
 		if err != nil {
@@ -41,18 +49,31 @@ func CreateUser(service services.ChatRoomService) gin.HandlerFunc {
 
 //  GetUserChatRooms godoc
 //	@Summary		Get user chat rooms
-//	@Description	retrieve chat rooms for the authenticated user
+//	@Description	retrieve chat rooms for the authenticated user, with the user's settings for each room
 //	@Tags			users
 //	@Produce		json
 //	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			include_archived	query		bool	false	"Include archived rooms"
+//	@Param			group				query		string	false	"Set to category to group the rooms by category, with direct messages in their own group"
 //	@Success		200	{array}		models.ChatRooms
+//	@Success		200	{array}		services.ChatRoomGroup	"When group=category"
 //	@Failure		401	{object}	map[string]interface{}	"Unauthenticated"
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/api/chatrooms [get]
 func GetUserChatRoomsHandler(service services.ChatRoomService) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if c.Query("group") == "category" {
+			groups, err := service.FetchGroupedChatRooms(c)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, groups)
+			return
+		}
 
-		chatRooms, err := service.FetchUserChatRooms(c.Request)
+		chatRooms, err := service.FetchUserChatRooms(c)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -68,6 +89,7 @@ func GetUserChatRoomsHandler(service services.ChatRoomService) gin.HandlerFunc {
 //	@Tags			messages
 //	@Produce		json
 //	@Security		ApiKeyAuth
+//	@Security		BearerAuth
 //	@Success		200			{array}		models.Messages
 //	@Failure		401			{object}	map[string]interface{}	"Unauthenticated"
 //	@Failure		500			{object}	map[string]interface{}
@@ -94,6 +116,7 @@ func GetMessagesHandler(service services.ChatRoomService) gin.HandlerFunc {
 //	@Param			chat_room_id	query	int	true	"chatroom id"
 //	@Param			userID			query	int	true	"user id"
 //	@Security		ApiKeyAuth
+//	@Security		BearerAuth
 //	@Success		200			{object}	map[string]interface{}
 //	@Failure		400,401,500	{object}	map[string]interface{}
 //	@Router			/messages/{messageID} [delete]
@@ -129,17 +152,26 @@ func DeleteMessageHandler(service services.ChatRoomService) gin.HandlerFunc {
 //	@Produce		json
 //	@Param			chatRoomID	path	int	true	"chatroom to leave"
 //	@Security		ApiKeyAuth
+//	@Security		BearerAuth
 //	@Success		200	{object}	map[string]interface{}	"message: User left the chat room successfully"
 //	@Failure		401	{object}	map[string]interface{}	"Unauthorized"
 //	@Failure		500	{object}	map[string]interface{}	"Internal Server Error"
 //	@Router			/api/chatrooms/leave/{chatRoomID} [post]
 func LeaveTheChatRoomHandler(service services.ChatRoomService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		err := service.LeaveChatRoom(c)
+		member, err := service.LeaveChatRoom(c)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+
+		// Let the remaining members update their member lists
+		Broadcast <- models.Messages{
+			ChatRoomID: member.ChatRoomID,
+			SenderID:   member.UserID,
+			Type:       models.EventMemberLeave,
+			Data:       member,
+		}
 		c.JSON(http.StatusOK, gin.H{"message": "User left the chat room successfully"})
 	}
 }
@@ -150,6 +182,7 @@ func LeaveTheChatRoomHandler(service services.ChatRoomService) gin.HandlerFunc {
 //	@Tags			users
 //	@Produce		json
 //	@Security		ApiKeyAuth
+//	@Security		BearerAuth
 //	@param			q	query		string	true	"Search users"
 //	@Success		200	{array}		services.UsersListResponse
 //	@Failure		401	{object}	map[string]interface{}	"Unauthorized"
@@ -174,19 +207,32 @@ func SearchUsersHandler(service services.ChatRoomService) gin.HandlerFunc {
 //	@Accept			json
 //	@Produce		json
 //	@Security		ApiKeyAuth
+//	@Security		BearerAuth
 //	@Param			request	body		object					true	"Add user request"	
 //	@Success		200		{object}	map[string]interface{}	"message: User added successfully"
 //	@Failure		401		{object}	map[string]interface{}	"Unauthorized"
+//	@Failure		403		{object}	map[string]interface{}	"User is banned from the chat room"
 //	@Failure		500		{object}	map[string]interface{}	"Internal Server Error"
 //	@Router			/api/chatrooms/add-user [post]
 func AddUserHandler(service services.ChatRoomService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		err := service.AddUserToChatRoom(c)
+		member, err := service.AddUserToChatRoom(c)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			if errors.Is(err, services.ErrUserBanned) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
 			return
 		}
 
+		Broadcast <- models.Messages{
+			ChatRoomID: member.ChatRoomID,
+			SenderID:   member.UserID,
+			Type:       models.EventMemberJoin,
+			Data:       member,
+		}
+
 		c.JSON(http.StatusOK, gin.H{"message": "User added successfully"})
 	}
 }
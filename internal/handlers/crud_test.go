@@ -14,6 +14,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/ratelimit"
 	"github.com/kontentski/chat/internal/services"
 	"github.com/kontentski/chat/internal/storage"
 	"github.com/stretchr/testify/assert"
@@ -50,6 +51,31 @@ func TestCreateUser_Success(t *testing.T) {
 	assert.JSONEq(t, expectedResponse, w.Body.String(), "Expected response %v but got %v", expectedResponse, w.Body.String())
 }
 
+func TestCreateUser_IgnoresProfilePicture(t *testing.T) {
+	var created models.Users
+	mockStorage := &storage.MockUser{
+		CreateUserFn: func(user *models.Users) error {
+			created = *user
+			return nil
+		},
+	}
+	service := &services.UserChatRoomServiceImpl{UserRepo: mockStorage}
+
+	router := gin.New()
+	router.POST("/users", CreateUser(service))
+
+	reqBody := `{"username":"testuser","name":"Test User","password":"password123","email":"testuser@example.com","profile_picture":"users/8/avatar/100"}`
+	req, _ := http.NewRequest(http.MethodPost, "/users", bytes.NewBufferString(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "testuser", created.Username)
+	assert.Empty(t, created.ProfilePicture)
+}
+
 func TestCreateUser_Failure(t *testing.T) {
 	mockStorage := &storage.MockUser{
 		CreateUserFn: func(user *models.Users) error {
@@ -343,91 +369,68 @@ func initTest() (*storage.MockUser, *storage.MockUser, *services.UserChatRoomSer
 	return mockAuth, mockRepo, service
 }
 
+func newChatRoomsContext(userID interface{}) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if userID != nil {
+		c.Set(services.UserIDKey, userID)
+	}
+	c.Request, _ = http.NewRequest(http.MethodGet, "/api/chatrooms", nil)
+	return c
+}
+
 func TestFetchUserChatRooms(t *testing.T) {
 	// Create a test context
 	gin.SetMode(gin.TestMode)
 
-	t.Run("Success - Valid Session and Chat Rooms Fetched", func(t *testing.T) {
-		mockAuth, mockRepo, service := initTest()
+	t.Run("Success - Signed In and Chat Rooms Fetched", func(t *testing.T) {
+		_, mockRepo, service := initTest()
 		// Arrange
 		userID := uint(123)
-		sessionValues := map[string]interface{}{
-			"userID": userID,
-		}
 		chatRooms := []models.ChatRooms{
 			{ID: 1, Name: "General", Description: "General Chat", Type: "public"},
 			{ID: 2, Name: "Tech Talk", Description: "Technology discussion", Type: "private"},
 		}
 
 		// Mock dependencies
-		mockAuth.On("GetSession", mock.Anything).Return(sessionValues, nil)
 		mockRepo.On("FetchUserChatRooms", userID).Return(chatRooms, nil)
 
-		// Create the test request
-		req, _ := http.NewRequest(http.MethodGet, "/", nil)
-
 		// Act
-		actualChatRooms, err := service.FetchUserChatRooms(req)
+		actualChatRooms, err := service.FetchUserChatRooms(newChatRoomsContext(userID))
 
 		// Assert
 		assert.NoError(t, err)
 		assert.Equal(t, chatRooms, actualChatRooms)
-		mockAuth.AssertExpectations(t)
 		mockRepo.AssertExpectations(t)
 	})
 
-	t.Run("Error - Invalid Session (userID not found)", func(t *testing.T) {
-		mockAuth, mockRepo, service := initTest()
-
-		// Arrange
-		sessionValues := map[string]interface{}{
-			// Missing userID or incorrect type
-		}
-
-		// Mock dependencies
-		mockAuth.On("GetSession", mock.Anything).Return(sessionValues, nil)
-
-		// Create the test request
-		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	t.Run("Error - No User (userID not set)", func(t *testing.T) {
+		_, mockRepo, service := initTest()
 
 		// Act
-		_, err := service.FetchUserChatRooms(req)
+		_, err := service.FetchUserChatRooms(newChatRoomsContext(nil))
 
 		// Assert
-		if err == nil {
-			t.Error("Expected error but got nil")
-		} else {
-			assert.Contains(t, err.Error(), "unauthorized")
-		}
-		mockAuth.AssertExpectations(t)
+		assert.ErrorIs(t, err, services.ErrNoUserID)
 		mockRepo.AssertNotCalled(t, "FetchUserChatRooms")
 	})
 
 	t.Run("Error - Failed to Fetch Chat Rooms from Repo", func(t *testing.T) {
-		mockAuth, mockRepo, service := initTest()
+		_, mockRepo, service := initTest()
 		// Arrange
 		userID := uint(123)
-		sessionValues := map[string]interface{}{
-			"userID": userID,
-		}
 		expectedErr := fmt.Errorf("database error")
 
 		// Mock dependencies
-		mockAuth.On("GetSession", mock.Anything).Return(sessionValues, nil)
 		mockRepo.On("FetchUserChatRooms", userID).Return(nil, expectedErr)
 
-		// Create the test request
-		req, _ := http.NewRequest(http.MethodGet, "/", nil)
-
 		// Act
-		_, err := service.FetchUserChatRooms(req)
+		_, err := service.FetchUserChatRooms(newChatRoomsContext(userID))
 
 		// Assert
 		assert.Error(t, err)
 		assert.EqualError(t, err, expectedErr.Error())
 
 		// Verify expectations were met
-		mockAuth.AssertExpectations(t)
 		mockRepo.AssertExpectations(t)
 	})
 }
@@ -474,10 +477,13 @@ func TestUploadMediaHandler(t *testing.T) {
 
 		service := services.UserChatRoomServiceImpl{
 			MediaStorage: mockStorage,
+			SendLimiter:  ratelimit.New(0.001, 1),
 		}
 
 		router := gin.New()
-		router.POST("/upload", UploadMediaHandler(&service))
+		router.POST("/upload", func(c *gin.Context) {
+			c.Set(services.UserIDKey, uint(123))
+		}, UploadMediaHandler(&service))
 
 		// Create a test request with a file
 		reqBody := &bytes.Buffer{}
@@ -499,6 +505,10 @@ func TestUploadMediaHandler(t *testing.T) {
 		expectedResponse := `{"filePath":"chatrooms/1/09238450934.jpg"}`
 		assert.JSONEq(t, expectedResponse, w.Body.String())
 
+		// The limit is charged once, when the media message is sent over the WebSocket
+		allowed, _ := service.SendLimiter.Allow(123)
+		assert.True(t, allowed)
+
 		mockStorage.AssertExpectations(t) // Assert that the expectations were met
 	})
 
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/auth"
+	"github.com/kontentski/chat/internal/middleware"
+)
+
+// CSRFTokenHandler godoc
+//	@Summary		Get the CSRF token
+//	@Description	Returns the CSRF token of the current session, issuing one if the session has none, and sets it in the csrf_token cookie. Requests authenticated with the session cookie must send the token in the X-CSRF-Token header on every POST, PUT, PATCH and DELETE.
+//	@Tags			auth
+//	@Produce		json
+//	@Success		200	{object}	map[string]interface{}
+//	@Failure		401	{object}	map[string]interface{}	"No session"
+//	@Failure		500	{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/auth/csrf [get]
+func CSRFTokenHandler(c *gin.Context) {
+	session, err := auth.Store.Get(c.Request, "auth-session")
+	if err != nil || session.IsNew {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	token, ok := session.Values[middleware.CSRFTokenKey].(string)
+	if ok && token != "" {
+		middleware.SetCSRFCookie(c, session, token)
+		c.JSON(http.StatusOK, gin.H{"csrf_token": token})
+		return
+	}
+	// Sessions from before CSRF protection have no token yet
+	token, err = middleware.IssueCSRFToken(session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue CSRF token"})
+		return
+	}
+	if err := session.Save(c.Request, c.Writer); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Session error"})
+		return
+	}
+	middleware.SetCSRFCookie(c, session, token)
+	c.JSON(http.StatusOK, gin.H{"csrf_token": token})
+}
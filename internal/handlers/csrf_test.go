@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/auth"
+	"github.com/kontentski/chat/internal/middleware"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/kontentski/chat/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func csrfEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	auth.Store = storage.NewSessionStore(storage.NewMockSessionBackend(), []byte("test-secret"))
+	engine := gin.New()
+	engine.Use(middleware.CSRF(auth.Store))
+	engine.GET("/auth/csrf", CSRFTokenHandler)
+	engine.POST("/api/chatrooms/add-user", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return engine
+}
+
+func csrfRequest(engine *gin.Engine, method, path, cookie, token string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, nil)
+	if cookie != "" {
+		req.Header.Set("Cookie", cookie)
+	}
+	if token != "" {
+		req.Header.Set(middleware.CSRFHeader, token)
+	}
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestCSRFRejectsCookieRequestWithoutToken(t *testing.T) {
+	engine := csrfEngine()
+	cookie := sessionCookie(t, map[interface{}]interface{}{services.UserIDKey: uint(5), middleware.CSRFTokenKey: "secret-token"})
+
+	w := csrfRequest(engine, http.MethodPost, "/api/chatrooms/add-user", cookie, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = csrfRequest(engine, http.MethodPost, "/api/chatrooms/add-user", cookie, "wrong-token")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = csrfRequest(engine, http.MethodPost, "/api/chatrooms/add-user", cookie, "secret-token")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestCSRFExemptions(t *testing.T) {
+	engine := csrfEngine()
+
+	// No session cookie, nothing to forge
+	w := csrfRequest(engine, http.MethodPost, "/api/chatrooms/add-user", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Browsers never send a bearer token on their own
+	cookie := sessionCookie(t, map[interface{}]interface{}{services.UserIDKey: uint(5), middleware.CSRFTokenKey: "secret-token"})
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/api/chatrooms/add-user", nil)
+	req.Header.Set("Cookie", cookie)
+	req.Header.Set("Authorization", "Bearer chat_pat_abc")
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestCSRFTokenHandlerIssuesTokenForOldSession(t *testing.T) {
+	engine := csrfEngine()
+	cookie := sessionCookie(t, map[interface{}]interface{}{services.UserIDKey: uint(5)})
+
+	w := csrfRequest(engine, http.MethodGet, "/auth/csrf", cookie, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Token string `json:"csrf_token"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.NotEmpty(t, body.Token)
+	assert.Contains(t, w.Header().Values("Set-Cookie")[1], middleware.CSRFCookie+"="+body.Token)
+
+	// The same token is returned again
+	w = csrfRequest(engine, http.MethodGet, "/auth/csrf", cookie, "")
+	assert.Contains(t, w.Body.String(), body.Token)
+
+	w = csrfRequest(engine, http.MethodPost, "/api/chatrooms/add-user", cookie, body.Token)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = csrfRequest(engine, http.MethodGet, "/auth/csrf", "", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestSaveAuthSessionIssuesCSRFToken(t *testing.T) {
+	csrfEngine()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/auth/login", nil)
+
+	assert.NoError(t, saveAuthSession(c, &models.Users{ID: 5, Username: "alice"}, false))
+
+	var csrfCookie *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == middleware.CSRFCookie {
+			csrfCookie = cookie
+		}
+	}
+	if assert.NotNil(t, csrfCookie) {
+		assert.NotEmpty(t, csrfCookie.Value)
+		assert.False(t, csrfCookie.HttpOnly)
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	AllowedOrigins = []string{"https://chat.example.com/"}
+	defer func() { AllowedOrigins = nil }()
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true},
+		{"http://localhost:8080", true},
+		{"https://chat.example.com", true},
+		{"https://evil.example.com", false},
+		{"http://localhost:9090", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/ws", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		assert.Equal(t, tt.want, checkOrigin(req), tt.origin)
+	}
+}
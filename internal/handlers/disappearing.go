@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+)
+
+// ExpireMessages erases disappearing messages whose time is up and tells the room members to remove
+// them, relayed the same way as a deleted message.
+func ExpireMessages(ctx context.Context, service services.ChatRoomService) error {
+	expired, err := service.ExpireMessages(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, msg := range expired {
+		Broadcast <- models.Messages{
+			MessageID:  msg.MessageID,
+			ChatRoomID: msg.ChatRoomID,
+			SenderID:   msg.SenderID,
+			Type:       models.EventMessageExpired,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/kontentski/chat/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestExpireMessages(t *testing.T) {
+	Broadcast = make(chan models.Messages, 100)
+	_, mockRepo, service := initTest()
+	mediaStorage := service.MediaStorage.(*storage.MockBucketStorage)
+
+	mockRepo.On("EraseExpiredMessages", mock.Anything, mock.Anything).Return([]models.Messages{
+		{MessageID: 1, ChatRoomID: 7, SenderID: 123, Content: "secret"},
+		{MessageID: 2, ChatRoomID: 7, SenderID: 123, Content: "chatrooms/7/photo.png", Type: "media"},
+	}, nil)
+	mediaStorage.On("DeleteFile", mock.Anything, "chatrooms/7/photo.png").Return(nil)
+
+	err := ExpireMessages(context.Background(), service)
+
+	assert.NoError(t, err)
+	for _, messageID := range []uint{1, 2} {
+		event := <-Broadcast
+		assert.Equal(t, models.EventMessageExpired, event.Type)
+		assert.Equal(t, messageID, event.MessageID)
+		assert.Empty(t, event.Content)
+	}
+	mockRepo.AssertExpectations(t)
+	mediaStorage.AssertExpectations(t)
+}
+
+func TestSetMessageTTLHandlerLetsDMMembersChangeIt(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	Broadcast = make(chan models.Messages, 100)
+	_, mockRepo, service := initTest()
+
+	mockRepo.On("GetChatRoom", mock.Anything, uint(7)).Return(&models.ChatRooms{ID: 7, IsDM: true}, nil)
+	mockRepo.On("IsUserInChatRoom", uint(123), uint(7)).Return(true)
+	mockRepo.On("SetMessageTTL", mock.Anything, uint(7), mock.AnythingOfType("*int")).Return(nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "chatRoomID", Value: "7"}}
+	c.Set(services.UserIDKey, uint(123))
+	c.Request, _ = http.NewRequest(http.MethodPut, "/api/chatrooms/7/message-ttl", bytes.NewBufferString(`{"seconds":3600}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	SetMessageTTLHandler(service)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertNotCalled(t, "GetChatRoomMember", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
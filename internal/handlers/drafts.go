@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+)
+
+// GetDraftHandler godoc
+//	@Summary		Get the message draft
+//	@Description	Returns the current user's unsent message in the chat room. The content is empty when there is no draft.
+//	@Tags			drafts
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			chatRoomID	path		int	true	"Chat Room ID"
+//	@Success		200			{object}	models.Draft
+//	@Failure		403			{object}	map[string]interface{}	"Not a member of the chat room"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/chatrooms/{chatRoomID}/draft [get]
+func GetDraftHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		draft, err := service.GetDraft(c)
+		if err != nil {
+			c.JSON(draftErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, draft)
+	}
+}
+
+// SaveDraftHandler godoc
+//	@Summary		Save the message draft
+//	@Description	Replaces the current user's draft in the chat room; empty content clears it. The draft is pushed to the user's WebSocket connections as a draft_updated event carrying the device_id of the request. Sending a message in the room clears the draft.
+//	@Tags			drafts
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			chatRoomID	path		int						true	"Chat Room ID"
+//	@Param			request		body		services.DraftRequest	true	"Draft content"
+//	@Success		200			{object}	models.Draft
+//	@Failure		400			{object}	map[string]interface{}	"Draft too long"
+//	@Failure		403			{object}	map[string]interface{}	"Not a member of the chat room"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/chatrooms/{chatRoomID}/draft [put]
+func SaveDraftHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		draft, err := service.SaveDraft(c)
+		if err != nil {
+			c.JSON(draftErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		userID, _ := c.Get(services.UserIDKey)
+		Broadcast <- models.Messages{
+			ChatRoomID:  draft.ChatRoomID,
+			Type:        models.EventDraftUpdated,
+			Data:        draft,
+			RecipientID: userID.(uint),
+		}
+
+		c.JSON(http.StatusOK, draft)
+	}
+}
+
+func draftErrorStatus(err error) int {
+	if errors.Is(err, services.ErrDraftTooLong) {
+		return http.StatusBadRequest
+	}
+	return moderationErrorStatus(err)
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSaveDraftHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, mockRepo, service := initTest()
+	Broadcast = make(chan models.Messages, 100)
+
+	updatedAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	mockRepo.On("IsUserInChatRoom", uint(123), uint(7)).Return(true)
+	mockRepo.On("SaveDraft", mock.Anything, uint(7), uint(123), "half a thought").
+		Return(&models.Draft{ChatRoomID: 7, Content: "half a thought", UpdatedAt: updatedAt}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set(services.UserIDKey, uint(123))
+	c.Params = gin.Params{{Key: "chatRoomID", Value: "7"}}
+	c.Request, _ = http.NewRequest(http.MethodPut, "/api/chatrooms/7/draft",
+		strings.NewReader(`{"content":"half a thought","device_id":"laptop"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	SaveDraftHandler(service)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"device_id":"laptop"`)
+
+	event := <-Broadcast
+	assert.Equal(t, models.EventDraftUpdated, event.Type)
+	assert.Equal(t, uint(7), event.ChatRoomID)
+	assert.Equal(t, uint(123), event.RecipientID)
+	assert.Equal(t, "half a thought", event.Data.(*models.Draft).Content)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSaveDraftHandlerNotMember(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, mockRepo, service := initTest()
+	Broadcast = make(chan models.Messages, 100)
+
+	mockRepo.On("IsUserInChatRoom", uint(123), uint(7)).Return(false)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set(services.UserIDKey, uint(123))
+	c.Params = gin.Params{{Key: "chatRoomID", Value: "7"}}
+	c.Request, _ = http.NewRequest(http.MethodPut, "/api/chatrooms/7/draft", strings.NewReader(`{"content":"hi"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	SaveDraftHandler(service)(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, Broadcast)
+	mockRepo.AssertNotCalled(t, "SaveDraft", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/services"
+)
+
+// ForwardMessageHandler godoc
+//	@Summary		Forward a message
+//	@Description	Copies a message, including media, to another chat room where the user is also a member. The copy is sent by the current user and keeps a reference to the original room, message and author in forwarded_from.
+//	@Tags			messages
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			chatRoomID	path		int						true	"Source Chat Room ID"
+//	@Param			messageID	path		int						true	"Message ID"
+//	@Param			request		body		services.ForwardRequest	true	"Target chat room"
+//	@Success		201			{object}	models.Messages
+//	@Failure		400			{object}	map[string]interface{}	"Invalid request"
+//	@Failure		403			{object}	map[string]interface{}	"Not a member of both chat rooms or muted"
+//	@Failure		404			{object}	map[string]interface{}	"Message not found"
+//	@Failure		429			{object}	map[string]interface{}	"Sending too fast"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/chatrooms/{chatRoomID}/messages/{messageID}/forward [post]
+func ForwardMessageHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		msg, err := service.PrepareForward(c)
+		if err != nil {
+			var rateErr *services.RateLimitError
+			if errors.As(err, &rateErr) {
+				c.Header("Retry-After", strconv.Itoa(rateErr.RetryAfterSeconds()))
+				c.JSON(http.StatusTooManyRequests, gin.H{"error": rateErr.Error(), "retry_after": rateErr.RetryAfterSeconds()})
+				return
+			}
+			c.JSON(forwardErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := saveMessageToDB(msg); err != nil {
+			log.Printf("Error saving forwarded message in chat room %d: %v", msg.ChatRoomID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to forward message"})
+			return
+		}
+
+		Broadcast <- *msg
+
+		if msg.Type == "media" {
+			if signedURL, err := service.GenerateSignedURL(msg.Content); err == nil {
+				msg.Content = signedURL
+			}
+		}
+		c.JSON(http.StatusCreated, msg)
+	}
+}
+
+func forwardErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrMessageNotFound), errors.Is(err, services.ErrChatRoomNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrUserMuted):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrForwardSameRoom), errors.Is(err, services.ErrCannotForward):
+		return http.StatusBadRequest
+	default:
+		return moderationErrorStatus(err)
+	}
+}
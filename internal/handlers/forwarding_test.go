@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/kontentski/chat/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newForwardContext(w *httptest.ResponseRecorder, body string) *gin.Context {
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "chatRoomID", Value: "7"}, {Key: "messageID", Value: "12"}}
+	c.Set(services.UserIDKey, uint(123))
+	c.Request, _ = http.NewRequest(http.MethodPost, "/api/chatrooms/7/messages/12/forward", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	return c
+}
+
+func TestForwardMessageHandlerRequiresBothRooms(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, mockRepo, service := initTest()
+
+	mockRepo.On("IsUserInChatRoom", uint(123), uint(7)).Return(true)
+	mockRepo.On("IsUserInChatRoom", uint(123), uint(8)).Return(false)
+
+	w := httptest.NewRecorder()
+	ForwardMessageHandler(service)(newForwardContext(w, `{"target_chat_room_id":8}`))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockRepo.AssertNotCalled(t, "GetMessage", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPrepareForwardCopiesMedia(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, mockRepo, service := initTest()
+	mediaStorage := service.MediaStorage.(*storage.MockBucketStorage)
+
+	mockRepo.On("IsUserInChatRoom", uint(123), uint(7)).Return(true)
+	mockRepo.On("IsUserInChatRoom", uint(123), uint(8)).Return(true)
+	mockRepo.On("GetMessage", mock.Anything, uint(7), uint(12)).Return(&models.Messages{
+		MessageID:  12,
+		ChatRoomID: 7,
+		SenderID:   456,
+		Content:    "chatrooms/7/1700000000.png",
+		Type:       "media",
+	}, nil)
+	mockRepo.On("GetMutedUntil", mock.Anything, uint(8), uint(123)).Return(time.Time{}, nil)
+	mockRepo.On("GetChatRoomMember", mock.Anything, uint(8), uint(123)).Return(&models.ChatRoomMemberProfile{Role: models.RoleMember}, nil)
+	mockRepo.On("GetSlowModeState", mock.Anything, uint(8), uint(123)).Return(time.Duration(0), time.Duration(-1), nil)
+	mockRepo.On("GetChatRoom", mock.Anything, uint(8)).Return(&models.ChatRooms{ID: 8, IsDM: true}, nil)
+	isTargetPath := mock.MatchedBy(func(path string) bool {
+		return strings.HasPrefix(path, "chatrooms/8/") && strings.HasSuffix(path, ".png")
+	})
+	mediaStorage.On("CopyFile", mock.Anything, "chatrooms/7/1700000000.png", isTargetPath).Return(nil)
+
+	msg, err := service.PrepareForward(newForwardContext(httptest.NewRecorder(), `{"target_chat_room_id":8}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(8), msg.ChatRoomID)
+	assert.Equal(t, uint(123), msg.SenderID)
+	assert.True(t, msg.IsDM)
+	assert.True(t, strings.HasPrefix(msg.Content, "chatrooms/8/"))
+	assert.Equal(t, &models.MessageReference{ChatRoomID: 7, MessageID: 12, SenderID: 456}, msg.ForwardedFrom)
+	mockRepo.AssertExpectations(t)
+	mediaStorage.AssertExpectations(t)
+}
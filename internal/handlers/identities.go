@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/auth"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/markbates/goth"
+	"github.com/markbates/goth/gothic"
+)
+
+// linkUserIDKey marks in the auth-session that the next provider callback links an identity to this user.
+const linkUserIDKey = "linkUserID"
+
+// LinkProviderHandler godoc
+//	@Summary		Link a sign-in provider
+//	@Description	Starts the provider's sign-in and, when it completes, links the provider account to the current user instead of signing in with it.
+//	@Tags			account
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			provider	query	string	true	"Provider name, such as google, github, gitlab or openid-connect"
+//	@Success		307
+//	@Failure		400	{object}	map[string]interface{}	"Unknown provider"
+//	@Failure		500	{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/auth/link [get]
+func LinkProviderHandler(c *gin.Context) {
+	if _, err := goth.GetProvider(c.Query("provider")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := auth.Store.Get(c.Request, "auth-session")
+	if err != nil {
+		log.Println("Error getting session:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Session error"})
+		return
+	}
+	userID, ok := session.Values[services.UserIDKey].(uint)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	session.Values[linkUserIDKey] = userID
+	if err := session.Save(c.Request, c.Writer); err != nil {
+		log.Println("Error saving session:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Session save error"})
+		return
+	}
+
+	gothic.BeginAuthHandler(c.Writer, c.Request)
+}
+
+// GetIdentitiesHandler godoc
+//	@Summary		List linked providers
+//	@Description	Returns the sign-in provider accounts linked to the current user.
+//	@Tags			account
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Success		200	{array}		models.UserIdentity
+//	@Failure		500	{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/account/identities [get]
+func GetIdentitiesHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identities, err := service.GetIdentities(c)
+		if err != nil {
+			c.JSON(identityErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, identities)
+	}
+}
+
+// UnlinkIdentityHandler godoc
+//	@Summary		Unlink a provider
+//	@Description	Removes the current user's linked account of the provider. The last provider can only be unlinked once the user has set a password.
+//	@Tags			account
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			provider	path	string	true	"Provider name"
+//	@Success		204
+//	@Failure		403	{object}	map[string]interface{}	"Requires a signed-in session"
+//	@Failure		404	{object}	map[string]interface{}	"Provider is not linked"
+//	@Failure		409	{object}	map[string]interface{}	"Only way left to sign in"
+//	@Failure		500	{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/account/identities/{provider} [delete]
+func UnlinkIdentityHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := service.UnlinkIdentity(c); err != nil {
+			c.JSON(identityErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func identityErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrIdentityNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrIdentityTaken), errors.Is(err, services.ErrProviderAlreadyLinked),
+		errors.Is(err, services.ErrEmailTaken), errors.Is(err, services.ErrLastLoginMethod):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrOAuthEmailRequired):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrSessionRequired):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/auth"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/kontentski/chat/internal/storage"
+	"github.com/markbates/goth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func stubCompleteUserAuth(t *testing.T, user goth.User) {
+	previous := completeUserAuth
+	completeUserAuth = func(http.ResponseWriter, *http.Request) (goth.User, error) { return user, nil }
+	t.Cleanup(func() { completeUserAuth = previous })
+}
+
+// sessionCookie returns an auth-session cookie holding values.
+func sessionCookie(t *testing.T, values map[interface{}]interface{}) string {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	session, _ := auth.Store.Get(req, "auth-session")
+	for k, v := range values {
+		session.Values[k] = v
+	}
+	assert.NoError(t, session.Save(req, w))
+	return w.Header().Get("Set-Cookie")
+}
+
+func performCallback(service services.ChatRoomService, cookie string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/auth/callback", nil)
+	if cookie != "" {
+		c.Request.Header.Set("Cookie", cookie)
+	}
+	CallbackHandler(service)(c)
+	return w
+}
+
+func TestCallbackHandlerLinksLegacyGoogleAccount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth.Store = storage.NewSessionStore(storage.NewMockSessionBackend(), []byte("test-secret"))
+	_, mockRepo, service := initTest()
+	mockRepo.On("GetTOTP", mock.Anything, mock.Anything).Return(nil, pgx.ErrNoRows)
+	stubCompleteUserAuth(t, goth.User{Provider: "google", UserID: "g-1", Email: "alice@example.com"})
+
+	user := &models.Users{ID: 5, Username: "alice", Password: "hash", Email: "alice@example.com"}
+	mockRepo.On("GetUserByIdentity", mock.Anything, "google", "g-1").Return(nil, pgx.ErrNoRows)
+	mockRepo.On("GetUnlinkedUserByEmail", mock.Anything, "alice@example.com").Return(user, nil)
+	mockRepo.On("LinkIdentity", mock.Anything, mock.MatchedBy(func(identity *models.UserIdentity) bool {
+		return identity.UserID == 5 && identity.Provider == "google" && identity.ProviderUserID == "g-1"
+	})).Return(nil)
+	mockRepo.On("UpdateLastSeen", mock.Anything, uint(5)).Return(nil)
+
+	w := performCallback(service, "")
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/homepage", w.Header().Get("Location"))
+	assert.Contains(t, w.Header().Get("Set-Cookie"), "auth-session=")
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCallbackHandlerDoesNotMatchOtherProvidersByEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth.Store = storage.NewSessionStore(storage.NewMockSessionBackend(), []byte("test-secret"))
+	_, mockRepo, service := initTest()
+	mockRepo.On("GetTOTP", mock.Anything, mock.Anything).Return(nil, pgx.ErrNoRows)
+	stubCompleteUserAuth(t, goth.User{Provider: "github", UserID: "42", Email: "alice@example.com"})
+
+	mockRepo.On("GetUserByIdentity", mock.Anything, "github", "42").Return(nil, pgx.ErrNoRows)
+	mockRepo.On("CreateOAuthUser", mock.Anything, mock.Anything, mock.Anything).Return(storage.ErrEmailTaken)
+
+	w := performCallback(service, "")
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	mockRepo.AssertNotCalled(t, "GetUnlinkedUserByEmail", mock.Anything, mock.Anything)
+}
+
+func TestCallbackHandlerLinksProviderToSignedInUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth.Store = storage.NewSessionStore(storage.NewMockSessionBackend(), []byte("test-secret"))
+	_, mockRepo, service := initTest()
+	stubCompleteUserAuth(t, goth.User{Provider: "gitlab", UserID: "7", Email: "alice@work.example"})
+
+	mockRepo.On("GetUserByIdentity", mock.Anything, "gitlab", "7").Return(nil, pgx.ErrNoRows)
+	mockRepo.On("LinkIdentity", mock.Anything, mock.MatchedBy(func(identity *models.UserIdentity) bool {
+		return identity.UserID == 5 && identity.Provider == "gitlab"
+	})).Return(nil)
+
+	cookie := sessionCookie(t, map[interface{}]interface{}{services.UserIDKey: uint(5), linkUserIDKey: uint(5)})
+	w := performCallback(service, cookie)
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/homepage", w.Header().Get("Location"))
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "CreateOAuthUser", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCallbackHandlerRefusesIdentityOfAnotherUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth.Store = storage.NewSessionStore(storage.NewMockSessionBackend(), []byte("test-secret"))
+	_, mockRepo, service := initTest()
+	stubCompleteUserAuth(t, goth.User{Provider: "github", UserID: "42"})
+
+	mockRepo.On("GetUserByIdentity", mock.Anything, "github", "42").Return(&models.Users{ID: 9}, nil)
+
+	cookie := sessionCookie(t, map[interface{}]interface{}{services.UserIDKey: uint(5), linkUserIDKey: uint(5)})
+	w := performCallback(service, cookie)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	mockRepo.AssertNotCalled(t, "LinkIdentity", mock.Anything, mock.Anything)
+}
+
+func TestUnlinkIdentityHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, mockRepo, service := initTest()
+
+	mockRepo.On("GetIdentities", mock.Anything, uint(5)).Return([]models.UserIdentity{{UserID: 5, Provider: "google"}}, nil)
+	mockRepo.On("UnlinkIdentity", mock.Anything, uint(5), "google").Return(false, nil)
+
+	for provider, status := range map[string]int{"google": http.StatusConflict, "github": http.StatusNotFound} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set(services.UserIDKey, uint(5))
+		c.Params = gin.Params{{Key: "provider", Value: provider}}
+		c.Request, _ = http.NewRequest(http.MethodDelete, "/api/account/identities/"+provider, nil)
+		UnlinkIdentityHandler(service)(c)
+
+		assert.Equal(t, status, w.Code, provider)
+	}
+}
+
+func TestUnlinkIdentityHandlerRequiresSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, mockRepo, service := initTest()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set(services.UserIDKey, uint(5))
+	c.Set(services.AccessTokenIDKey, uint(9))
+	c.Params = gin.Params{{Key: "provider", Value: "google"}}
+	c.Request, _ = http.NewRequest(http.MethodDelete, "/api/account/identities/google", nil)
+	UnlinkIdentityHandler(service)(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockRepo.AssertNotCalled(t, "UnlinkIdentity", mock.Anything, mock.Anything, mock.Anything)
+}
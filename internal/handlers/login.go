@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/auth"
+	"github.com/kontentski/chat/internal/middleware"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+)
+
+// LoginHandler godoc
+//	@Summary		Log in with username and password
+//	@Description	Checks the username and password chosen at registration and starts the same auth-session cookie as Google sign-in. Repeated failures lock out the username and the client IP for a growing period. Users with two-factor authentication get two_factor_required and must finish with POST /auth/2fa.
+//	@Tags			auth
+//	@Accept			json
+//	@Accept			x-www-form-urlencoded
+//	@Produce		json
+//	@Param			request	body		services.LoginRequest	true	"Credentials"
+//	@Success		200		{object}	map[string]interface{}
+//	@Failure		400		{object}	map[string]interface{}	"Missing username or password"
+//	@Failure		401		{object}	map[string]interface{}	"Invalid username or password"
+//	@Failure		429		{object}	map[string]interface{}	"Too many failed attempts"
+//	@Failure		500		{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/auth/login [post]
+func LoginHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := service.Login(c)
+		if err != nil {
+			var rateErr *services.RateLimitError
+			if errors.As(err, &rateErr) {
+				c.Header("Retry-After", strconv.Itoa(rateErr.RetryAfterSeconds()))
+				c.JSON(http.StatusTooManyRequests, gin.H{"error": rateErr.Error(), "retry_after": rateErr.RetryAfterSeconds()})
+				return
+			}
+			c.JSON(loginErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		state, err := startAuthSession(c, service, user)
+		if err != nil {
+			log.Println("Error saving session:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Session save error"})
+			return
+		}
+
+		switch state {
+		case services.TwoFactorPending:
+			c.JSON(http.StatusOK, gin.H{"two_factor_required": true})
+		case services.TwoFactorSetup:
+			c.JSON(http.StatusOK, gin.H{"user_id": user.ID, "username": user.Username, "two_factor_setup_required": true})
+		default:
+			c.JSON(http.StatusOK, gin.H{"user_id": user.ID, "username": user.Username})
+		}
+	}
+}
+
+// ChangePasswordHandler godoc
+//	@Summary		Change the password
+//	@Description	Replaces the current user's password after checking the current one. Every other session is signed out and all access tokens are revoked. Accounts that have not registered a password yet use /auth/register.
+//	@Tags			account
+//	@Accept			json
+//	@Accept			x-www-form-urlencoded
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			request	body	services.ChangePasswordRequest	true	"Current and new password"
+//	@Success		204
+//	@Failure		400	{object}	map[string]interface{}	"Missing current password or weak new password"
+//	@Failure		403	{object}	map[string]interface{}	"Wrong current password, or request was authenticated with an access token"
+//	@Failure		409	{object}	map[string]interface{}	"Account has no password yet"
+//	@Failure		429	{object}	map[string]interface{}	"Too many failed attempts"
+//	@Failure		500	{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/account/password [put]
+func ChangePasswordHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reset, err := service.ChangePassword(c)
+		if err != nil {
+			var rateErr *services.RateLimitError
+			if errors.As(err, &rateErr) {
+				c.Header("Retry-After", strconv.Itoa(rateErr.RetryAfterSeconds()))
+				c.JSON(http.StatusTooManyRequests, gin.H{"error": rateErr.Error(), "retry_after": rateErr.RetryAfterSeconds()})
+				return
+			}
+			c.JSON(changePasswordErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		credentialsRevoked(reset)
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// saveAuthSession signs the user in with a new auth-session, replacing the session of the request if there is one.
+// With setupTwoFactor the session can only enroll an authenticator until it confirms one.
+func saveAuthSession(c *gin.Context, user *models.Users, setupTwoFactor bool) error {
+	session, err := auth.Store.Get(c.Request, "auth-session")
+	if err != nil {
+		return err
+	}
+	if err := auth.Store.Renew(c.Request.Context(), session); err != nil {
+		return err
+	}
+	csrfToken, err := middleware.IssueCSRFToken(session)
+	if err != nil {
+		return err
+	}
+	session.Values[services.UserIDKey] = user.ID
+	session.Values["username"] = user.Username
+	if setupTwoFactor {
+		session.Values[services.TwoFactorSetupKey] = true
+	}
+	if err := session.Save(c.Request, c.Writer); err != nil {
+		return err
+	}
+	middleware.SetCSRFCookie(c, session, csrfToken)
+	return nil
+}
+
+func loginErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidCredentials):
+		return http.StatusUnauthorized
+	case errors.Is(err, services.ErrMissingCredentials):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func changePasswordErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrMissingCredentials), errors.Is(err, services.ErrWeakPassword):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrWrongPassword), errors.Is(err, services.ErrSessionRequired):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrNotRegistered):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrUserNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
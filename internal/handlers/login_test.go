@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/auth"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/ratelimit"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/kontentski/chat/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func performLogin(service services.ChatRoomService, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.RemoteAddr = "203.0.113.7:51000"
+	LoginHandler(service)(c)
+	return w
+}
+
+func TestLoginHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth.Store = storage.NewSessionStore(storage.NewMockSessionBackend(), []byte("test-secret"))
+	_, mockRepo, service := initTest()
+	mockRepo.On("GetTOTP", mock.Anything, mock.Anything).Return(nil, pgx.ErrNoRows)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("Secret123"), bcrypt.MinCost)
+	mockRepo.On("GetUserByUsername", mock.Anything, "alice").
+		Return(&models.Users{ID: 5, Username: "alice", Password: string(hash)}, nil)
+
+	w := performLogin(service, `{"username":"alice","password":"Secret123"}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Set-Cookie"), "auth-session=")
+	assert.NotContains(t, w.Body.String(), string(hash))
+}
+
+func TestLoginHandlerLocksOutAfterFailures(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth.Store = storage.NewSessionStore(storage.NewMockSessionBackend(), []byte("test-secret"))
+	_, mockRepo, service := initTest()
+	mockRepo.On("GetTOTP", mock.Anything, mock.Anything).Return(nil, pgx.ErrNoRows)
+
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	accounts := ratelimit.NewLockout(2, time.Minute, time.Hour)
+	accounts.Now = func() time.Time { return now }
+	service.LoginGuard = services.LoginGuard{Accounts: accounts}
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("Secret123"), bcrypt.MinCost)
+	mockRepo.On("GetUserByUsername", mock.Anything, "alice").
+		Return(&models.Users{ID: 5, Username: "alice", Password: string(hash)}, nil)
+	mockRepo.On("GetUserByUsername", mock.Anything, "nobody").Return(nil, pgx.ErrNoRows)
+
+	assert.Equal(t, http.StatusUnauthorized, performLogin(service, `{"username":"alice","password":"wrong"}`).Code)
+	assert.Equal(t, http.StatusUnauthorized, performLogin(service, `{"username":"alice","password":"wrong"}`).Code)
+
+	// Even the right password is refused while the account is locked
+	w := performLogin(service, `{"username":"alice","password":"Secret123"}`)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+	// Unknown usernames fail the same way
+	assert.Equal(t, http.StatusUnauthorized, performLogin(service, `{"username":"nobody","password":"wrong"}`).Code)
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, http.StatusOK, performLogin(service, `{"username":"alice","password":"Secret123"}`).Code)
+	mockRepo.AssertNumberOfCalls(t, "GetUserByUsername", 4)
+}
+
+func TestLoginHandlerLocksOutIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth.Store = storage.NewSessionStore(storage.NewMockSessionBackend(), []byte("test-secret"))
+	_, mockRepo, service := initTest()
+	service.LoginGuard = services.LoginGuard{IPs: ratelimit.NewLockout(1, time.Minute, time.Hour)}
+
+	mockRepo.On("GetUserByUsername", mock.Anything, "nobody").Return(nil, pgx.ErrNoRows)
+
+	assert.Equal(t, http.StatusUnauthorized, performLogin(service, `{"username":"nobody","password":"wrong"}`).Code)
+	assert.Equal(t, http.StatusTooManyRequests, performLogin(service, `{"username":"alice","password":"Secret123"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, performLogin(service, `{"username":"alice"}`).Code)
+}
+
+func changePasswordEngine(service services.ChatRoomService, sessionID uint) *gin.Engine {
+	engine := gin.New()
+	engine.PUT("/api/account/password", func(c *gin.Context) {
+		c.Set(services.UserIDKey, uint(5))
+		c.Set(services.SessionIDKey, sessionID)
+	}, ChangePasswordHandler(service))
+	return engine
+}
+
+func TestChangePasswordHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	Broadcast = make(chan models.Messages, 10)
+	_, mockRepo, service := initTest()
+	engine := changePasswordEngine(service, 3)
+
+	oldHash, _ := bcrypt.GenerateFromPassword([]byte("Secret123"), bcrypt.MinCost)
+	mockRepo.On("GetUserByID", mock.Anything, uint(5)).
+		Return(&models.Users{ID: 5, Username: "alice", Password: string(oldHash)}, nil)
+
+	w := twoFactorRequest(engine, http.MethodPut, "/api/account/password", "", `{"current_password":"wrong","new_password":"NewSecret456"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = twoFactorRequest(engine, http.MethodPut, "/api/account/password", "", `{"current_password":"Secret123","new_password":"weak"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	var newHash string
+	mockRepo.On("UpdatePassword", mock.Anything, uint(5), string(oldHash), mock.Anything).
+		Run(func(args mock.Arguments) { newHash = args.String(3) }).Return(true, nil)
+	mockRepo.On("RevokeSessions", mock.Anything, uint(5), uint(3)).Return([]uint{1, 2}, nil)
+	mockRepo.On("RevokeAccessTokens", mock.Anything, uint(5)).Return([]uint{7}, nil)
+
+	w = twoFactorRequest(engine, http.MethodPut, "/api/account/password", "", `{"current_password":"Secret123","new_password":"NewSecret456"}`)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(newHash), []byte("NewSecret456")))
+
+	revoked := <-Broadcast
+	assert.Equal(t, models.EventSessionRevoked, revoked.Type)
+	assert.Equal(t, []uint{1, 2}, revoked.Data)
+	revoked = <-Broadcast
+	assert.Equal(t, models.EventAccessTokenRevoked, revoked.Type)
+	assert.Equal(t, []uint{7}, revoked.Data)
+}
+
+func TestChangePasswordHandlerRejectsAccessTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, mockRepo, service := initTest()
+	engine := gin.New()
+	engine.PUT("/api/account/password", func(c *gin.Context) {
+		c.Set(services.UserIDKey, uint(5))
+		c.Set(services.AccessTokenIDKey, uint(9))
+	}, ChangePasswordHandler(service))
+
+	w := twoFactorRequest(engine, http.MethodPut, "/api/account/password", "", `{"current_password":"Secret123","new_password":"NewSecret456"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockRepo.AssertNotCalled(t, "GetUserByID", mock.Anything, mock.Anything)
+}
+
+func TestRegisterPostHandlerRejectsRegisteredAccounts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth.Store = storage.NewSessionStore(storage.NewMockSessionBackend(), []byte("test-secret"))
+	cookie := sessionCookie(t, map[interface{}]interface{}{"userID": uint(5), "username": "alice"})
+
+	engine := gin.New()
+	engine.POST("/auth/register", RegisterPostHandler)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/auth/register", strings.NewReader("username=mallory&password=NewSecret456"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Cookie", cookie)
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/services"
+)
+
+// GetChatRoomMembersHandler godoc
+//	@Summary		List chat room members
+//	@Description	Lists the members of a chat room with their profile, role and presence. Only members may list a room.
+//	@Tags			chatrooms
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			chatRoomID	path		int	true	"Chat Room ID"
+//	@Param			limit		query		int	false	"Page size (default 50, max 200)"
+//	@Param			offset		query		int	false	"Number of members to skip"
+//	@Success		200			{object}	services.ChatRoomMembersResponse
+//	@Failure		401			{object}	map[string]interface{}	"Unauthorized"
+//	@Failure		403			{object}	map[string]interface{}	"Not a member of the chat room"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/chatrooms/{chatRoomID}/members [get]
+func GetChatRoomMembersHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		response, err := service.GetChatRoomMembers(c)
+		if err != nil {
+			if errors.Is(err, services.ErrNotAuthorized) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+
+		for i := range response.Members {
+			response.Members[i].Online = isUserOnline(response.Members[i].UserID)
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// UpdateChatRoomSettingsHandler godoc
+//	@Summary		Update personal room settings
+//	@Description	Partially updates the current user's settings for a chat room: muted-until, favorite, archived and notification level (all, mentions or none)
+//	@Tags			chatrooms
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			chatRoomID	path		int									true	"Chat Room ID"
+//	@Param			request		body		services.ChatRoomSettingsRequest	true	"Settings to change"
+//	@Success		200			{object}	models.ChatRoomMemberSettings
+//	@Failure		400			{object}	map[string]interface{}	"Invalid settings"
+//	@Failure		403			{object}	map[string]interface{}	"Not a member of the chat room"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/chatrooms/{chatRoomID}/settings [patch]
+func UpdateChatRoomSettingsHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		settings, err := service.UpdateChatRoomSettings(c)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrNotAuthorized):
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			case errors.Is(err, services.ErrInvalidNotificationLevel), errors.Is(err, services.ErrInvalidMutedUntil):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+		c.JSON(http.StatusOK, settings)
+	}
+}
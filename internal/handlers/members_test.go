@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetChatRoomMembersHandler(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+
+		userID := uint(123)
+		members := []models.ChatRoomMemberProfile{
+			{ChatRoomID: 7, UserID: 123, Username: "alice", Role: models.RoleAdmin, JoinedAt: time.Date(2024, 8, 5, 0, 0, 0, 0, time.UTC)},
+			{ChatRoomID: 7, UserID: 456, Username: "bob", Role: models.RoleMember, JoinedAt: time.Date(2024, 8, 6, 0, 0, 0, 0, time.UTC)},
+		}
+
+		mockRepo.On("IsUserInChatRoom", userID, uint(7)).Return(true)
+		mockRepo.On("GetChatRoomMembers", mock.Anything, uint(7), 2, 0).Return(members, nil)
+		mockRepo.On("CountChatRoomMembers", mock.Anything, uint(7)).Return(5, nil)
+		mockRepo.On("GetStatusSettingsForUsers", mock.Anything, []uint{123, 456}).Return([]models.StatusSettings(nil), nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "chatRoomID", Value: "7"}}
+		c.Set(services.UserIDKey, userID)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/chatrooms/7/members?limit=2", nil)
+
+		GetChatRoomMembersHandler(service)(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response services.ChatRoomMembersResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, members, response.Members)
+		assert.Equal(t, 5, response.Total)
+		assert.Equal(t, 2, response.Limit)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Not a member", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+
+		mockRepo.On("IsUserInChatRoom", uint(123), uint(7)).Return(false)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "chatRoomID", Value: "7"}}
+		c.Set(services.UserIDKey, uint(123))
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/chatrooms/7/members", nil)
+
+		GetChatRoomMembersHandler(service)(c)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockRepo.AssertNotCalled(t, "GetChatRoomMembers")
+	})
+}
+
+func TestIsUserOnlineWhileClientsChange(t *testing.T) {
+	clients = map[*websocket.Conn]*wsClient{}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn := &websocket.Conn{}
+			clientsMu.Lock()
+			clients[conn] = &wsClient{userID: 9}
+			clientsMu.Unlock()
+			isUserOnline(9)
+			clientsMu.Lock()
+			delete(clients, conn)
+			clientsMu.Unlock()
+		}()
+		isUserOnline(9)
+	}
+	wg.Wait()
+	assert.False(t, isUserOnline(9))
+}
+
+func TestUpdateChatRoomSettingsHandler(t *testing.T) {
+	newContext := func(w *httptest.ResponseRecorder, body string) *gin.Context {
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "chatRoomID", Value: "7"}}
+		c.Set(services.UserIDKey, uint(123))
+		c.Request, _ = http.NewRequest(http.MethodPatch, "/api/chatrooms/7/settings", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		return c
+	}
+
+	t.Run("Partial update keeps other settings", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		current := &models.ChatRoomMemberSettings{Favorite: true, NotificationLevel: models.NotificationAll}
+
+		mockRepo.On("IsUserInChatRoom", uint(123), uint(7)).Return(true)
+		mockRepo.On("GetChatRoomSettings", mock.Anything, uint(7), uint(123)).Return(current, nil)
+		mockRepo.On("UpsertChatRoomSettings", mock.Anything, uint(7), uint(123), mock.Anything).Return(nil)
+
+		w := httptest.NewRecorder()
+		UpdateChatRoomSettingsHandler(service)(newContext(w, `{"archived":true,"notification_level":"mentions"}`))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response models.ChatRoomMemberSettings
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.True(t, response.Favorite)
+		assert.True(t, response.Archived)
+		assert.Equal(t, models.NotificationMentions, response.NotificationLevel)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid notification level", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		mockRepo.On("IsUserInChatRoom", uint(123), uint(7)).Return(true)
+		mockRepo.On("GetChatRoomSettings", mock.Anything, uint(7), uint(123)).Return(&models.ChatRoomMemberSettings{NotificationLevel: models.NotificationAll}, nil)
+
+		w := httptest.NewRecorder()
+		UpdateChatRoomSettingsHandler(service)(newContext(w, `{"notification_level":"loud"}`))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockRepo.AssertNotCalled(t, "UpsertChatRoomSettings")
+	})
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+)
+
+// ModerateMemberHandler godoc
+//	@Summary		Moderate a chat room member
+//	@Description	Kick, ban, unban, mute or unmute a member, or make them an admin or a regular member again. Only room admins can moderate, and other admins can only be demoted, never kicked, banned or muted. Admins cannot moderate themselves, so a room always keeps an admin. Every action is written to the moderation log.
+//	@Tags			moderation
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			chatRoomID	path		int							true	"Chat Room ID"
+//	@Param			userID		path		int							true	"Target user ID"
+//	@Param			request		body		services.ModerationRequest	false	"Reason, and duration_seconds for mutes"
+//	@Success		200			{object}	services.ModerationResponse
+//	@Failure		400			{object}	map[string]interface{}	"Invalid request"
+//	@Failure		403			{object}	map[string]interface{}	"Not a room admin"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/chatrooms/{chatRoomID}/members/{userID}/kick [post]
+//	@Router			/api/chatrooms/{chatRoomID}/members/{userID}/ban [post]
+//	@Router			/api/chatrooms/{chatRoomID}/members/{userID}/ban [delete]
+//	@Router			/api/chatrooms/{chatRoomID}/members/{userID}/mute [post]
+//	@Router			/api/chatrooms/{chatRoomID}/members/{userID}/mute [delete]
+//	@Router			/api/chatrooms/{chatRoomID}/members/{userID}/admin [post]
+//	@Router			/api/chatrooms/{chatRoomID}/members/{userID}/admin [delete]
+func ModerateMemberHandler(service services.ChatRoomService, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		response, err := service.ModerateMember(c, action)
+		if err != nil {
+			c.JSON(moderationErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		event := models.Messages{
+			ChatRoomID: response.Action.ChatRoomID,
+			SenderID:   response.Action.ModeratorID,
+			Type:       models.EventModeration,
+			Data:       response.Action,
+		}
+		Broadcast <- event
+
+		if action == models.ModerationKick || action == models.ModerationBan {
+			// The target is no longer a member, so tell them directly
+			event.RecipientID = response.Action.TargetUserID
+			Broadcast <- event
+
+			if response.Member != nil {
+				Broadcast <- models.Messages{
+					ChatRoomID: response.Action.ChatRoomID,
+					SenderID:   response.Action.TargetUserID,
+					Type:       models.EventMemberLeave,
+					Data:       response.Member,
+				}
+			}
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// GetModerationLogHandler godoc
+//	@Summary		Get the moderation log
+//	@Description	Lists the moderation actions taken in a chat room, newest first. Only room admins can read it.
+//	@Tags			moderation
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			chatRoomID	path		int	true	"Chat Room ID"
+//	@Param			limit		query		int	false	"Page size (default 50, max 200)"
+//	@Param			offset		query		int	false	"Number of entries to skip"
+//	@Success		200			{array}		models.ModerationAction
+//	@Failure		403			{object}	map[string]interface{}	"Not a room admin"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/chatrooms/{chatRoomID}/moderation-log [get]
+func GetModerationLogHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actions, err := service.GetModerationLog(c)
+		if err != nil {
+			c.JSON(moderationErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, actions)
+	}
+}
+
+func moderationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrNotAuthorized), errors.Is(err, services.ErrNotRoomAdmin):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrInvalidModeration),
+		errors.Is(err, services.ErrTargetNotMember),
+		errors.Is(err, services.ErrTargetNotAdmin),
+		errors.Is(err, services.ErrMuteDurationNeeded):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// SetSlowModeHandler godoc
+//	@Summary		Set slow mode
+//	@Description	Sets the minimum number of seconds between two messages of the same member. Zero turns slow mode off. Only room admins can change it, and admins are exempt from it.
+//	@Tags			moderation
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			chatRoomID	path		int							true	"Chat Room ID"
+//	@Param			request		body		services.SlowModeRequest	true	"Slow mode interval"
+//	@Success		200			{object}	models.ChatRooms
+//	@Failure		400			{object}	map[string]interface{}	"Invalid request"
+//	@Failure		403			{object}	map[string]interface{}	"Not a room admin"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/chatrooms/{chatRoomID}/slow-mode [put]
+func SetSlowModeHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		room, err := service.SetSlowMode(c)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidSlowMode) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			} else {
+				c.JSON(moderationErrorStatus(err), gin.H{"error": err.Error()})
+			}
+			return
+		}
+
+		roomUpdated(room)
+
+		c.JSON(http.StatusOK, room)
+	}
+}
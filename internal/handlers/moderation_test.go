@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/kontentski/chat/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newModerationContext(w *httptest.ResponseRecorder, method, body string) *gin.Context {
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "chatRoomID", Value: "7"}, {Key: "userID", Value: "456"}}
+	c.Set(services.UserIDKey, uint(123))
+	c.Request, _ = http.NewRequest(method, "/api/chatrooms/7/members/456/mute", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	return c
+}
+
+func TestModerateMemberHandler(t *testing.T) {
+	Broadcast = make(chan models.Messages, 100)
+
+	t.Run("Admin mutes member", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		mockRepo.On("GetChatRoomMember", mock.Anything, uint(7), uint(123)).Return(&models.ChatRoomMemberProfile{UserID: 123, Role: models.RoleAdmin}, nil)
+		mockRepo.On("GetChatRoomMember", mock.Anything, uint(7), uint(456)).Return(&models.ChatRoomMemberProfile{UserID: 456, Role: models.RoleMember}, nil)
+		mockRepo.On("ApplyModerationAction", mock.Anything, mock.MatchedBy(func(a *models.ModerationAction) bool {
+			return a.Action == models.ModerationMute && a.TargetUserID == 456 && a.ExpiresAt != nil && a.Reason == "spam"
+		})).Return(nil)
+
+		w := httptest.NewRecorder()
+		c := newModerationContext(w, http.MethodPost, `{"reason":"spam","duration_seconds":600}`)
+
+		ModerateMemberHandler(service, models.ModerationMute)(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		event := <-Broadcast
+		assert.Equal(t, models.EventModeration, event.Type)
+		assert.Equal(t, uint(7), event.ChatRoomID)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Mute without duration", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		mockRepo.On("GetChatRoomMember", mock.Anything, uint(7), uint(123)).Return(&models.ChatRoomMemberProfile{UserID: 123, Role: models.RoleAdmin}, nil)
+		mockRepo.On("GetChatRoomMember", mock.Anything, uint(7), uint(456)).Return(&models.ChatRoomMemberProfile{UserID: 456, Role: models.RoleMember}, nil)
+
+		w := httptest.NewRecorder()
+		c := newModerationContext(w, http.MethodPost, `{"reason":"spam"}`)
+
+		ModerateMemberHandler(service, models.ModerationMute)(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockRepo.AssertNotCalled(t, "ApplyModerationAction")
+	})
+
+	t.Run("Non-admin is forbidden", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		mockRepo.On("GetChatRoomMember", mock.Anything, uint(7), uint(123)).Return(&models.ChatRoomMemberProfile{UserID: 123, Role: models.RoleMember}, nil)
+
+		w := httptest.NewRecorder()
+		c := newModerationContext(w, http.MethodPost, "")
+
+		ModerateMemberHandler(service, models.ModerationKick)(c)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockRepo.AssertNotCalled(t, "ApplyModerationAction")
+	})
+
+	t.Run("Admins cannot be moderated", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		mockRepo.On("GetChatRoomMember", mock.Anything, uint(7), uint(123)).Return(&models.ChatRoomMemberProfile{UserID: 123, Role: models.RoleAdmin}, nil)
+		mockRepo.On("GetChatRoomMember", mock.Anything, uint(7), uint(456)).Return(&models.ChatRoomMemberProfile{UserID: 456, Role: models.RoleAdmin}, nil)
+
+		w := httptest.NewRecorder()
+		c := newModerationContext(w, http.MethodPost, "")
+
+		ModerateMemberHandler(service, models.ModerationBan)(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockRepo.AssertNotCalled(t, "ApplyModerationAction")
+	})
+
+	t.Run("Admin promotes member", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		mockRepo.On("GetChatRoomMember", mock.Anything, uint(7), uint(123)).Return(&models.ChatRoomMemberProfile{UserID: 123, Role: models.RoleAdmin}, nil)
+		mockRepo.On("GetChatRoomMember", mock.Anything, uint(7), uint(456)).Return(&models.ChatRoomMemberProfile{UserID: 456, Role: models.RoleMember}, nil)
+		mockRepo.On("ApplyModerationAction", mock.Anything, mock.MatchedBy(func(a *models.ModerationAction) bool {
+			return a.Action == models.ModerationPromote && a.TargetUserID == 456
+		})).Return(nil)
+
+		w := httptest.NewRecorder()
+		c := newModerationContext(w, http.MethodPost, "")
+
+		ModerateMemberHandler(service, models.ModerationPromote)(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"role":"admin"`)
+		event := <-Broadcast
+		assert.Equal(t, models.EventModeration, event.Type)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Admin demotes another admin", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		mockRepo.On("GetChatRoomMember", mock.Anything, uint(7), uint(123)).Return(&models.ChatRoomMemberProfile{UserID: 123, Role: models.RoleAdmin}, nil)
+		mockRepo.On("GetChatRoomMember", mock.Anything, uint(7), uint(456)).Return(&models.ChatRoomMemberProfile{UserID: 456, Role: models.RoleAdmin}, nil)
+		mockRepo.On("ApplyModerationAction", mock.Anything, mock.MatchedBy(func(a *models.ModerationAction) bool {
+			return a.Action == models.ModerationDemote && a.TargetUserID == 456
+		})).Return(nil)
+
+		w := httptest.NewRecorder()
+		c := newModerationContext(w, http.MethodDelete, "")
+
+		ModerateMemberHandler(service, models.ModerationDemote)(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"role":"member"`)
+		<-Broadcast
+	})
+
+	t.Run("Only admins can be demoted", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		mockRepo.On("GetChatRoomMember", mock.Anything, uint(7), uint(123)).Return(&models.ChatRoomMemberProfile{UserID: 123, Role: models.RoleAdmin}, nil)
+		mockRepo.On("GetChatRoomMember", mock.Anything, uint(7), uint(456)).Return(&models.ChatRoomMemberProfile{UserID: 456, Role: models.RoleMember}, nil)
+
+		w := httptest.NewRecorder()
+		c := newModerationContext(w, http.MethodDelete, "")
+
+		ModerateMemberHandler(service, models.ModerationDemote)(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockRepo.AssertNotCalled(t, "ApplyModerationAction")
+	})
+}
+
+func TestSlowMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	Broadcast = make(chan models.Messages, 100)
+
+	t.Run("Member sending too soon gets 429", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		mockRepo.On("IsUserInChatRoom", uint(123), uint(7)).Return(true)
+		mockRepo.On("IsUserInChatRoom", uint(123), uint(8)).Return(true)
+		mockRepo.On("GetMessage", mock.Anything, uint(7), uint(12)).Return(&models.Messages{MessageID: 12, ChatRoomID: 7, SenderID: 456, Content: "hi"}, nil)
+		mockRepo.On("GetMutedUntil", mock.Anything, uint(8), uint(123)).Return(time.Time{}, nil)
+		mockRepo.On("GetChatRoomMember", mock.Anything, uint(8), uint(123)).Return(&models.ChatRoomMemberProfile{UserID: 123, Role: models.RoleMember}, nil)
+		mockRepo.On("GetSlowModeState", mock.Anything, uint(8), uint(123)).Return(30*time.Second, 10*time.Second, nil)
+
+		w := httptest.NewRecorder()
+		ForwardMessageHandler(service)(newForwardContext(w, `{"target_chat_room_id":8}`))
+
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "20", w.Header().Get("Retry-After"))
+		assert.Contains(t, w.Body.String(), `"retry_after":20`)
+		assert.Contains(t, w.Body.String(), "slow mode")
+		mockRepo.AssertNotCalled(t, "GetChatRoom", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Admins are exempt", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		mockRepo.On("GetChatRoomMember", mock.Anything, uint(8), uint(123)).Return(&models.ChatRoomMemberProfile{UserID: 123, Role: models.RoleAdmin}, nil)
+
+		assert.NoError(t, service.CheckSendRate(context.Background(), 123, 8))
+		mockRepo.AssertNotCalled(t, "GetSlowModeState", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Setting slow mode broadcasts the whole room", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		mediaStorage := service.MediaStorage.(*storage.MockBucketStorage)
+		mockRepo.On("GetChatRoomMember", mock.Anything, uint(7), uint(123)).Return(&models.ChatRoomMemberProfile{UserID: 123, Role: models.RoleAdmin}, nil)
+		mockRepo.On("SetSlowMode", mock.Anything, uint(7), 30).Return(nil)
+		mockRepo.On("GetChatRoom", mock.Anything, uint(7)).Return(&models.ChatRooms{
+			ID: 7, Name: "General", Description: "Everything", Type: "public", SlowModeSeconds: 30, AvatarPath: "chatrooms/7/meta/avatar-1.png",
+		}, nil)
+		mediaStorage.On("GenerateSignedURL", "chatrooms/7/meta/avatar-1.png").Return("http://signed.url/avatar.png", nil)
+
+		w := httptest.NewRecorder()
+		c := newModerationContext(w, http.MethodPut, `{"seconds":30}`)
+		SetSlowModeHandler(service)(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		event := <-Broadcast
+		assert.Equal(t, models.EventRoomUpdated, event.Type)
+		body, _ := json.Marshal(event.Data)
+		assert.Contains(t, string(body), `"name":"General"`)
+		assert.Contains(t, string(body), `"slow_mode_seconds":30`)
+		assert.Contains(t, string(body), `"avatar_url":"http://signed.url/avatar.png"`)
+	})
+}
+
+func TestSocketMessageIgnoresClaimedSender(t *testing.T) {
+	var frame map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"chat_room_id":8,"sender_id":456,"content":"hi"}`), &frame))
+
+	msg, err := socketMessage(frame, 123)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(123), msg.SenderID)
+	assert.Equal(t, uint(8), msg.ChatRoomID)
+	assert.Equal(t, "hi", msg.Content)
+
+	_, err = socketMessage(map[string]interface{}{"content": "hi"}, 123)
+	assert.Error(t, err)
+}
+
+func TestSetDMFlagIgnoresClaimedFlag(t *testing.T) {
+	_, mockRepo, _ := initTest()
+	mockRepo.On("GetChatRoom", mock.Anything, uint(8)).Return(&models.ChatRooms{ID: 8, IsDM: false}, nil)
+	mockRepo.On("GetChatRoom", mock.Anything, uint(9)).Return(&models.ChatRooms{ID: 9, IsDM: true}, nil)
+
+	var frame map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"chat_room_id":8,"content":"hi","is_dm":true}`), &frame))
+	msg, err := socketMessage(frame, 123)
+	assert.NoError(t, err)
+	assert.NoError(t, setDMFlag(context.Background(), mockRepo, &msg))
+	assert.False(t, msg.IsDM)
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"chat_room_id":9,"content":"hi","is_dm":false}`), &frame))
+	msg, err = socketMessage(frame, 123)
+	assert.NoError(t, err)
+	assert.NoError(t, setDMFlag(context.Background(), mockRepo, &msg))
+	assert.True(t, msg.IsDM)
+}
@@ -4,12 +4,12 @@ import (
 	"context"
 	"log"
 	"net/http"
-	"unicode"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kontentski/chat/internal/auth"
 	"github.com/kontentski/chat/internal/database"
 	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -17,6 +17,11 @@ func RegisterHandler(c *gin.Context) {
 	c.File("./homepage/register/register.html")
 }
 
+// errAlreadyRegistered answers registrations of accounts that already picked a username and password.
+var errAlreadyRegistered = gin.H{"error": "account is already registered, change the password with PUT /api/account/password"}
+
+// RegisterPostHandler sets the username and password of an account created by a provider sign-in.
+// It only completes registrations; registered accounts change their password with ChangePasswordHandler.
 func RegisterPostHandler(c *gin.Context) {
 	username := c.PostForm("username")
 	password := c.PostForm("password")
@@ -33,10 +38,14 @@ func RegisterPostHandler(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
+	if current, _ := session.Values["username"].(string); current != services.UnregisteredUsername {
+		c.JSON(http.StatusConflict, errAlreadyRegistered)
+		return
+	}
 
 	// Validate username and password
-	if !isValidPassword(password) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Password must be 8 characters long and contain at least one uppercase letter and one digit"})
+	if !services.ValidPassword(password) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrWeakPassword.Error()})
 		return
 	}
 
@@ -45,7 +54,7 @@ func RegisterPostHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Username already taken"})
 		return
 	}
-	if username == "system_default" {
+	if username == services.UnregisteredUsername {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "reserved username"})
 		return
 	}
@@ -60,11 +69,19 @@ func RegisterPostHandler(c *gin.Context) {
 	}
 
 	// Save the new user and handle both return values
-	err = UpdateUser(user)
+	registered, err := completeRegistration(user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
 		return
 	}
+	if !registered {
+		c.JSON(http.StatusConflict, errAlreadyRegistered)
+		return
+	}
+	session.Values["username"] = user.Username
+	if err := session.Save(c.Request, c.Writer); err != nil {
+		log.Printf("Error saving session of user %d: %v", user.ID, err)
+	}
 
 	// Log the saved user's details for verification
 	log.Printf("User saved successfully: ID=%d, Username=%s", user.ID, user.Username)
@@ -79,19 +96,6 @@ func UsernameExists(username string) (bool, error) {
 	return count > 0, err
 }
 
-func isValidPassword(password string) bool {
-	var hasUpper, hasDigit bool
-	for _, char := range password {
-		switch {
-		case unicode.IsUpper(char):
-			hasUpper = true
-		case unicode.IsDigit(char):
-			hasDigit = true
-		}
-	}
-	return hasUpper && hasDigit && len(password) >= 8
-}
-
 func hashPassword(password string) string {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -102,8 +106,10 @@ func hashPassword(password string) string {
 
 // In storage package
 
-func UpdateUser(user *models.Users) error {
-	query := `UPDATE users SET username = $1, password = $2 WHERE id = $3`
-	_, err := database.DB.Exec(context.Background(), query, user.Username, user.Password, user.ID)
-	return err
+// completeRegistration sets the username and password of the user if the account still has the
+// placeholder username, and reports whether it did.
+func completeRegistration(user *models.Users) (bool, error) {
+	query := `UPDATE users SET username = $1, password = $2 WHERE id = $3 AND username = $4`
+	tag, err := database.DB.Exec(context.Background(), query, user.Username, user.Password, user.ID, services.UnregisteredUsername)
+	return tag.RowsAffected() > 0, err
 }
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+)
+
+// GetPinnedMessagesHandler godoc
+//	@Summary		List pinned messages
+//	@Description	Lists the pinned messages of a chat room in pin order, with who pinned each one and when
+//	@Tags			pins
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			chatRoomID	path		int	true	"Chat Room ID"
+//	@Success		200			{array}		models.PinnedMessage
+//	@Failure		403			{object}	map[string]interface{}	"Not a member of the chat room"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/chatrooms/{chatRoomID}/pins [get]
+func GetPinnedMessagesHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pins, err := service.GetPinnedMessages(c)
+		if err != nil {
+			c.JSON(pinErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, pins)
+	}
+}
+
+// PinMessageHandler godoc
+//	@Summary		Pin a message
+//	@Description	Pins a message of the chat room. Only room admins can pin. Pinning an already pinned message returns the original pin.
+//	@Tags			pins
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			chatRoomID	path		int					true	"Chat Room ID"
+//	@Param			request		body		services.PinRequest	true	"Message to pin"
+//	@Success		200			{object}	models.PinnedMessage
+//	@Failure		400,404		{object}	map[string]interface{}
+//	@Failure		403			{object}	map[string]interface{}	"Not a room admin"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/chatrooms/{chatRoomID}/pins [post]
+func PinMessageHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pin, err := service.PinMessage(c)
+		if err != nil {
+			c.JSON(pinErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		Broadcast <- models.Messages{
+			MessageID:  pin.MessageID,
+			ChatRoomID: pin.ChatRoomID,
+			SenderID:   pin.PinnedBy.ID,
+			Type:       models.EventPin,
+			Data:       pin,
+		}
+
+		c.JSON(http.StatusOK, pin)
+	}
+}
+
+// UnpinMessageHandler godoc
+//	@Summary		Unpin a message
+//	@Description	Removes a pin from the chat room. Only room admins can unpin.
+//	@Tags			pins
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			chatRoomID	path		int	true	"Chat Room ID"
+//	@Param			messageID	path		int	true	"Pinned message ID"
+//	@Success		200			{object}	map[string]interface{}
+//	@Failure		403			{object}	map[string]interface{}	"Not a room admin"
+//	@Failure		404			{object}	map[string]interface{}	"Message is not pinned"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/chatrooms/{chatRoomID}/pins/{messageID} [delete]
+func UnpinMessageHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pin, err := service.UnpinMessage(c)
+		if err != nil {
+			c.JSON(pinErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		Broadcast <- models.Messages{
+			MessageID:  pin.MessageID,
+			ChatRoomID: pin.ChatRoomID,
+			Type:       models.EventUnpin,
+			Data:       pin,
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Message unpinned successfully"})
+	}
+}
+
+func pinErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrMessageNotFound), errors.Is(err, services.ErrNotPinned):
+		return http.StatusNotFound
+	default:
+		return moderationErrorStatus(err)
+	}
+}
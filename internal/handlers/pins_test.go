@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/kontentski/chat/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newPinContext(w *httptest.ResponseRecorder, method, body string, params gin.Params) *gin.Context {
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(gin.Params{{Key: "chatRoomID", Value: "7"}}, params...)
+	c.Set(services.UserIDKey, uint(123))
+	c.Request, _ = http.NewRequest(method, "/api/chatrooms/7/pins", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	return c
+}
+
+func TestPinMessageHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	Broadcast = make(chan models.Messages, 100)
+	pinnedAt := time.Date(2024, 8, 5, 10, 0, 0, 0, time.UTC)
+
+	t.Run("Already pinned message keeps the original pin", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		mockRepo.On("GetChatRoomMember", mock.Anything, uint(7), uint(123)).Return(&models.ChatRoomMemberProfile{UserID: 123, Username: "alice", Role: models.RoleAdmin}, nil)
+		mockRepo.On("GetMessage", mock.Anything, uint(7), uint(12)).Return(&models.Messages{MessageID: 12, ChatRoomID: 7, SenderID: 456, Content: "hello"}, nil)
+		mockRepo.On("PinMessage", mock.Anything, uint(7), uint(12), uint(123)).Return(&models.PinnedMessage{
+			ChatRoomID: 7,
+			MessageID:  12,
+			PinnedBy:   models.Users{ID: 789, Username: "carol"},
+			PinnedAt:   pinnedAt,
+		}, nil)
+
+		w := httptest.NewRecorder()
+		PinMessageHandler(service)(newPinContext(w, http.MethodPost, `{"message_id":12}`, nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"pinned_at":"2024-08-05T10:00:00Z"`)
+		assert.Contains(t, w.Body.String(), `"username":"carol"`)
+		assert.Contains(t, w.Body.String(), `"content":"hello"`)
+
+		event := <-Broadcast
+		assert.Equal(t, models.EventPin, event.Type)
+		assert.Equal(t, uint(789), event.SenderID)
+		assert.Equal(t, pinnedAt, event.Data.(*models.PinnedMessage).PinnedAt)
+	})
+
+	t.Run("Non-admin is forbidden", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		mockRepo.On("GetChatRoomMember", mock.Anything, uint(7), uint(123)).Return(&models.ChatRoomMemberProfile{UserID: 123, Role: models.RoleMember}, nil)
+
+		w := httptest.NewRecorder()
+		PinMessageHandler(service)(newPinContext(w, http.MethodPost, `{"message_id":12}`, nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockRepo.AssertNotCalled(t, "PinMessage", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unknown message", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		mockRepo.On("GetChatRoomMember", mock.Anything, uint(7), uint(123)).Return(&models.ChatRoomMemberProfile{UserID: 123, Role: models.RoleAdmin}, nil)
+		mockRepo.On("GetMessage", mock.Anything, uint(7), uint(12)).Return(nil, pgx.ErrNoRows)
+
+		w := httptest.NewRecorder()
+		PinMessageHandler(service)(newPinContext(w, http.MethodPost, `{"message_id":12}`, nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestUnpinMessageHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	Broadcast = make(chan models.Messages, 100)
+	params := gin.Params{{Key: "messageID", Value: "12"}}
+
+	t.Run("Admin unpins", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		mockRepo.On("GetChatRoomMember", mock.Anything, uint(7), uint(123)).Return(&models.ChatRoomMemberProfile{UserID: 123, Role: models.RoleAdmin}, nil)
+		mockRepo.On("UnpinMessage", mock.Anything, uint(7), uint(12)).Return(true, nil)
+
+		w := httptest.NewRecorder()
+		UnpinMessageHandler(service)(newPinContext(w, http.MethodDelete, "", params))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		event := <-Broadcast
+		assert.Equal(t, models.EventUnpin, event.Type)
+		assert.Equal(t, uint(12), event.MessageID)
+	})
+
+	t.Run("Message that is not pinned", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		mockRepo.On("GetChatRoomMember", mock.Anything, uint(7), uint(123)).Return(&models.ChatRoomMemberProfile{UserID: 123, Role: models.RoleAdmin}, nil)
+		mockRepo.On("UnpinMessage", mock.Anything, uint(7), uint(12)).Return(false, nil)
+
+		w := httptest.NewRecorder()
+		UnpinMessageHandler(service)(newPinContext(w, http.MethodDelete, "", params))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Non-admin is forbidden", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		mockRepo.On("GetChatRoomMember", mock.Anything, uint(7), uint(123)).Return(&models.ChatRoomMemberProfile{UserID: 123, Role: models.RoleMember}, nil)
+
+		w := httptest.NewRecorder()
+		UnpinMessageHandler(service)(newPinContext(w, http.MethodDelete, "", params))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockRepo.AssertNotCalled(t, "UnpinMessage", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestGetPinnedMessagesHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Members see pins with signed media", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		mediaStorage := service.MediaStorage.(*storage.MockBucketStorage)
+		mockRepo.On("IsUserInChatRoom", uint(123), uint(7)).Return(true)
+		mockRepo.On("GetPinnedMessages", mock.Anything, uint(7)).Return([]models.PinnedMessage{
+			{ChatRoomID: 7, MessageID: 12, Message: models.Messages{MessageID: 12, Content: "hello"}},
+			{ChatRoomID: 7, MessageID: 13, Message: models.Messages{MessageID: 13, Content: "chatrooms/7/1.png", Type: "media"}},
+		}, nil)
+		mediaStorage.On("GenerateSignedURL", "chatrooms/7/1.png").Return("http://signed.url/1.png", nil)
+
+		w := httptest.NewRecorder()
+		GetPinnedMessagesHandler(service)(newPinContext(w, http.MethodGet, "", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var pins []models.PinnedMessage
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &pins))
+		assert.Len(t, pins, 2)
+		assert.Equal(t, "http://signed.url/1.png", pins[1].Message.Content)
+	})
+
+	t.Run("No pins is an empty list", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		mockRepo.On("IsUserInChatRoom", uint(123), uint(7)).Return(true)
+		mockRepo.On("GetPinnedMessages", mock.Anything, uint(7)).Return(nil, nil)
+
+		w := httptest.NewRecorder()
+		GetPinnedMessagesHandler(service)(newPinContext(w, http.MethodGet, "", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "[]", w.Body.String())
+	})
+
+	t.Run("Non-member is forbidden", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		mockRepo.On("IsUserInChatRoom", uint(123), uint(7)).Return(false)
+
+		w := httptest.NewRecorder()
+		GetPinnedMessagesHandler(service)(newPinContext(w, http.MethodGet, "", nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/kontentski/chat/internal/storage"
+)
+
+// CreatePollHandler godoc
+//	@Summary		Create a poll
+//	@Description	Posts a message of type "poll" to the chat room. The message content is the question and its data holds the poll. Posting is subject to mutes, slow mode and the send rate limit like any message.
+//	@Tags			polls
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			chatRoomID	path		int							true	"Chat Room ID"
+//	@Param			request		body		services.CreatePollRequest	true	"Poll definition"
+//	@Success		201			{object}	models.Messages
+//	@Failure		400			{object}	map[string]interface{}	"Invalid poll"
+//	@Failure		403			{object}	map[string]interface{}	"Not a member of the chat room or muted"
+//	@Failure		429			{object}	map[string]interface{}	"Sending too fast"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/chatrooms/{chatRoomID}/polls [post]
+func CreatePollHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		msg, err := service.PreparePoll(c)
+		if err != nil {
+			var rateErr *services.RateLimitError
+			if errors.As(err, &rateErr) {
+				c.Header("Retry-After", strconv.Itoa(rateErr.RetryAfterSeconds()))
+				c.JSON(http.StatusTooManyRequests, gin.H{"error": rateErr.Error(), "retry_after": rateErr.RetryAfterSeconds()})
+				return
+			}
+			c.JSON(pollErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		poll := msg.Data.(*models.Poll)
+		err = saveMessageToDB(msg, func(tx pgx.Tx) error {
+			poll.MessageID = msg.MessageID
+			return storage.CreatePollTx(c.Request.Context(), tx, poll)
+		})
+		if err != nil {
+			log.Printf("Error saving poll in chat room %d: %v", poll.ChatRoomID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create poll"})
+			return
+		}
+
+		Broadcast <- *msg
+
+		c.JSON(http.StatusCreated, msg)
+	}
+}
+
+// GetPollHandler godoc
+//	@Summary		Get a poll
+//	@Description	Returns a poll with its tallies and the current user's votes. Voters are listed only for polls that are not anonymous.
+//	@Tags			polls
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			chatRoomID	path		int	true	"Chat Room ID"
+//	@Param			pollID		path		int	true	"Poll ID"
+//	@Success		200			{object}	models.Poll
+//	@Failure		403			{object}	map[string]interface{}	"Not a member of the chat room"
+//	@Failure		404			{object}	map[string]interface{}	"Poll not found"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/chatrooms/{chatRoomID}/polls/{pollID} [get]
+func GetPollHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		poll, err := service.GetPoll(c)
+		if err != nil {
+			c.JSON(pollErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, poll)
+	}
+}
+
+// VotePollHandler godoc
+//	@Summary		Vote on a poll
+//	@Description	Replaces the current user's votes with the given options. Single choice polls take exactly one option. The new tallies are pushed to the room as a poll_updated event.
+//	@Tags			polls
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			chatRoomID	path		int							true	"Chat Room ID"
+//	@Param			pollID		path		int							true	"Poll ID"
+//	@Param			request		body		services.PollVoteRequest	true	"Chosen options"
+//	@Success		200			{object}	models.Poll
+//	@Failure		400			{object}	map[string]interface{}	"Invalid vote"
+//	@Failure		403			{object}	map[string]interface{}	"Not a member of the chat room"
+//	@Failure		404			{object}	map[string]interface{}	"Poll not found"
+//	@Failure		409			{object}	map[string]interface{}	"Poll is closed"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/chatrooms/{chatRoomID}/polls/{pollID}/votes [put]
+func VotePollHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return pollUpdateHandler(service, services.ChatRoomService.VotePoll)
+}
+
+// RetractPollVoteHandler godoc
+//	@Summary		Retract a poll vote
+//	@Description	Removes the current user's votes from a poll. The new tallies are pushed to the room as a poll_updated event.
+//	@Tags			polls
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			chatRoomID	path		int	true	"Chat Room ID"
+//	@Param			pollID		path		int	true	"Poll ID"
+//	@Success		200			{object}	models.Poll
+//	@Failure		403			{object}	map[string]interface{}	"Not a member of the chat room"
+//	@Failure		404			{object}	map[string]interface{}	"Poll not found"
+//	@Failure		409			{object}	map[string]interface{}	"Poll is closed"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/chatrooms/{chatRoomID}/polls/{pollID}/votes [delete]
+func RetractPollVoteHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return pollUpdateHandler(service, services.ChatRoomService.RetractPollVote)
+}
+
+// ClosePollHandler godoc
+//	@Summary		Close a poll
+//	@Description	Closes a poll to new votes. Only its creator or a room admin can close it.
+//	@Tags			polls
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			chatRoomID	path		int	true	"Chat Room ID"
+//	@Param			pollID		path		int	true	"Poll ID"
+//	@Success		200			{object}	models.Poll
+//	@Failure		403			{object}	map[string]interface{}	"Not the creator or a room admin"
+//	@Failure		404			{object}	map[string]interface{}	"Poll not found"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/chatrooms/{chatRoomID}/polls/{pollID}/close [post]
+func ClosePollHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return pollUpdateHandler(service, services.ChatRoomService.ClosePoll)
+}
+
+// pollUpdateHandler runs a change to a poll, answers with the poll as the current user sees it and
+// pushes the new tallies to the room without the user's own votes.
+func pollUpdateHandler(service services.ChatRoomService, update func(services.ChatRoomService, *gin.Context) (*models.Poll, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		poll, err := update(service, c)
+		if err != nil {
+			c.JSON(pollErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		tally := *poll
+		tally.MyVotes = nil
+		Broadcast <- models.Messages{
+			MessageID:  poll.MessageID,
+			ChatRoomID: poll.ChatRoomID,
+			Type:       models.EventPollUpdated,
+			Data:       &tally,
+		}
+
+		c.JSON(http.StatusOK, poll)
+	}
+}
+
+func pollErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrPollNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrPollClosed):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrUserMuted):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrInvalidPollQuestion),
+		errors.Is(err, services.ErrInvalidPollOptions),
+		errors.Is(err, services.ErrInvalidPollClose),
+		errors.Is(err, services.ErrInvalidVote):
+		return http.StatusBadRequest
+	default:
+		return moderationErrorStatus(err)
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newPollVoteContext(w *httptest.ResponseRecorder, body string) *gin.Context {
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "chatRoomID", Value: "7"}, {Key: "pollID", Value: "3"}}
+	c.Set(services.UserIDKey, uint(123))
+	c.Request, _ = http.NewRequest(http.MethodPut, "/api/chatrooms/7/polls/3/votes", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	return c
+}
+
+func testPoll(multipleChoice bool) *models.Poll {
+	return &models.Poll{
+		ID:             3,
+		ChatRoomID:     7,
+		MessageID:      12,
+		Question:       "Lunch?",
+		MultipleChoice: multipleChoice,
+		Options:        []models.PollOption{{ID: 1, Text: "Pizza"}, {ID: 2, Text: "Sushi"}},
+	}
+}
+
+func TestVotePollHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		Broadcast = make(chan models.Messages, 100)
+		_, mockRepo, service := initTest()
+
+		voted := testPoll(true)
+		voted.Options[0].Votes, voted.Options[1].Votes = 1, 1
+		voted.TotalVoters = 1
+		voted.MyVotes = []uint{1, 2}
+
+		mockRepo.On("IsUserInChatRoom", uint(123), uint(7)).Return(true)
+		mockRepo.On("GetPoll", mock.Anything, uint(7), uint(3), uint(123)).Return(testPoll(true), nil).Once()
+		mockRepo.On("SetPollVotes", mock.Anything, uint(3), uint(123), []uint{1, 2}).Return(nil)
+		mockRepo.On("GetPoll", mock.Anything, uint(7), uint(3), uint(123)).Return(voted, nil).Once()
+
+		w := httptest.NewRecorder()
+		VotePollHandler(service)(newPollVoteContext(w, `{"option_ids":[1,2]}`))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"my_votes":[1,2]`)
+		event := <-Broadcast
+		assert.Equal(t, models.EventPollUpdated, event.Type)
+		assert.Equal(t, uint(12), event.MessageID)
+		assert.Nil(t, event.Data.(*models.Poll).MyVotes)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Single choice takes one option", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		mockRepo.On("IsUserInChatRoom", uint(123), uint(7)).Return(true)
+		mockRepo.On("GetPoll", mock.Anything, uint(7), uint(3), uint(123)).Return(testPoll(false), nil)
+
+		w := httptest.NewRecorder()
+		VotePollHandler(service)(newPollVoteContext(w, `{"option_ids":[1,2]}`))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockRepo.AssertNotCalled(t, "SetPollVotes", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Closed poll", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		closed := testPoll(false)
+		closesAt := time.Now().Add(-time.Minute)
+		closed.ClosesAt = &closesAt
+		closed.Closed = closed.IsClosed(time.Now())
+
+		mockRepo.On("IsUserInChatRoom", uint(123), uint(7)).Return(true)
+		mockRepo.On("GetPoll", mock.Anything, uint(7), uint(3), uint(123)).Return(closed, nil)
+
+		w := httptest.NewRecorder()
+		VotePollHandler(service)(newPollVoteContext(w, `{"option_ids":[1]}`))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		mockRepo.AssertNotCalled(t, "SetPollVotes", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestPreparePollInDirectMessage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, mockRepo, service := initTest()
+
+	mockRepo.On("IsUserInChatRoom", uint(123), uint(7)).Return(true)
+	mockRepo.On("GetMutedUntil", mock.Anything, uint(7), uint(123)).Return(time.Time{}, nil)
+	mockRepo.On("GetChatRoomMember", mock.Anything, uint(7), uint(123)).Return(&models.ChatRoomMemberProfile{Role: models.RoleMember}, nil)
+	mockRepo.On("GetSlowModeState", mock.Anything, uint(7), uint(123)).Return(time.Duration(0), time.Duration(-1), nil)
+	mockRepo.On("GetChatRoom", mock.Anything, uint(7)).Return(&models.ChatRooms{ID: 7, IsDM: true}, nil)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Params = gin.Params{{Key: "chatRoomID", Value: "7"}}
+	c.Set(services.UserIDKey, uint(123))
+	c.Request, _ = http.NewRequest(http.MethodPost, "/api/chatrooms/7/polls", bytes.NewBufferString(`{"question":"Lunch?","options":["Pizza","Sushi"]}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	msg, err := service.PreparePoll(c)
+
+	assert.NoError(t, err)
+	assert.True(t, msg.IsDM)
+	assert.Equal(t, models.MessageTypePoll, msg.Type)
+	assert.Equal(t, uint(123), msg.SenderID)
+	assert.Equal(t, "Lunch?", msg.Content)
+	assert.Equal(t, uint(7), msg.Data.(*models.Poll).ChatRoomID)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+)
+
+// GetOwnProfileHandler godoc
+//	@Summary		Get your profile
+//	@Description	Returns the current user's profile, including the private email fields.
+//	@Tags			users
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Success		200	{object}	models.UserProfile
+//	@Failure		500	{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/users/me [get]
+func GetOwnProfileHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		profile, err := service.GetOwnProfile(c)
+		if err != nil {
+			c.JSON(profileErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, profile)
+	}
+}
+
+// UpdateProfileHandler godoc
+//	@Summary		Edit your profile
+//	@Description	Changes the current user's name, username or email; fields left out stay as they are. A new email address has to be verified again, and can only be set with a session. The new public profile is sent to everyone who shares a chat room with the user as a profile_updated event.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			request	body		services.UpdateProfileRequest	true	"Fields to change"
+//	@Success		200		{object}	models.UserProfile
+//	@Failure		400		{object}	map[string]interface{}	"Invalid name, username or email"
+//	@Failure		403		{object}	map[string]interface{}	"Email changed with an access token"
+//	@Failure		409		{object}	map[string]interface{}	"Username or email already taken"
+//	@Failure		500		{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/users/me [patch]
+func UpdateProfileHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		profile, err := service.UpdateProfile(c)
+		if err != nil {
+			c.JSON(profileErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		Broadcast <- models.Messages{
+			SenderID: profile.ID,
+			Type:     models.EventProfileUpdated,
+			Data:     services.PublicProfile(profile),
+		}
+		c.JSON(http.StatusOK, profile)
+	}
+}
+
+// UploadAvatarHandler godoc
+//	@Summary		Upload your avatar
+//	@Description	Replaces the current user's profile picture with an uploaded JPEG, PNG or GIF of up to 5 MB. The picture is cropped to a square and stored in 32, 64, 128 and 256 pixel sizes. The new public profile is sent to everyone who shares a chat room with the user as a profile_updated event.
+//	@Tags			users
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			file	formData	file	true	"Image file"
+//	@Success		200		{object}	models.UserProfile
+//	@Failure		400		{object}	map[string]interface{}	"Invalid image"
+//	@Failure		500		{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/users/me/avatar [post]
+func UploadAvatarHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		profile, err := service.UploadAvatar(c)
+		if err != nil {
+			c.JSON(profileErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		Broadcast <- models.Messages{
+			SenderID: profile.ID,
+			Type:     models.EventProfileUpdated,
+			Data:     services.PublicProfile(profile),
+		}
+		c.JSON(http.StatusOK, profile)
+	}
+}
+
+// GetUserProfileHandler godoc
+//	@Summary		Get a user's profile
+//	@Description	Returns the public profile of a user. Private fields are only included for the current user.
+//	@Tags			users
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			id	path		int	true	"User ID"
+//	@Success		200	{object}	models.UserProfile
+//	@Failure		404	{object}	map[string]interface{}	"User not found"
+//	@Failure		500	{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/users/{id} [get]
+func GetUserProfileHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		profile, err := service.GetUserProfile(c)
+		if err != nil {
+			c.JSON(profileErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, profile)
+	}
+}
+
+func profileErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidUsername), errors.Is(err, services.ErrInvalidName),
+		errors.Is(err, services.ErrInvalidEmail), errors.Is(err, services.ErrInvalidAvatar),
+		errors.Is(err, services.ErrAvatarTooLarge), errors.Is(err, services.ErrAvatarTooManyPixels):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrUsernameTaken), errors.Is(err, services.ErrEmailInUse):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrSessionRequired):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
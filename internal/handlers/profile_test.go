@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/kontentski/chat/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func profileEngine(service services.ChatRoomService, userID uint, withToken bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		c.Set(services.UserIDKey, userID)
+		if withToken {
+			c.Set(services.AccessTokenIDKey, uint(1))
+		}
+	})
+	engine.GET("/api/users/me", GetOwnProfileHandler(service))
+	engine.PATCH("/api/users/me", UpdateProfileHandler(service))
+	engine.POST("/api/users/me/avatar", UploadAvatarHandler(service))
+	engine.GET("/api/users/:id", GetUserProfileHandler(service))
+	return engine
+}
+
+func profileRequest(engine *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func profileUser() *models.Users {
+	verified := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	return &models.Users{
+		ID:              7,
+		Username:        "bob",
+		Name:            "Bob",
+		Password:        "$2a$10$hash",
+		Email:           "bob@example.com",
+		EmailVerifiedAt: &verified,
+	}
+}
+
+func TestUsersNeverSerializesPassword(t *testing.T) {
+	data, err := json.Marshal(profileUser())
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "password")
+	assert.NotContains(t, string(data), "$2a$10$hash")
+}
+
+func TestGetUserProfileHidesPrivateFields(t *testing.T) {
+	_, mockRepo, service := initTest()
+	mockRepo.On("GetUserByID", mock.Anything, uint(7)).Return(profileUser(), nil)
+	mockRepo.On("GetUserByID", mock.Anything, uint(8)).Return(nil, pgx.ErrNoRows)
+
+	w := profileRequest(profileEngine(service, 5, false), http.MethodGet, "/api/users/7", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"username":"bob"`)
+	assert.NotContains(t, w.Body.String(), "email")
+	assert.NotContains(t, w.Body.String(), "password")
+
+	w = profileRequest(profileEngine(service, 7, false), http.MethodGet, "/api/users/me", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"email":"bob@example.com"`)
+	assert.Contains(t, w.Body.String(), `"email_verified_at"`)
+	assert.NotContains(t, w.Body.String(), "password")
+
+	w = profileRequest(profileEngine(service, 5, false), http.MethodGet, "/api/users/8", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestUpdateProfileBroadcastsPublicProfile(t *testing.T) {
+	_, mockRepo, service := initTest()
+	Broadcast = make(chan models.Messages, 100)
+	updated := profileUser()
+	updated.Name = "Robert"
+	mockRepo.On("GetUserByID", mock.Anything, uint(7)).Return(profileUser(), nil)
+	mockRepo.On("UpdateProfile", mock.Anything, uint(7), "Robert", "bob", "bob@example.com").Return(updated, nil)
+
+	w := profileRequest(profileEngine(service, 7, false), http.MethodPatch, "/api/users/me", `{"name":" Robert "}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"Robert"`)
+	assert.Contains(t, w.Body.String(), `"email":"bob@example.com"`)
+
+	event := <-Broadcast
+	assert.Equal(t, models.EventProfileUpdated, event.Type)
+	assert.Equal(t, uint(7), event.SenderID)
+	profile := event.Data.(*models.UserProfile)
+	assert.Equal(t, "Robert", profile.Name)
+	assert.Empty(t, profile.Email)
+	assert.Nil(t, profile.EmailVerifiedAt)
+}
+
+func TestUpdateProfileValidation(t *testing.T) {
+	_, mockRepo, service := initTest()
+	mockRepo.On("GetUserByID", mock.Anything, uint(7)).Return(profileUser(), nil)
+	mockRepo.On("UpdateProfile", mock.Anything, uint(7), "Bob", "alice", "bob@example.com").Return(nil, storage.ErrUsernameTaken)
+	mockRepo.On("GetUserByEmail", mock.Anything, "Alice@example.com").Return(&models.Users{ID: 5}, nil)
+
+	engine := profileEngine(service, 7, false)
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"username":"a"}`, http.StatusBadRequest},
+		{`{"username":"system_default"}`, http.StatusBadRequest},
+		{`{"username":"bob smith"}`, http.StatusBadRequest},
+		{`{"email":"not an email"}`, http.StatusBadRequest},
+		{`{"username":"alice"}`, http.StatusConflict},
+		{`{"email":"Alice@example.com"}`, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		w := profileRequest(engine, http.MethodPatch, "/api/users/me", tt.body)
+		assert.Equal(t, tt.want, w.Code, tt.body)
+	}
+
+	// The email address receives password resets, so access tokens cannot change it
+	w := profileRequest(profileEngine(service, 7, true), http.MethodPatch, "/api/users/me", `{"email":"new@example.com"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockRepo.AssertNotCalled(t, "GetUserByEmail", mock.Anything, "new@example.com")
+}
+
+func avatarUpload(t *testing.T, engine *gin.Engine, data []byte) *httptest.ResponseRecorder {
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	fileWriter, err := writer.CreateFormFile("file", "me.png")
+	assert.NoError(t, err)
+	fileWriter.Write(data)
+	writer.Close()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/api/users/me/avatar", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestUploadAvatarStoresEverySize(t *testing.T) {
+	_, mockRepo, service := initTest()
+	mediaStorage := service.MediaStorage.(*storage.MockBucketStorage)
+	Broadcast = make(chan models.Messages, 100)
+
+	old := profileUser()
+	old.ProfilePicture = "users/7/avatar/100"
+	mockRepo.On("GetUserByID", mock.Anything, uint(7)).Return(old, nil)
+	updated := profileUser()
+	mockRepo.On("SetProfilePicture", mock.Anything, uint(7), mock.AnythingOfType("string")).
+		Run(func(args mock.Arguments) { updated.ProfilePicture = args.String(2) }).
+		Return(updated, nil)
+
+	var uploaded []image.Config
+	mediaStorage.On("UploadFileToBucket", mock.Anything, mock.Anything, mock.MatchedBy(func(path string) bool {
+		return regexp.MustCompile(`^users/7/avatar/\d+-(32|64|128|256)\.png$`).MatchString(path)
+	}), mock.Anything).Run(func(args mock.Arguments) {
+		config, err := png.DecodeConfig(args.Get(0).(io.Reader))
+		assert.NoError(t, err)
+		uploaded = append(uploaded, config)
+	}).Return("", nil)
+	mediaStorage.On("GenerateSignedURL", mock.Anything).Return("https://signed", nil)
+	for _, size := range []string{"32", "64", "128", "256"} {
+		mediaStorage.On("DeleteFile", mock.Anything, "users/7/avatar/100-"+size+".png").Return(nil).Once()
+	}
+
+	var picture bytes.Buffer
+	assert.NoError(t, png.Encode(&picture, image.NewRGBA(image.Rect(0, 0, 300, 200))))
+	w := avatarUpload(t, profileEngine(service, 7, false), picture.Bytes())
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Len(t, uploaded, 4)
+	for i, size := range []int{32, 64, 128, 256} {
+		assert.Equal(t, size, uploaded[i].Width)
+		assert.Equal(t, size, uploaded[i].Height)
+	}
+	mediaStorage.AssertExpectations(t)
+
+	assert.Regexp(t, `^users/7/avatar/\d+$`, updated.ProfilePicture)
+	mediaStorage.AssertCalled(t, "GenerateSignedURL", updated.ProfilePicture+"-128.png")
+	mediaStorage.AssertCalled(t, "GenerateSignedURL", updated.ProfilePicture+"-32.png")
+	var profile models.UserProfile
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &profile))
+	assert.Equal(t, "https://signed", profile.ProfilePicture)
+	assert.Len(t, profile.Avatars, 4)
+
+	event := <-Broadcast
+	assert.Equal(t, models.EventProfileUpdated, event.Type)
+	assert.Equal(t, profile.ProfilePicture, event.Data.(*models.UserProfile).ProfilePicture)
+}
+
+func TestAvatarOfAnotherUserIsNotOwned(t *testing.T) {
+	_, mockRepo, service := initTest()
+	mediaStorage := service.MediaStorage.(*storage.MockBucketStorage)
+	Broadcast = make(chan models.Messages, 100)
+
+	old := profileUser()
+	old.ProfilePicture = "users/8/avatar/100"
+	mockRepo.On("GetUserByID", mock.Anything, uint(7)).Return(old, nil)
+	mockRepo.On("SetProfilePicture", mock.Anything, uint(7), mock.AnythingOfType("string")).Return(profileUser(), nil)
+	mediaStorage.On("UploadFileToBucket", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("", nil)
+
+	var picture bytes.Buffer
+	assert.NoError(t, png.Encode(&picture, image.NewRGBA(image.Rect(0, 0, 64, 64))))
+	w := avatarUpload(t, profileEngine(service, 7, false), picture.Bytes())
+	assert.Equal(t, http.StatusOK, w.Code)
+	mediaStorage.AssertNotCalled(t, "DeleteFile", mock.Anything, mock.Anything)
+
+	w = profileRequest(profileEngine(service, 5, false), http.MethodGet, "/api/users/7", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "avatars")
+	mediaStorage.AssertNotCalled(t, "GenerateSignedURL", mock.Anything)
+}
+
+func TestUploadAvatarRejectsNonImages(t *testing.T) {
+	_, _, service := initTest()
+	w := avatarUpload(t, profileEngine(service, 7, false), []byte("<svg></svg>"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	service.MediaStorage.(*storage.MockBucketStorage).AssertNotCalled(t, "UploadFileToBucket", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestProviderProfilePictureIsNotSigned(t *testing.T) {
+	_, mockRepo, service := initTest()
+	user := profileUser()
+	user.ProfilePicture = "https://lh3.googleusercontent.com/a/photo"
+	mockRepo.On("GetUserByID", mock.Anything, uint(7)).Return(user, nil)
+
+	w := profileRequest(profileEngine(service, 5, false), http.MethodGet, "/api/users/7", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"profile_picture":"https://lh3.googleusercontent.com/a/photo"`)
+	assert.NotContains(t, w.Body.String(), "avatars")
+}
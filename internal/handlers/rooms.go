@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+)
+
+// GetChatRoomHandler godoc
+//	@Summary		Get chat room details
+//	@Description	Returns a chat room with signed avatar and banner URLs and the current user's settings. Only members can see it.
+//	@Tags			chatrooms
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			chatRoomID	path		int	true	"Chat Room ID"
+//	@Success		200			{object}	models.ChatRooms
+//	@Failure		403			{object}	map[string]interface{}	"Not a member of the chat room"
+//	@Failure		404			{object}	map[string]interface{}	"Chat room not found"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/chatrooms/{chatRoomID} [get]
+func GetChatRoomHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		room, err := service.GetChatRoom(c)
+		if err != nil {
+			c.JSON(roomErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, room)
+	}
+}
+
+// UploadChatRoomImageHandler godoc
+//	@Summary		Upload a room avatar or banner
+//	@Description	Replaces the avatar or banner of a chat room. Only room admins can change them. Accepts .jpg, .jpeg, .png and .gif up to 5 MB.
+//	@Tags			chatrooms
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			chatRoomID	path		int		true	"Chat Room ID"
+//	@Param			file		formData	file	true	"Image file"
+//	@Success		200			{object}	models.ChatRooms
+//	@Failure		400			{object}	map[string]interface{}	"Invalid image"
+//	@Failure		403			{object}	map[string]interface{}	"Not a room admin"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/chatrooms/{chatRoomID}/avatar [post]
+//	@Router			/api/chatrooms/{chatRoomID}/banner [post]
+func UploadChatRoomImageHandler(service services.ChatRoomService, kind string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		room, err := service.UploadChatRoomImage(c, kind)
+		if err != nil {
+			c.JSON(roomErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		roomUpdated(room)
+
+		c.JSON(http.StatusOK, room)
+	}
+}
+
+// SetRetentionHandler godoc
+//	@Summary		Set message retention
+//	@Description	Sets how many days messages and uploaded media are kept in the chat room. Null falls back to the server default and 0 keeps messages forever. Only room admins can change it.
+//	@Tags			chatrooms
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			chatRoomID	path		int							true	"Chat Room ID"
+//	@Param			request		body		services.RetentionRequest	true	"Retention period in days"
+//	@Success		200			{object}	models.ChatRooms
+//	@Failure		400			{object}	map[string]interface{}	"Invalid request"
+//	@Failure		403			{object}	map[string]interface{}	"Not a room admin"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/chatrooms/{chatRoomID}/retention [put]
+func SetRetentionHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		room, err := service.SetRetention(c)
+		if err != nil {
+			c.JSON(roomErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		roomUpdated(room)
+
+		c.JSON(http.StatusOK, room)
+	}
+}
+
+// SetMessageTTLHandler godoc
+//	@Summary		Set disappearing messages
+//	@Description	Sets after how many seconds new messages in the chat room are erased. Null turns disappearing messages off. Any member of a DM can change it, other rooms require a room admin.
+//	@Tags			chatrooms
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			chatRoomID	path		int							true	"Chat Room ID"
+//	@Param			request		body		services.MessageTTLRequest	true	"Time-to-live in seconds"
+//	@Success		200			{object}	models.ChatRooms
+//	@Failure		400			{object}	map[string]interface{}	"Invalid request"
+//	@Failure		403			{object}	map[string]interface{}	"Not allowed to change the chat room"
+//	@Failure		404			{object}	map[string]interface{}	"Chat room not found"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/chatrooms/{chatRoomID}/message-ttl [put]
+func SetMessageTTLHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		room, err := service.SetMessageTTL(c)
+		if err != nil {
+			c.JSON(roomErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		roomUpdated(room)
+
+		c.JSON(http.StatusOK, room)
+	}
+}
+
+// sharedRoom is a chat room without the settings of the current user, which are different for every member.
+type sharedRoom struct {
+	*models.ChatRooms
+	Settings *models.ChatRoomMemberSettings `json:"settings,omitempty"`
+}
+
+// roomUpdated sends the room fields all members share to the members of the room.
+func roomUpdated(room *models.ChatRooms) {
+	Broadcast <- models.Messages{
+		ChatRoomID: room.ID,
+		Type:       models.EventRoomUpdated,
+		Data:       sharedRoom{ChatRooms: room},
+	}
+}
+
+func roomErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrChatRoomNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidRoomImage), errors.Is(err, services.ErrRoomImageTooLarge),
+		errors.Is(err, services.ErrInvalidRetention),
+		errors.Is(err, services.ErrInvalidMessageTTL):
+		return http.StatusBadRequest
+	default:
+		return moderationErrorStatus(err)
+	}
+}
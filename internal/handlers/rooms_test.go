@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/kontentski/chat/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newImageUploadContext(t *testing.T, w *httptest.ResponseRecorder, fileName string) *gin.Context {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	fileWriter, err := writer.CreateFormFile("file", fileName)
+	assert.NoError(t, err)
+	fileWriter.Write([]byte("image content"))
+	writer.Close()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "chatRoomID", Value: "7"}}
+	c.Set(services.UserIDKey, uint(123))
+	c.Request, _ = http.NewRequest(http.MethodPost, "/api/chatrooms/7/avatar", body)
+	c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+	return c
+}
+
+func TestUploadChatRoomImageHandler(t *testing.T) {
+	Broadcast = make(chan models.Messages, 100)
+
+	t.Run("Success", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		mediaStorage := service.MediaStorage.(*storage.MockBucketStorage)
+
+		isMetaPath := mock.MatchedBy(func(path string) bool {
+			return strings.HasPrefix(path, "chatrooms/7/meta/avatar-") && strings.HasSuffix(path, ".png")
+		})
+		mockRepo.On("GetChatRoomMember", mock.Anything, uint(7), uint(123)).Return(&models.ChatRoomMemberProfile{UserID: 123, Role: models.RoleAdmin}, nil)
+		mediaStorage.On("UploadFileToBucket", mock.Anything, "room.png", isMetaPath, mock.Anything).Return("chatrooms/7/meta/avatar-1.png", nil)
+		mockRepo.On("SetChatRoomImage", mock.Anything, uint(7), models.RoomImageAvatar, "chatrooms/7/meta/avatar-1.png").Return("chatrooms/7/meta/avatar-0.png", nil)
+		mediaStorage.On("DeleteFile", mock.Anything, "chatrooms/7/meta/avatar-0.png").Return(nil)
+		mockRepo.On("GetChatRoom", mock.Anything, uint(7)).Return(&models.ChatRooms{ID: 7, Name: "General", AvatarPath: "chatrooms/7/meta/avatar-1.png"}, nil)
+		mediaStorage.On("GenerateSignedURL", "chatrooms/7/meta/avatar-1.png").Return("http://signed.url/avatar.png", nil)
+
+		w := httptest.NewRecorder()
+		UploadChatRoomImageHandler(service, models.RoomImageAvatar)(newImageUploadContext(t, w, "room.png"))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"avatar_url":"http://signed.url/avatar.png"`)
+		assert.NotContains(t, w.Body.String(), "avatar_path")
+		event := <-Broadcast
+		assert.Equal(t, models.EventRoomUpdated, event.Type)
+		// Members keep their own settings, so the event must not carry any
+		body, _ := json.Marshal(event.Data)
+		assert.Contains(t, string(body), `"name":"General"`)
+		assert.Contains(t, string(body), `"avatar_url":"http://signed.url/avatar.png"`)
+		assert.NotContains(t, string(body), "settings")
+		mockRepo.AssertExpectations(t)
+		mediaStorage.AssertExpectations(t)
+	})
+
+	t.Run("Rejects non-images", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		mediaStorage := service.MediaStorage.(*storage.MockBucketStorage)
+		mockRepo.On("GetChatRoomMember", mock.Anything, uint(7), uint(123)).Return(&models.ChatRoomMemberProfile{UserID: 123, Role: models.RoleAdmin}, nil)
+
+		w := httptest.NewRecorder()
+		UploadChatRoomImageHandler(service, models.RoomImageAvatar)(newImageUploadContext(t, w, "notes.txt"))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mediaStorage.AssertNotCalled(t, "UploadFileToBucket")
+	})
+}
+
+func TestPurgeExpiredMessages(t *testing.T) {
+	_, mockRepo, service := initTest()
+	mediaStorage := service.MediaStorage.(*storage.MockBucketStorage)
+
+	thirty, keepForever := 30, 0
+
+	mockRepo.On("GetRetentionPolicies", mock.Anything).Return(map[uint]*int{
+		7: &thirty,      // room setting
+		8: nil,          // server default
+		9: &keepForever, // opted out
+	}, nil)
+	mockRepo.On("PurgeMessagesOlderThan", mock.Anything, uint(7), 30).Return(&models.PurgedMessages{
+		Messages:     4,
+		ReadReceipts: 6,
+		Media:        []string{"chatrooms/7/old.png", "chatrooms/7/gone.png"},
+	}, nil)
+	mockRepo.On("PurgeMessagesOlderThan", mock.Anything, uint(8), 90).Return(&models.PurgedMessages{}, nil)
+	// Only media of purged messages is removed, however old the object is
+	mediaStorage.On("DeleteFile", mock.Anything, "chatrooms/7/old.png").Return(nil)
+	mediaStorage.On("DeleteFile", mock.Anything, "chatrooms/7/gone.png").Return(errors.New("not found"))
+
+	reports, err := service.PurgeExpiredMessages(context.Background(), 90)
+
+	assert.NoError(t, err)
+	assert.Len(t, reports, 1)
+	assert.Equal(t, uint(7), reports[0].ChatRoomID)
+	assert.Equal(t, int64(4), reports[0].Messages)
+	assert.Equal(t, int64(6), reports[0].ReadReceipts)
+	assert.Equal(t, 1, reports[0].MediaObjects)
+	mockRepo.AssertNotCalled(t, "PurgeMessagesOlderThan", mock.Anything, uint(9), mock.Anything)
+	mockRepo.AssertExpectations(t)
+	mediaStorage.AssertExpectations(t)
+}
+
+func TestSetRetentionHandlerBroadcastsWholeRoom(t *testing.T) {
+	Broadcast = make(chan models.Messages, 100)
+	_, mockRepo, service := initTest()
+	thirty := 30
+
+	mockRepo.On("GetChatRoomMember", mock.Anything, uint(7), uint(123)).Return(&models.ChatRoomMemberProfile{UserID: 123, Role: models.RoleAdmin}, nil)
+	mockRepo.On("SetRetentionDays", mock.Anything, uint(7), &thirty).Return(nil)
+	mockRepo.On("GetChatRoom", mock.Anything, uint(7)).Return(&models.ChatRooms{ID: 7, Name: "General", Type: "public", RetentionDays: &thirty}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "chatRoomID", Value: "7"}}
+	c.Set(services.UserIDKey, uint(123))
+	c.Request, _ = http.NewRequest(http.MethodPut, "/api/chatrooms/7/retention", strings.NewReader(`{"days":30}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	SetRetentionHandler(service)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	event := <-Broadcast
+	body, _ := json.Marshal(event.Data)
+	assert.Contains(t, string(body), `"name":"General"`)
+	assert.Contains(t, string(body), `"retention_days":30`)
+}
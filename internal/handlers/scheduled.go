@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/kontentski/chat/internal/storage"
+)
+
+// scheduledBatchSize is the number of due messages the dispatcher claims per run.
+const scheduledBatchSize = 100
+
+// scheduledClaimTimeout is how long a claimed message may stay unsent before another run retries it.
+const scheduledClaimTimeout = 5 * time.Minute
+
+// ScheduleMessageHandler godoc
+//	@Summary		Schedule a message
+//	@Description	Stores a message that is delivered to the chat room at send_at. Media messages take the file path returned by /api/upload-media as content.
+//	@Tags			scheduled
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			chatRoomID	path		int								true	"Chat Room ID"
+//	@Param			request		body		services.ScheduleMessageRequest	true	"Message and delivery time"
+//	@Success		201			{object}	models.ScheduledMessage
+//	@Failure		400			{object}	map[string]interface{}	"Invalid request"
+//	@Failure		403			{object}	map[string]interface{}	"Not a member of the chat room"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/chatrooms/{chatRoomID}/scheduled [post]
+func ScheduleMessageHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheduled, err := service.ScheduleMessage(c)
+		if err != nil {
+			c.JSON(scheduledErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, scheduled)
+	}
+}
+
+// GetScheduledMessagesHandler godoc
+//	@Summary		List scheduled messages
+//	@Description	Lists the current user's pending scheduled messages, in one chat room or across all rooms
+//	@Tags			scheduled
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			chatRoomID	path		int	false	"Chat Room ID"
+//	@Success		200			{array}		models.ScheduledMessage
+//	@Failure		403			{object}	map[string]interface{}	"Not a member of the chat room"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/scheduled [get]
+//	@Router			/api/chatrooms/{chatRoomID}/scheduled [get]
+func GetScheduledMessagesHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheduled, err := service.GetScheduledMessages(c)
+		if err != nil {
+			c.JSON(scheduledErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, scheduled)
+	}
+}
+
+// CancelScheduledMessageHandler godoc
+//	@Summary		Cancel a scheduled message
+//	@Description	Cancels one of the current user's pending scheduled messages
+//	@Tags			scheduled
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			scheduledID	path		int	true	"Scheduled message ID"
+//	@Success		200			{object}	models.ScheduledMessage
+//	@Failure		404			{object}	map[string]interface{}	"No pending message with this ID"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/scheduled/{scheduledID} [delete]
+func CancelScheduledMessageHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheduled, err := service.CancelScheduledMessage(c)
+		if err != nil {
+			c.JSON(scheduledErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, scheduled)
+	}
+}
+
+func scheduledErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrScheduledNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrEmptyMessage),
+		errors.Is(err, services.ErrScheduleInPast),
+		errors.Is(err, services.ErrScheduleTooFar),
+		errors.Is(err, services.ErrInvalidScheduledType):
+		return http.StatusBadRequest
+	default:
+		return roomErrorStatus(err)
+	}
+}
+
+// DispatchScheduledMessages delivers the scheduled messages that are due. Each one is saved like a
+// message sent over the WebSocket, so it gets the next message ID of its room and the real send time,
+// and is then broadcast to the room. Messages whose sender left the room or is muted are marked failed.
+// A message is marked sent in the transaction that saves it, so a retried claim never delivers it twice.
+func DispatchScheduledMessages(ctx context.Context, repo storage.UserRepository) error {
+	due, err := repo.ClaimDueScheduledMessages(ctx, scheduledBatchSize, scheduledClaimTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to claim scheduled messages: %w", err)
+	}
+
+	for _, scheduled := range due {
+		if reason := scheduledDeliveryBlocked(ctx, repo, scheduled); reason != "" {
+			log.Printf("Scheduled message %d not delivered: %s", scheduled.ID, reason)
+			if err := repo.MarkScheduledMessageFailed(ctx, scheduled.ID, reason); err != nil {
+				log.Printf("Error marking scheduled message %d as failed: %v", scheduled.ID, err)
+			}
+			continue
+		}
+
+		msg := models.Messages{
+			SenderID:   scheduled.SenderID,
+			Content:    scheduled.Content,
+			ChatRoomID: scheduled.ChatRoomID,
+			IsDM:       scheduled.IsDM,
+			Type:       scheduled.Type,
+		}
+		markSent := func(tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, storage.MarkScheduledMessageSentQuery, scheduled.ID, msg.MessageID)
+			return err
+		}
+		if err := saveMessageToDB(&msg, markSent); err != nil {
+			log.Printf("Error saving scheduled message %d: %v", scheduled.ID, err)
+			if err := repo.MarkScheduledMessageFailed(ctx, scheduled.ID, "could not save message"); err != nil {
+				log.Printf("Error marking scheduled message %d as failed: %v", scheduled.ID, err)
+			}
+			continue
+		}
+		Broadcast <- msg
+	}
+	return nil
+}
+
+// scheduledDeliveryBlocked returns why a scheduled message can no longer be delivered, or "" if it can.
+func scheduledDeliveryBlocked(ctx context.Context, repo storage.UserRepository, scheduled models.ScheduledMessage) string {
+	if !repo.IsUserInChatRoom(scheduled.SenderID, scheduled.ChatRoomID) {
+		return "sender is no longer a member of the chat room"
+	}
+	mutedUntil, err := repo.GetMutedUntil(ctx, scheduled.ChatRoomID, scheduled.SenderID)
+	if err != nil {
+		log.Printf("Error checking mute for user %d: %v", scheduled.SenderID, err)
+		return "could not check whether the sender is muted"
+	}
+	if mutedUntil.After(time.Now()) {
+		return "sender is muted in the chat room"
+	}
+	return ""
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newScheduleContext(w *httptest.ResponseRecorder, body string) *gin.Context {
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "chatRoomID", Value: "7"}}
+	c.Set(services.UserIDKey, uint(123))
+	c.Request, _ = http.NewRequest(http.MethodPost, "/api/chatrooms/7/scheduled", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	return c
+}
+
+func TestScheduleMessageHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		sendAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+		mockRepo.On("IsUserInChatRoom", uint(123), uint(7)).Return(true)
+		mockRepo.On("GetChatRoom", mock.Anything, uint(7)).Return(&models.ChatRooms{ID: 7, IsDM: true}, nil)
+		mockRepo.On("CreateScheduledMessage", mock.Anything, mock.MatchedBy(func(s *models.ScheduledMessage) bool {
+			return s.SenderID == 123 && s.Content == "good morning" && s.IsDM && s.SendAt.Equal(sendAt)
+		})).Run(func(args mock.Arguments) {
+			scheduled := args.Get(1).(*models.ScheduledMessage)
+			scheduled.ID = 1
+			scheduled.Status = models.ScheduledPending
+		}).Return(nil)
+
+		w := httptest.NewRecorder()
+		body := fmt.Sprintf(`{"content":" good morning ","send_at":%q}`, sendAt.Format(time.RFC3339))
+		ScheduleMessageHandler(service)(newScheduleContext(w, body))
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"pending"`)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Send time in the past", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+
+		w := httptest.NewRecorder()
+		body := fmt.Sprintf(`{"content":"late","send_at":%q}`, time.Now().Add(-time.Minute).Format(time.RFC3339))
+		ScheduleMessageHandler(service)(newScheduleContext(w, body))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockRepo.AssertNotCalled(t, "CreateScheduledMessage", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Media outside the chat room", func(t *testing.T) {
+		_, _, service := initTest()
+
+		w := httptest.NewRecorder()
+		body := fmt.Sprintf(`{"content":"chatrooms/8/photo.png","type":"media","send_at":%q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+		ScheduleMessageHandler(service)(newScheduleContext(w, body))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestDispatchScheduledMessagesSkipsFormerMembers(t *testing.T) {
+	_, mockRepo, _ := initTest()
+
+	mockRepo.On("ClaimDueScheduledMessages", mock.Anything, scheduledBatchSize, scheduledClaimTimeout).Return([]models.ScheduledMessage{
+		{ID: 5, ChatRoomID: 7, SenderID: 123, Content: "hello"},
+	}, nil)
+	mockRepo.On("IsUserInChatRoom", uint(123), uint(7)).Return(false)
+	mockRepo.On("MarkScheduledMessageFailed", mock.Anything, uint(5), "sender is no longer a member of the chat room").Return(nil)
+
+	err := DispatchScheduledMessages(context.Background(), mockRepo)
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/services"
+)
+
+// SearchMessagesHandler godoc
+//	@Summary		Search messages
+//	@Description	Full-text search over the messages of the rooms the current user belongs to, newest first. Snippets are HTML-escaped with matches wrapped in <mark> tags. Pass next_cursor as cursor to get the next page.
+//	@Tags			search
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			q				query		string	true	"Search terms, supports quoted phrases, OR and -exclusion"
+//	@Param			chat_room_id	query		int		false	"Only messages of this chat room"
+//	@Param			sender_id		query		int		false	"Only messages of this sender"
+//	@Param			from			query		string	false	"Only messages sent at or after this RFC 3339 time or date"
+//	@Param			to				query		string	false	"Only messages sent before this RFC 3339 time or date"
+//	@Param			type			query		string	false	"Only messages of this type"
+//	@Param			limit			query		int		false	"Results per page, 20 by default and at most 100"
+//	@Param			cursor			query		string	false	"Cursor from the previous page"
+//	@Success		200				{object}	services.MessageSearchResponse
+//	@Failure		400				{object}	map[string]interface{}	"Invalid query, filter or cursor"
+//	@Failure		403				{object}	map[string]interface{}	"Not a member of the chat room"
+//	@Failure		500				{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/search/messages [get]
+func SearchMessagesHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		response, err := service.SearchMessages(c)
+		if err != nil {
+			c.JSON(searchErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+func searchErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidSearchQuery),
+		errors.Is(err, services.ErrInvalidSearchFilter),
+		errors.Is(err, services.ErrInvalidCursor):
+		return http.StatusBadRequest
+	default:
+		return moderationErrorStatus(err)
+	}
+}
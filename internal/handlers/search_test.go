@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newSearchContext(w *httptest.ResponseRecorder, query string) *gin.Context {
+	c, _ := gin.CreateTestContext(w)
+	c.Set(services.UserIDKey, uint(123))
+	c.Request, _ = http.NewRequest(http.MethodGet, "/api/search/messages?"+query, nil)
+	return c
+}
+
+func TestSearchMessagesHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Paginates with a cursor", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		newest := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+		results := []models.MessageSearchResult{
+			{MessageID: 9, ChatRoomID: 7, Timestamp: newest, Snippet: "the <mark>deploy</mark> is done"},
+			{MessageID: 8, ChatRoomID: 7, Timestamp: newest.Add(-time.Minute), Snippet: "<mark>deploy</mark> at noon"},
+			{MessageID: 4, ChatRoomID: 2, Timestamp: newest.Add(-time.Hour)},
+		}
+
+		mockRepo.On("IsUserInChatRoom", uint(123), uint(7)).Return(true)
+		mockRepo.On("SearchMessages", mock.Anything, uint(123), mock.MatchedBy(func(f models.MessageSearchFilter) bool {
+			return f.Query == "deploy" && f.ChatRoomID == 7 && f.Limit == 3 && f.After == nil
+		})).Return(results, nil)
+
+		w := httptest.NewRecorder()
+		SearchMessagesHandler(service)(newSearchContext(w, "q=deploy&chat_room_id=7&limit=2"))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response services.MessageSearchResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Len(t, response.Results, 2)
+		assert.NotEmpty(t, response.NextCursor)
+
+		// The cursor continues below the last returned result
+		_, mockRepo, service = initTest()
+		mockRepo.On("SearchMessages", mock.Anything, uint(123), mock.MatchedBy(func(f models.MessageSearchFilter) bool {
+			return f.After != nil && f.After.MessageID == 8 && f.After.ChatRoomID == 7 && f.After.Timestamp.Equal(newest.Add(-time.Minute))
+		})).Return(results[2:], nil)
+
+		w = httptest.NewRecorder()
+		SearchMessagesHandler(service)(newSearchContext(w, "q=deploy&limit=2&cursor="+response.NextCursor))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "next_cursor")
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Empty query", func(t *testing.T) {
+		_, _, service := initTest()
+
+		w := httptest.NewRecorder()
+		SearchMessagesHandler(service)(newSearchContext(w, "q=+"))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Room the user is not in", func(t *testing.T) {
+		_, mockRepo, service := initTest()
+		mockRepo.On("IsUserInChatRoom", uint(123), uint(8)).Return(false)
+
+		w := httptest.NewRecorder()
+		SearchMessagesHandler(service)(newSearchContext(w, "q=deploy&chat_room_id=8"))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
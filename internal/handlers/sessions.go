@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/auth"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+)
+
+// GetSessionsHandler godoc
+//	@Summary		List active sessions
+//	@Description	Returns the current user's signed-in sessions with their device, IP and last activity. The session of this request is marked current.
+//	@Tags			account
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Success		200	{array}		models.Session
+//	@Failure		500	{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/sessions [get]
+func GetSessionsHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userSessions, err := service.GetSessions(c)
+		if err != nil {
+			c.JSON(sessionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, userSessions)
+	}
+}
+
+// RevokeSessionHandler godoc
+//	@Summary		Revoke a session
+//	@Description	Signs one of the current user's sessions out and closes its WebSocket connections.
+//	@Tags			account
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			sessionID	path	int	true	"Session ID"
+//	@Success		204
+//	@Failure		403	{object}	map[string]interface{}	"Requires a signed-in session"
+//	@Failure		404	{object}	map[string]interface{}	"Session not found"
+//	@Failure		500	{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/sessions/{sessionID} [delete]
+func RevokeSessionHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID, err := service.RevokeSession(c)
+		if err != nil {
+			c.JSON(sessionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		sessionsRevoked(c, []uint{sessionID})
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// RevokeSessionsHandler godoc
+//	@Summary		Revoke all other sessions
+//	@Description	Signs out every session of the current user except this one, or all of them with include_current=true, and closes their WebSocket connections.
+//	@Tags			account
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			include_current	query		bool	false	"Also sign out the session of this request"
+//	@Success		200				{object}	map[string]interface{}
+//	@Failure		403				{object}	map[string]interface{}	"Requires a signed-in session"
+//	@Failure		500				{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/sessions [delete]
+func RevokeSessionsHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		revoked, err := service.RevokeSessions(c)
+		if err != nil {
+			c.JSON(sessionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		sessionsRevoked(c, revoked)
+		c.JSON(http.StatusOK, gin.H{"revoked": revoked})
+	}
+}
+
+// sessionsRevoked closes the WebSocket connections of the revoked sessions of the current user,
+// and expires the cookie if the request's own session is one of them.
+func sessionsRevoked(c *gin.Context, sessionIDs []uint) {
+	if len(sessionIDs) == 0 {
+		return
+	}
+	userID, _ := c.Get(services.UserIDKey)
+	Broadcast <- models.Messages{
+		Type:        models.EventSessionRevoked,
+		RecipientID: userID.(uint),
+		Data:        sessionIDs,
+	}
+
+	current, _ := c.Get(services.SessionIDKey)
+	for _, id := range sessionIDs {
+		if id == current {
+			session, _ := auth.Store.Get(c.Request, "auth-session")
+			session.Options.MaxAge = -1
+			session.Save(c.Request, c.Writer)
+			return
+		}
+	}
+}
+
+func sessionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrSessionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrSessionRequired):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
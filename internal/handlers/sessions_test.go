@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/kontentski/chat/internal/auth"
+	"github.com/kontentski/chat/internal/middleware"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/kontentski/chat/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLogoutRevokesSessionCookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	backend := storage.NewMockSessionBackend()
+	auth.Store = storage.NewSessionStore(backend, []byte("test-secret"))
+	Broadcast = make(chan models.Messages, 100)
+
+	engine := gin.New()
+	engine.POST("/auth/logout", LogoutHandler)
+	engine.GET("/api/ping", middleware.AuthMiddleware(auth.Store, nil), func(c *gin.Context) {
+		sessionID, _ := c.Get(services.SessionIDKey)
+		c.JSON(http.StatusOK, gin.H{"session_id": sessionID})
+	})
+
+	cookie := sessionCookie(t, map[interface{}]interface{}{services.UserIDKey: uint(5), "username": "alice"})
+	assert.Len(t, backend.Sessions, 1)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/ping", nil)
+	req.Header.Set("Cookie", cookie)
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"session_id":1}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/auth/logout", nil)
+	req.Header.Set("Cookie", cookie)
+	engine.ServeHTTP(w, req)
+	assert.Empty(t, backend.Sessions)
+
+	event := <-Broadcast
+	assert.Equal(t, models.EventSessionRevoked, event.Type)
+	assert.Equal(t, uint(5), event.RecipientID)
+	assert.Equal(t, []uint{1}, event.Data)
+
+	// A copy of the old cookie no longer authenticates
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/api/ping", nil)
+	req.Header.Set("Cookie", cookie)
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRevokeSessionsHandlerKeepsCurrentSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, mockRepo, service := initTest()
+	Broadcast = make(chan models.Messages, 100)
+
+	mockRepo.On("RevokeSessions", mock.Anything, uint(5), uint(3)).Return([]uint{1, 2}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set(services.UserIDKey, uint(5))
+	c.Set(services.SessionIDKey, uint(3))
+	c.Request, _ = http.NewRequest(http.MethodDelete, "/api/sessions", nil)
+	RevokeSessionsHandler(service)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"revoked":[1,2]}`, w.Body.String())
+	event := <-Broadcast
+	assert.Equal(t, models.EventSessionRevoked, event.Type)
+	assert.Equal(t, []uint{1, 2}, event.Data)
+	assert.Empty(t, w.Header().Get("Set-Cookie"))
+}
+
+func TestRevokeSessionsHandlersRequireSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, mockRepo, service := initTest()
+
+	for name, handler := range map[string]gin.HandlerFunc{"all": RevokeSessionsHandler(service), "one": RevokeSessionHandler(service)} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set(services.UserIDKey, uint(5))
+		c.Set(services.AccessTokenIDKey, uint(9))
+		c.Params = gin.Params{{Key: "sessionID", Value: "3"}}
+		c.Request, _ = http.NewRequest(http.MethodDelete, "/api/sessions", nil)
+		handler(c)
+
+		assert.Equal(t, http.StatusForbidden, w.Code, name)
+	}
+	mockRepo.AssertNotCalled(t, "RevokeSessions", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "RevokeSession", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetSessionsHandlerMarksCurrent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, mockRepo, service := initTest()
+
+	mockRepo.On("GetSessions", mock.Anything, uint(5)).Return([]models.Session{{ID: 3, Device: "Firefox"}, {ID: 1, Device: "curl"}}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set(services.UserIDKey, uint(5))
+	c.Set(services.SessionIDKey, uint(3))
+	c.Request, _ = http.NewRequest(http.MethodGet, "/api/sessions", nil)
+	GetSessionsHandler(service)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":3,"device":"Firefox"`)
+	assert.Contains(t, w.Body.String(), `"current":true`)
+	assert.Equal(t, 1, strings.Count(w.Body.String(), `"current":true`))
+}
+
+func TestCloseRevokedConnections(t *testing.T) {
+	registered := make(chan *websocket.Conn)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		registered <- conn
+	}))
+	defer server.Close()
+
+	dial := func() *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+		assert.NoError(t, err)
+		return conn
+	}
+	revokedConn := dial()
+	revokedServer := <-registered
+	keptConn := dial()
+	defer keptConn.Close()
+	clients = map[*websocket.Conn]*wsClient{
+		revokedServer: {userID: 5, sessionID: 1},
+		<-registered:  {userID: 5, sessionID: 2},
+	}
+
+	closeRevokedConnections(models.Messages{Type: models.EventSessionRevoked, RecipientID: 5, Data: []uint{1}})
+
+	assert.Len(t, clients, 1)
+	_, stillThere := clients[revokedServer]
+	assert.False(t, stillThere)
+
+	var frame models.Messages
+	assert.NoError(t, revokedConn.ReadJSON(&frame))
+	assert.Equal(t, models.EventSessionRevoked, frame.Type)
+	_, _, err := revokedConn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation))
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+)
+
+// GetStatusHandler godoc
+//	@Summary		Get your status
+//	@Description	Returns the current user's status as other users see it, null when there is none, and their do-not-disturb settings.
+//	@Tags			users
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Success		200	{object}	services.StatusResponse
+//	@Failure		500	{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/users/me/status [get]
+func GetStatusHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, err := service.GetStatus(c)
+		if err != nil {
+			c.JSON(statusErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// SetStatusHandler godoc
+//	@Summary		Set your status
+//	@Description	Replaces the current user's status text and emoji, shown in member lists and search results until expires_at. Empty text and emoji clear the status. The new status is sent to everyone who shares a chat room with the user as a status_updated event.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			request	body		services.StatusRequest	true	"Status"
+//	@Success		200		{object}	models.UserStatus
+//	@Failure		400		{object}	map[string]interface{}	"Invalid text, emoji or expiry"
+//	@Failure		500		{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/users/me/status [put]
+func SetStatusHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, err := service.SetStatus(c)
+		if err != nil {
+			c.JSON(statusErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		statusUpdated(status)
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// SetDoNotDisturbHandler godoc
+//	@Summary		Set do-not-disturb
+//	@Description	Replaces the current user's do-not-disturb settings. Enabled turns it on until the optional until time; the optional schedule turns it on every day from start to end (HH:MM) in time_zone. While it is on, the user gets no notification events for direct messages and mentions, but still receives the messages. Returns the user's status as others now see it, which is also sent to everyone who shares a chat room with the user as a status_updated event.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			request	body		models.DoNotDisturb	true	"Do-not-disturb settings"
+//	@Success		200		{object}	models.UserStatus
+//	@Failure		400		{object}	map[string]interface{}	"Invalid until or schedule"
+//	@Failure		500		{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/users/me/do-not-disturb [put]
+func SetDoNotDisturbHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, err := service.SetDoNotDisturb(c)
+		if err != nil {
+			c.JSON(statusErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		statusUpdated(status)
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// statusUpdated lets the user's contacts update the status they show.
+func statusUpdated(status *models.UserStatus) {
+	Broadcast <- models.Messages{
+		SenderID: status.UserID,
+		Type:     models.EventStatusUpdated,
+		Data:     status,
+	}
+}
+
+// notifyRecipients sends notification events about a new message to the members who should be
+// alerted about it.
+func notifyRecipients(service services.ChatRoomService, msg models.Messages) {
+	notifications, err := service.MessageNotifications(context.Background(), &msg)
+	if err != nil {
+		log.Printf("Error finding whom to notify about message %d in chat room %d: %v", msg.MessageID, msg.ChatRoomID, err)
+		return
+	}
+	for _, notification := range notifications {
+		sendToUser(notification.UserID, models.Messages{
+			MessageID:   msg.MessageID,
+			ChatRoomID:  msg.ChatRoomID,
+			SenderID:    msg.SenderID,
+			Sender:      msg.Sender,
+			Type:        models.EventNotification,
+			RecipientID: notification.UserID,
+			Data:        notification,
+		})
+	}
+}
+
+func statusErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidStatusText), errors.Is(err, services.ErrInvalidStatusEmoji),
+		errors.Is(err, services.ErrInvalidStatusExpiry), errors.Is(err, services.ErrInvalidDNDUntil),
+		errors.Is(err, services.ErrInvalidDNDSchedule):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
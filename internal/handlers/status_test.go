@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func statusEngine(service services.ChatRoomService) *gin.Engine {
+	engine := profileEngine(service, 7, false)
+	engine.GET("/api/users/me/status", GetStatusHandler(service))
+	engine.PUT("/api/users/me/status", SetStatusHandler(service))
+	engine.PUT("/api/users/me/do-not-disturb", SetDoNotDisturbHandler(service))
+	return engine
+}
+
+func TestSetStatusBroadcastsStatus(t *testing.T) {
+	_, mockRepo, service := initTest()
+	Broadcast = make(chan models.Messages, 100)
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	mockRepo.On("SetStatus", mock.Anything, uint(7), "In a meeting", "📅", mock.MatchedBy(func(t *time.Time) bool {
+		return t != nil && t.Equal(expiresAt)
+	})).Return(nil)
+	mockRepo.On("GetStatusSettings", mock.Anything, uint(7)).Return(&models.StatusSettings{
+		UserID: 7, Text: "In a meeting", Emoji: "📅", ExpiresAt: &expiresAt,
+	}, nil)
+
+	w := profileRequest(statusEngine(service), http.MethodPut, "/api/users/me/status",
+		`{"text":" In a meeting ","emoji":"📅","expires_at":"`+expiresAt.Format(time.RFC3339)+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"text":"In a meeting"`)
+	assert.Contains(t, w.Body.String(), `"do_not_disturb":false`)
+
+	event := <-Broadcast
+	assert.Equal(t, models.EventStatusUpdated, event.Type)
+	assert.Equal(t, uint(7), event.SenderID)
+	assert.Equal(t, "📅", event.Data.(*models.UserStatus).Emoji)
+}
+
+func TestSetStatusValidation(t *testing.T) {
+	_, _, service := initTest()
+	engine := statusEngine(service)
+
+	tests := []string{
+		`{"text":"` + strings.Repeat("a", 101) + `"}`,
+		`{"emoji":"not an emoji"}`,
+		`{"text":"Away","expires_at":"2020-01-01T00:00:00Z"}`,
+	}
+	for _, body := range tests {
+		w := profileRequest(engine, http.MethodPut, "/api/users/me/status", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	for _, body := range []string{
+		`{"enabled":true,"until":"2020-01-01T00:00:00Z"}`,
+		`{"schedule":{"start":"22:00","end":"22:00","time_zone":"UTC"}}`,
+		`{"schedule":{"start":"22:00","end":"07:00","time_zone":"Mars/Olympus"}}`,
+		`{"schedule":{"start":"25:00","end":"07:00","time_zone":"UTC"}}`,
+	} {
+		w := profileRequest(engine, http.MethodPut, "/api/users/me/do-not-disturb", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func TestGetStatusHidesExpiredText(t *testing.T) {
+	_, mockRepo, service := initTest()
+	expired := time.Now().Add(-time.Minute)
+	mockRepo.On("GetStatusSettings", mock.Anything, uint(7)).Return(&models.StatusSettings{
+		UserID: 7, Text: "Lunch", ExpiresAt: &expired,
+		DoNotDisturb: models.DoNotDisturb{Enabled: true},
+	}, nil)
+
+	w := profileRequest(statusEngine(service), http.MethodGet, "/api/users/me/status", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "Lunch")
+	assert.Contains(t, w.Body.String(), `"status":{"user_id":7,"do_not_disturb":true}`)
+	assert.Contains(t, w.Body.String(), `"do_not_disturb":{"enabled":true}`)
+}
+
+func TestMessageNotificationsRespectDoNotDisturb(t *testing.T) {
+	_, mockRepo, service := initTest()
+	now := time.Now().UTC()
+	past := now.Add(-time.Minute)
+	activeSchedule := &models.DNDSchedule{Start: now.Add(-time.Hour).Format("15:04"), End: now.Add(time.Hour).Format("15:04"), TimeZone: "UTC"}
+	laterSchedule := &models.DNDSchedule{Start: now.Add(2 * time.Hour).Format("15:04"), End: now.Add(3 * time.Hour).Format("15:04"), TimeZone: "UTC"}
+
+	mockRepo.On("GetNotificationTargets", mock.Anything, uint(3)).Return([]models.NotificationTarget{
+		{UserID: 1, Username: "sender"},
+		{UserID: 2, Username: "alice"},
+		{UserID: 3, Username: "bob", DoNotDisturb: models.DoNotDisturb{Enabled: true}},
+		{UserID: 4, Username: "carol", DoNotDisturb: models.DoNotDisturb{Schedule: activeSchedule}},
+		{UserID: 5, Username: "dave", DoNotDisturb: models.DoNotDisturb{Enabled: true, Until: &past, Schedule: laterSchedule}},
+		{UserID: 6, Username: "erin", NotificationLevel: models.NotificationNone},
+		{UserID: 7, Username: "frank"},
+	}, nil)
+
+	notifications, err := service.MessageNotifications(context.Background(), &models.Messages{
+		SenderID: 1, ChatRoomID: 3, Content: "@Alice @bob @carol @dave. @erin @sender",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Notification{
+		{UserID: 2, Reason: models.NotificationReasonMention},
+		{UserID: 5, Reason: models.NotificationReasonMention},
+	}, notifications)
+}
+
+func TestMessageNotificationsForDirectMessages(t *testing.T) {
+	_, mockRepo, service := initTest()
+	mockRepo.On("GetNotificationTargets", mock.Anything, uint(4)).Return([]models.NotificationTarget{
+		{UserID: 1, Username: "sender"},
+		{UserID: 2, Username: "alice"},
+	}, nil)
+
+	notifications, err := service.MessageNotifications(context.Background(), &models.Messages{SenderID: 1, ChatRoomID: 4, Content: "hi", IsDM: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Notification{{UserID: 2, Reason: models.NotificationReasonDM}}, notifications)
+}
+
+func TestSearchUsersIncludesStatus(t *testing.T) {
+	_, mockRepo, service := initTest()
+	mockRepo.On("SearchUsers", mock.Anything, "al").Return([]models.Users{{ID: 2, Username: "alice"}, {ID: 3, Username: "alan"}}, nil)
+	mockRepo.On("GetStatusSettingsForUsers", mock.Anything, []uint{2, 3}).Return([]models.StatusSettings{
+		{UserID: 2, Text: "Working remotely", Emoji: "🏠"},
+	}, nil)
+
+	engine := profileEngine(service, 7, false)
+	engine.GET("/api/chatrooms/search-users", SearchUsersHandler(service))
+	w := profileRequest(engine, http.MethodGet, "/api/chatrooms/search-users?q=al", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[
+		{"user_id":"2","username":"alice","name":"","status":{"user_id":2,"text":"Working remotely","emoji":"🏠","do_not_disturb":false}},
+		{"user_id":"3","username":"alan","name":""}
+	]`, w.Body.String())
+}
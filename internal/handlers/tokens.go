@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+)
+
+// CreateAccessTokenHandler godoc
+//	@Summary		Create a personal access token
+//	@Description	Creates a scoped, expiring token for CLI tools and integrations, sent as "Authorization: Bearer <token>". Scopes are read, write and websocket. The token is only returned by this request. Tokens cannot be managed with a token.
+//	@Tags			account
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			request	body		services.AccessTokenRequest	true	"Token name, scopes and lifetime in days (default 30, at most 365)"
+//	@Success		201		{object}	models.AccessToken
+//	@Failure		400		{object}	map[string]interface{}	"Invalid name, scopes or expiry"
+//	@Failure		403		{object}	map[string]interface{}	"Request was authenticated with an access token"
+//	@Failure		500		{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/tokens [post]
+func CreateAccessTokenHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, err := service.CreateAccessToken(c)
+		if err != nil {
+			c.JSON(accessTokenErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, token)
+	}
+}
+
+// GetAccessTokensHandler godoc
+//	@Summary		List personal access tokens
+//	@Description	Returns the current user's unexpired access tokens with their scopes and last use. The tokens themselves are not returned.
+//	@Tags			account
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Success		200	{array}		models.AccessToken
+//	@Failure		403	{object}	map[string]interface{}	"Request was authenticated with an access token"
+//	@Failure		500	{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/tokens [get]
+func GetAccessTokensHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokens, err := service.GetAccessTokens(c)
+		if err != nil {
+			c.JSON(accessTokenErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, tokens)
+	}
+}
+
+// RevokeAccessTokenHandler godoc
+//	@Summary		Revoke a personal access token
+//	@Description	Deletes one of the current user's access tokens and closes the WebSocket connections opened with it.
+//	@Tags			account
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			tokenID	path	int	true	"Access token ID"
+//	@Success		204
+//	@Failure		403	{object}	map[string]interface{}	"Request was authenticated with an access token"
+//	@Failure		404	{object}	map[string]interface{}	"Access token not found"
+//	@Failure		500	{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/tokens/{tokenID} [delete]
+func RevokeAccessTokenHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenID, err := service.RevokeAccessToken(c)
+		if err != nil {
+			c.JSON(accessTokenErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		userID, _ := c.Get(services.UserIDKey)
+		Broadcast <- models.Messages{
+			Type:        models.EventAccessTokenRevoked,
+			RecipientID: userID.(uint),
+			Data:        []uint{tokenID},
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func accessTokenErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrAccessTokenNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrSessionRequired):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrInvalidTokenName),
+		errors.Is(err, services.ErrInvalidTokenScopes),
+		errors.Is(err, services.ErrInvalidTokenExpiry):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/auth"
+	"github.com/kontentski/chat/internal/middleware"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/kontentski/chat/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func tokenEngine(service services.ChatRoomService) *gin.Engine {
+	engine := gin.New()
+	protected := engine.Group("/", middleware.AuthMiddleware(auth.Store, service))
+	protected.GET("/api/ping", func(c *gin.Context) {
+		userID, _ := c.Get(services.UserIDKey)
+		tokenID, _ := c.Get(services.AccessTokenIDKey)
+		c.JSON(http.StatusOK, gin.H{"user_id": userID, "token_id": tokenID})
+	})
+	protected.POST("/api/ping", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	protected.GET("/api/tokens", GetAccessTokensHandler(service))
+	protected.POST("/api/tokens", CreateAccessTokenHandler(service))
+	protected.DELETE("/api/tokens/:tokenID", RevokeAccessTokenHandler(service))
+	return engine
+}
+
+func bearerRequest(method, path, token, body string) *http.Request {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func TestAuthMiddlewareAcceptsBearerToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth.Store = storage.NewSessionStore(storage.NewMockSessionBackend(), []byte("test-secret"))
+	_, mockRepo, service := initTest()
+	engine := tokenEngine(service)
+
+	readOnly := &models.AccessToken{ID: 3, UserID: 5, Scopes: []string{models.ScopeRead}}
+	mockRepo.On("UseAccessToken", mock.Anything, "chat_pat_read").Return(readOnly, nil)
+	mockRepo.On("UseAccessToken", mock.Anything, "chat_pat_unknown").Return(nil, pgx.ErrNoRows)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, bearerRequest(http.MethodGet, "/api/ping", "chat_pat_read", ""))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id":5,"token_id":3}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, bearerRequest(http.MethodPost, "/api/ping", "chat_pat_read", ""))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), `scope="write"`)
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, bearerRequest(http.MethodGet, "/api/ping", "chat_pat_unknown", ""))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Tokens without the prefix never reach the database.
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, bearerRequest(http.MethodGet, "/api/ping", "not-a-token", ""))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	mockRepo.AssertNumberOfCalls(t, "UseAccessToken", 3)
+}
+
+func TestCreateAccessTokenHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth.Store = storage.NewSessionStore(storage.NewMockSessionBackend(), []byte("test-secret"))
+	_, mockRepo, service := initTest()
+	engine := tokenEngine(service)
+	cookie := sessionCookie(t, map[interface{}]interface{}{services.UserIDKey: uint(5), "username": "alice"})
+
+	var secret string
+	mockRepo.On("CreateAccessToken", mock.Anything, mock.AnythingOfType("*models.AccessToken"), mock.Anything, 30).
+		Run(func(args mock.Arguments) {
+			token := args.Get(1).(*models.AccessToken)
+			secret = args.String(2)
+			assert.Equal(t, uint(5), token.UserID)
+			assert.Equal(t, []string{models.ScopeRead, models.ScopeWebSocket}, token.Scopes)
+			assert.True(t, strings.HasPrefix(secret, token.Prefix))
+			token.ID = 3
+		}).Return(nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/api/tokens", strings.NewReader(`{"name":"cli","scopes":["read","websocket","read"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Cookie", cookie)
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.True(t, strings.HasPrefix(secret, "chat_pat_"))
+	assert.Contains(t, w.Body.String(), `"token":"`+secret+`"`)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/api/tokens", strings.NewReader(`{"name":"cli","scopes":["admin"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Cookie", cookie)
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// A token cannot be used to mint more tokens.
+	writer := &models.AccessToken{ID: 3, UserID: 5, Scopes: []string{models.ScopeRead, models.ScopeWrite}}
+	mockRepo.On("UseAccessToken", mock.Anything, "chat_pat_write").Return(writer, nil)
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, bearerRequest(http.MethodPost, "/api/tokens", "chat_pat_write", `{"name":"more","scopes":["write"]}`))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockRepo.AssertNumberOfCalls(t, "CreateAccessToken", 1)
+}
+
+func TestRevokeAccessTokenHandlerBroadcasts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth.Store = storage.NewSessionStore(storage.NewMockSessionBackend(), []byte("test-secret"))
+	Broadcast = make(chan models.Messages, 100)
+	_, mockRepo, service := initTest()
+	engine := tokenEngine(service)
+	cookie := sessionCookie(t, map[interface{}]interface{}{services.UserIDKey: uint(5), "username": "alice"})
+
+	mockRepo.On("RevokeAccessToken", mock.Anything, uint(5), uint(3)).Return(true, nil)
+	mockRepo.On("RevokeAccessToken", mock.Anything, uint(5), uint(4)).Return(false, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/api/tokens/3", nil)
+	req.Header.Set("Cookie", cookie)
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	msg := <-Broadcast
+	assert.Equal(t, models.EventAccessTokenRevoked, msg.Type)
+	assert.Equal(t, uint(5), msg.RecipientID)
+	assert.Equal(t, []uint{3}, msg.Data)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodDelete, "/api/tokens/4", nil)
+	req.Header.Set("Cookie", cookie)
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Len(t, Broadcast, 0)
+}
+
+func TestRevokeAccessTokenHandlerRequiresSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth.Store = storage.NewSessionStore(storage.NewMockSessionBackend(), []byte("test-secret"))
+	Broadcast = make(chan models.Messages, 100)
+	_, mockRepo, service := initTest()
+	engine := tokenEngine(service)
+
+	// A leaked token must not be able to revoke the user's other tokens
+	writer := &models.AccessToken{ID: 3, UserID: 5, Scopes: []string{models.ScopeRead, models.ScopeWrite}}
+	mockRepo.On("UseAccessToken", mock.Anything, "chat_pat_write").Return(writer, nil)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, bearerRequest(http.MethodDelete, "/api/tokens/4", "chat_pat_write", ""))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockRepo.AssertNotCalled(t, "RevokeAccessToken", mock.Anything, mock.Anything, mock.Anything)
+	assert.Len(t, Broadcast, 0)
+}
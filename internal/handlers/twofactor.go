@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/auth"
+	"github.com/kontentski/chat/internal/middleware"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+)
+
+// pendingTwoFactorTTL is how long a sign-in waits for its two-factor code.
+const pendingTwoFactorTTL = 5 * time.Minute
+
+// startAuthSession signs the user in after a password or provider check and returns the
+// two-factor state of the sign-in. If the user has two-factor authentication on, the session is
+// left pending until VerifyTwoFactorHandler gets a code.
+func startAuthSession(c *gin.Context, service services.ChatRoomService, user *models.Users) (string, error) {
+	state, err := service.LoginTwoFactorState(c.Request.Context(), user.ID)
+	if err != nil {
+		return "", err
+	}
+	if state != services.TwoFactorPending {
+		return state, saveAuthSession(c, user, state == services.TwoFactorSetup)
+	}
+
+	session, err := auth.Store.Get(c.Request, "auth-session")
+	if err != nil {
+		return "", err
+	}
+	if err := auth.Store.Renew(c.Request.Context(), session); err != nil {
+		return "", err
+	}
+	csrfToken, err := middleware.IssueCSRFToken(session)
+	if err != nil {
+		return "", err
+	}
+	session.Values[services.PendingUserIDKey] = user.ID
+	session.Values["username"] = user.Username
+	session.Values[services.PendingSinceKey] = time.Now().Unix()
+	if err := session.Save(c.Request, c.Writer); err != nil {
+		return "", err
+	}
+	middleware.SetCSRFCookie(c, session, csrfToken)
+	return state, nil
+}
+
+// VerifyTwoFactorHandler godoc
+//	@Summary		Complete sign-in with a two-factor code
+//	@Description	Completes a password or provider sign-in of a user with two-factor authentication, using a code from their authenticator or one of their recovery codes. The sign-in must be completed within five minutes.
+//	@Tags			auth
+//	@Accept			json
+//	@Accept			x-www-form-urlencoded
+//	@Produce		json
+//	@Param			request	body		services.TwoFactorCodeRequest	true	"Authenticator or recovery code"
+//	@Success		200		{object}	map[string]interface{}
+//	@Failure		401		{object}	map[string]interface{}	"No pending sign-in, sign-in expired or invalid code"
+//	@Failure		429		{object}	map[string]interface{}	"Too many wrong codes"
+//	@Failure		500		{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/auth/2fa [post]
+func VerifyTwoFactorHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session, err := auth.Store.Get(c.Request, "auth-session")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Session error"})
+			return
+		}
+		userID, ok := session.Values[services.PendingUserIDKey].(uint)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "No sign-in is waiting for a two-factor code"})
+			return
+		}
+		since, _ := session.Values[services.PendingSinceKey].(int64)
+		if time.Since(time.Unix(since, 0)) > pendingTwoFactorTTL {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": services.ErrTwoFactorExpired.Error()})
+			return
+		}
+
+		var input services.TwoFactorCodeRequest
+		if err := c.ShouldBind(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+		err = service.VerifyTwoFactor(c.Request.Context(), userID, input.Code)
+		if errors.Is(err, services.ErrInvalidTwoFactorCode) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			twoFactorError(c, err)
+			return
+		}
+
+		username, _ := session.Values["username"].(string)
+		user := &models.Users{ID: userID, Username: username}
+		if err := saveAuthSession(c, user, false); err != nil {
+			log.Println("Error saving session:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Session save error"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"user_id": user.ID, "username": user.Username})
+	}
+}
+
+// GetTwoFactorStatusHandler godoc
+//	@Summary		Get two-factor status
+//	@Description	Returns whether the current user has two-factor authentication on, whether the server requires it and how many recovery codes are left.
+//	@Tags			account
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	models.TwoFactorStatus
+//	@Failure		500	{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/account/2fa [get]
+func GetTwoFactorStatusHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, err := service.GetTwoFactorStatus(c)
+		if err != nil {
+			c.JSON(twoFactorErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// EnrollTOTPHandler godoc
+//	@Summary		Start enrolling an authenticator
+//	@Description	Generates a new authenticator secret for the current user. Show the otpauth:// URI as a QR code, then confirm with a code from the app. Starting again replaces an unconfirmed secret.
+//	@Tags			account
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	models.TOTPEnrollment
+//	@Failure		403	{object}	map[string]interface{}	"Request was authenticated with an access token"
+//	@Failure		409	{object}	map[string]interface{}	"Two-factor authentication is already enabled"
+//	@Failure		500	{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/account/2fa/enroll [post]
+func EnrollTOTPHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session, _ := auth.Store.Get(c.Request, "auth-session")
+		account, _ := session.Values["username"].(string)
+
+		enrollment, err := service.EnrollTOTP(c, account)
+		if err != nil {
+			c.JSON(twoFactorErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, enrollment)
+	}
+}
+
+// ConfirmTOTPHandler godoc
+//	@Summary		Turn on two-factor authentication
+//	@Description	Confirms the enrolled authenticator with one of its codes and turns two-factor authentication on. Returns ten one-time recovery codes, which are not shown again.
+//	@Tags			account
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			request	body		services.TwoFactorCodeRequest	true	"Authenticator code"
+//	@Success		200		{object}	services.RecoveryCodesResponse
+//	@Failure		400		{object}	map[string]interface{}	"Invalid code"
+//	@Failure		403		{object}	map[string]interface{}	"Request was authenticated with an access token"
+//	@Failure		409		{object}	map[string]interface{}	"Not enrolled, or already enabled"
+//	@Failure		500		{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/account/2fa/confirm [post]
+func ConfirmTOTPHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		codes, err := service.ConfirmTOTP(c)
+		if err != nil {
+			c.JSON(twoFactorErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		// The session no longer has to set up two-factor authentication first
+		session, err := auth.Store.Get(c.Request, "auth-session")
+		if err == nil {
+			if _, ok := session.Values[services.TwoFactorSetupKey]; ok {
+				delete(session.Values, services.TwoFactorSetupKey)
+				if err := session.Save(c.Request, c.Writer); err != nil {
+					log.Println("Error saving session:", err)
+				}
+			}
+		}
+		c.JSON(http.StatusOK, services.RecoveryCodesResponse{RecoveryCodes: codes})
+	}
+}
+
+// DisableTOTPHandler godoc
+//	@Summary		Turn off two-factor authentication
+//	@Description	Turns two-factor authentication off after checking an authenticator or recovery code. Not allowed when the server requires two-factor authentication.
+//	@Tags			account
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			request	body	services.TwoFactorCodeRequest	true	"Authenticator or recovery code"
+//	@Success		204
+//	@Failure		400	{object}	map[string]interface{}	"Invalid code"
+//	@Failure		403	{object}	map[string]interface{}	"Required by the server, or request was authenticated with an access token"
+//	@Failure		409	{object}	map[string]interface{}	"Two-factor authentication is not enabled"
+//	@Failure		429	{object}	map[string]interface{}	"Too many wrong codes"
+//	@Failure		500	{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/account/2fa [delete]
+func DisableTOTPHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := service.DisableTOTP(c); err != nil {
+			twoFactorError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// RegenerateRecoveryCodesHandler godoc
+//	@Summary		Regenerate recovery codes
+//	@Description	Replaces the current user's recovery codes with ten new ones after checking an authenticator code.
+//	@Tags			account
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Param			request	body		services.TwoFactorCodeRequest	true	"Authenticator code"
+//	@Success		200		{object}	services.RecoveryCodesResponse
+//	@Failure		400		{object}	map[string]interface{}	"Invalid code"
+//	@Failure		403		{object}	map[string]interface{}	"Request was authenticated with an access token"
+//	@Failure		409		{object}	map[string]interface{}	"Two-factor authentication is not enabled"
+//	@Failure		429		{object}	map[string]interface{}	"Too many wrong codes"
+//	@Failure		500		{object}	map[string]interface{}	"Internal Server Error"
+//	@Router			/api/account/2fa/recovery-codes [post]
+func RegenerateRecoveryCodesHandler(service services.ChatRoomService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		codes, err := service.RegenerateRecoveryCodes(c)
+		if err != nil {
+			twoFactorError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, services.RecoveryCodesResponse{RecoveryCodes: codes})
+	}
+}
+
+// twoFactorError responds to a failed code check, with a Retry-After header once the user is locked out.
+func twoFactorError(c *gin.Context, err error) {
+	var rateErr *services.RateLimitError
+	if errors.As(err, &rateErr) {
+		c.Header("Retry-After", strconv.Itoa(rateErr.RetryAfterSeconds()))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": rateErr.Error(), "retry_after": rateErr.RetryAfterSeconds()})
+		return
+	}
+	c.JSON(twoFactorErrorStatus(err), gin.H{"error": err.Error()})
+}
+
+func twoFactorErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidTwoFactorCode):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrTwoFactorRequired), errors.Is(err, services.ErrSessionRequired):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrTwoFactorEnabled), errors.Is(err, services.ErrTwoFactorNotEnabled),
+		errors.Is(err, services.ErrTwoFactorNotEnrolled):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/auth"
+	"github.com/kontentski/chat/internal/middleware"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/kontentski/chat/internal/storage"
+	"github.com/kontentski/chat/internal/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testTOTPSecret is a fixed authenticator secret, codes for it are computed with the service clock.
+const testTOTPSecret = "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+
+func twoFactorEngine(service services.ChatRoomService) *gin.Engine {
+	engine := gin.New()
+	engine.POST("/auth/login", LoginHandler(service))
+	engine.POST("/auth/2fa", VerifyTwoFactorHandler(service))
+	protected := engine.Group("/", middleware.AuthMiddleware(auth.Store, service))
+	protected.GET("/api/ping", func(c *gin.Context) {
+		userID, _ := c.Get(services.UserIDKey)
+		c.JSON(http.StatusOK, gin.H{"user_id": userID})
+	})
+	protected.GET("/api/account/2fa", GetTwoFactorStatusHandler(service))
+	protected.DELETE("/api/account/2fa", DisableTOTPHandler(service))
+	protected.POST("/api/account/2fa/enroll", EnrollTOTPHandler(service))
+	protected.POST("/api/account/2fa/confirm", ConfirmTOTPHandler(service))
+	return engine
+}
+
+func twoFactorRequest(engine *gin.Engine, method, path, cookie, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if cookie != "" {
+		req.Header.Set("Cookie", cookie)
+	}
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func twoFactorService(t *testing.T, now time.Time) (*storage.MockUser, *services.UserChatRoomServiceImpl) {
+	gin.SetMode(gin.TestMode)
+	auth.Store = storage.NewSessionStore(storage.NewMockSessionBackend(), []byte("test-secret"))
+	_, mockRepo, service := initTest()
+	service.TwoFactor = services.TwoFactorConfig{Issuer: "Chat", Now: func() time.Time { return now }}
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("Secret123"), bcrypt.MinCost)
+	mockRepo.On("GetUserByUsername", mock.Anything, "alice").
+		Return(&models.Users{ID: 5, Username: "alice", Password: string(hash)}, nil)
+	return mockRepo, service
+}
+
+func TestLoginWithTwoFactorStaysPendingUntilCode(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	mockRepo, service := twoFactorService(t, now)
+	engine := twoFactorEngine(service)
+
+	confirmedAt := now.Add(-24 * time.Hour)
+	mockRepo.On("GetTOTP", mock.Anything, uint(5)).
+		Return(&models.TOTP{UserID: 5, Secret: testTOTPSecret, ConfirmedAt: &confirmedAt}, nil)
+	mockRepo.On("UseTOTPStep", mock.Anything, uint(5), totp.Step(now)).Return(true, nil)
+
+	w := twoFactorRequest(engine, http.MethodPost, "/auth/login", "", `{"username":"alice","password":"Secret123"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"two_factor_required":true}`, w.Body.String())
+	cookie := w.Header().Get("Set-Cookie")
+
+	// The pending session is not signed in
+	assert.Equal(t, http.StatusUnauthorized, twoFactorRequest(engine, http.MethodGet, "/api/ping", cookie, "").Code)
+
+	w = twoFactorRequest(engine, http.MethodPost, "/auth/2fa", cookie, `{"code":"000000"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	code, err := totp.Code(testTOTPSecret, now)
+	assert.NoError(t, err)
+	w = twoFactorRequest(engine, http.MethodPost, "/auth/2fa", cookie, `{"code":"`+code+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id":5,"username":"alice"}`, w.Body.String())
+	signedIn := w.Header().Get("Set-Cookie")
+
+	w = twoFactorRequest(engine, http.MethodGet, "/api/ping", signedIn, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id":5}`, w.Body.String())
+
+	// The pending token was replaced when the sign-in completed
+	assert.Equal(t, http.StatusUnauthorized, twoFactorRequest(engine, http.MethodPost, "/auth/2fa", cookie, `{"code":"`+code+`"}`).Code)
+}
+
+func TestVerifyTwoFactorAcceptsRecoveryCode(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	mockRepo, service := twoFactorService(t, now)
+	engine := twoFactorEngine(service)
+
+	mockRepo.On("GetTOTP", mock.Anything, uint(5)).
+		Return(&models.TOTP{UserID: 5, Secret: testTOTPSecret, ConfirmedAt: &now}, nil)
+	mockRepo.On("UseRecoveryCode", mock.Anything, uint(5), "abcde-fghij").Return(true, nil).Once()
+	mockRepo.On("UseRecoveryCode", mock.Anything, uint(5), "abcde-fghij").Return(false, nil)
+
+	cookie := twoFactorRequest(engine, http.MethodPost, "/auth/login", "", `{"username":"alice","password":"Secret123"}`).Header().Get("Set-Cookie")
+	w := twoFactorRequest(engine, http.MethodPost, "/auth/2fa", cookie, `{"code":"ABCDE FGHIJ"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	cookie = twoFactorRequest(engine, http.MethodPost, "/auth/login", "", `{"username":"alice","password":"Secret123"}`).Header().Get("Set-Cookie")
+	w = twoFactorRequest(engine, http.MethodPost, "/auth/2fa", cookie, `{"code":"abcde-fghij"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestEnrollAndConfirmTOTP(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	mockRepo, service := twoFactorService(t, now)
+	engine := twoFactorEngine(service)
+	cookie := sessionCookie(t, map[interface{}]interface{}{services.UserIDKey: uint(5), "username": "alice"})
+
+	var secret string
+	mockRepo.On("GetTOTP", mock.Anything, uint(5)).Return(nil, pgx.ErrNoRows).Once()
+	mockRepo.On("SaveTOTPSecret", mock.Anything, uint(5), mock.Anything).
+		Run(func(args mock.Arguments) { secret = args.String(2) }).Return(nil)
+
+	w := twoFactorRequest(engine, http.MethodPost, "/api/account/2fa/enroll", cookie, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"secret":"`+secret+`"`)
+	assert.Contains(t, w.Body.String(), `otpauth://totp/Chat:alice?`)
+
+	mockRepo.On("GetTOTP", mock.Anything, uint(5)).
+		Return(&models.TOTP{UserID: 5, Secret: secret}, nil)
+	mockRepo.On("ConfirmTOTP", mock.Anything, uint(5), totp.Step(now), mock.Anything).Return(true, nil)
+
+	w = twoFactorRequest(engine, http.MethodPost, "/api/account/2fa/confirm", cookie, `{"code":"123"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	code, _ := totp.Code(secret, now)
+	w = twoFactorRequest(engine, http.MethodPost, "/api/account/2fa/confirm", cookie, `{"code":"`+code+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	codes := mockRepo.Calls[len(mockRepo.Calls)-1].Arguments.Get(3).([]string)
+	assert.Len(t, codes, 10)
+	for _, recovery := range codes {
+		assert.Contains(t, w.Body.String(), recovery)
+	}
+}
+
+func TestRequiredTwoFactorRestrictsSessionToEnrollment(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	mockRepo, service := twoFactorService(t, now)
+	service.TwoFactor.Required = true
+	engine := twoFactorEngine(service)
+
+	mockRepo.On("GetTOTP", mock.Anything, uint(5)).Return(nil, pgx.ErrNoRows)
+
+	w := twoFactorRequest(engine, http.MethodPost, "/auth/login", "", `{"username":"alice","password":"Secret123"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"two_factor_setup_required":true`)
+	cookie := w.Header().Get("Set-Cookie")
+
+	w = twoFactorRequest(engine, http.MethodGet, "/api/ping", cookie, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = twoFactorRequest(engine, http.MethodGet, "/api/account/2fa", cookie, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"enabled":false,"required":true,"recovery_codes_left":0}`, w.Body.String())
+
+	// Users cannot turn off two-factor authentication the server requires
+	signedIn := sessionCookie(t, map[interface{}]interface{}{services.UserIDKey: uint(5), "username": "alice"})
+	w = twoFactorRequest(engine, http.MethodDelete, "/api/account/2fa", signedIn, `{"code":"123456"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -2,11 +2,15 @@ package handlers
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/jackc/pgx/v5"
 	"github.com/kontentski/chat/internal/database"
 	"github.com/kontentski/chat/internal/models"
 	"github.com/kontentski/chat/internal/services"
@@ -28,8 +32,8 @@ func handleConnection(conn *websocket.Conn) {
 
 		for range ticker.C {
 			log.Println("Sending ping to client")
-			conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			// WriteControl may be called concurrently with the other writes
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
 				log.Printf("Error sending ping: %v", err)
 				return
 			}
@@ -39,11 +43,10 @@ func handleConnection(conn *websocket.Conn) {
 	log.Println("Connection setup complete")
 }
 
-func readMessages(conn *websocket.Conn, messageStorage storage.UserRepository) {
+func readMessages(conn *websocket.Conn, clientData *wsClient, messageStorage storage.UserRepository, service services.ChatRoomService) {
 	defer func() {
-		storage.UpdateLastSeen(clients[conn].userID)
-		conn.Close()
-		delete(clients, conn)
+		storage.UpdateLastSeen(clientData.userID)
+		dropClient(conn)
 	}()
 
 	for {
@@ -57,12 +60,6 @@ func readMessages(conn *websocket.Conn, messageStorage storage.UserRepository) {
 		// Log the received data for debugging
 		log.Printf("Received data: %+v", data)
 
-		clientData, ok := clients[conn]
-		if !ok {
-			log.Println("Client data not found")
-			continue
-		}
-
 		// Check if the data contains a read receipt
 		if messageID, ok := data["message_id"].(float64); ok {
 			chatRoomID := uint(data["chat_room_id"].(float64))
@@ -76,8 +73,8 @@ func readMessages(conn *websocket.Conn, messageStorage storage.UserRepository) {
 		}
 
 		// If it's a regular message, continue with the existing processing
-		var msg models.Messages
-		if err := mapToStruct(data, &msg); err != nil {
+		msg, err := socketMessage(data, clientData.userID)
+		if err != nil {
 			log.Printf("Error mapping data to message struct: %v", err)
 			continue
 		}
@@ -85,7 +82,7 @@ func readMessages(conn *websocket.Conn, messageStorage storage.UserRepository) {
 		log.Printf("Received message")
 		log.Printf("Received message data: %+v", msg)
 
-		chatRoomID := uint(data["chat_room_id"].(float64))
+		chatRoomID := msg.ChatRoomID
 		log.Printf("chat_room_id received message: %v", chatRoomID)
 
 		// Handle media type messages
@@ -100,13 +97,66 @@ func readMessages(conn *websocket.Conn, messageStorage storage.UserRepository) {
 			log.Printf("Media message content: %s", msg.Content)
 		}
 
+		if msg.Type == models.MessageTypePoll {
+			writeErrorFrame(conn, clientData, chatRoomID, "polls are created with POST /api/chatrooms/:chatRoomID/polls", 0)
+			continue
+		}
+
+		if msg.TTLSeconds != 0 {
+			if err := services.ValidateMessageTTL(msg.TTLSeconds); err != nil {
+				writeErrorFrame(conn, clientData, chatRoomID, err.Error(), 0)
+				continue
+			}
+		}
+
 		if !messageStorage.IsUserInChatRoom(clientData.userID, chatRoomID) {
 			log.Printf("User %d is not a member of chat room %d", clientData.userID, msg.ChatRoomID)
 			continue
 		}
+		if err := setDMFlag(context.Background(), messageStorage, &msg); err != nil {
+			log.Printf("Error loading chat room %d: %v", chatRoomID, err)
+			continue
+		}
+
+		// Clients cannot claim a forwarded origin over the socket, only through the forward endpoint
+		msg.ForwardedFrom = nil
+		if msg.QuotedMessageID != 0 {
+			quote, err := service.GetQuote(context.Background(), chatRoomID, msg.QuotedMessageID)
+			if err != nil {
+				writeErrorFrame(conn, clientData, chatRoomID, err.Error(), 0)
+				continue
+			}
+			msg.Quote = quote
+		}
+
+		mutedUntil, err := messageStorage.GetMutedUntil(context.Background(), chatRoomID, clientData.userID)
+		if err != nil {
+			log.Printf("Error checking mute for user %d: %v", clientData.userID, err)
+			continue
+		}
+		if mutedUntil.After(time.Now()) {
+			writeErrorFrame(conn, clientData, chatRoomID, fmt.Sprintf("you are muted in this chat room until %s", mutedUntil.Format(time.RFC3339)), 0)
+			continue
+		}
+
+		if err := service.CheckSendRate(context.Background(), clientData.userID, chatRoomID); err != nil {
+			var rateErr *services.RateLimitError
+			if errors.As(err, &rateErr) {
+				writeErrorFrame(conn, clientData, chatRoomID, rateErr.Error(), rateErr.RetryAfterSeconds())
+			} else {
+				log.Printf("Error checking send rate for user %d: %v", clientData.userID, err)
+			}
+			continue
+		}
 
 		log.Printf("Saving message:")
-		if err := saveMessageToDB(&msg); err != nil {
+		draftCleared := false
+		clearDraft := func(tx pgx.Tx) error {
+			tag, err := tx.Exec(context.Background(), storage.DeleteDraftQuery, chatRoomID, clientData.userID)
+			draftCleared = tag.RowsAffected() > 0
+			return err
+		}
+		if err := saveMessageToDB(&msg, clearDraft); err != nil {
 			log.Printf("Error saving message to DB: %v", err)
 			continue
 		}
@@ -116,7 +166,44 @@ func readMessages(conn *websocket.Conn, messageStorage storage.UserRepository) {
 
 		// Broadcast the message
 		Broadcast <- msg
+
+		// Sending a message clears the draft on the user's other devices too
+		if draftCleared {
+			Broadcast <- models.Messages{
+				ChatRoomID:  chatRoomID,
+				Type:        models.EventDraftUpdated,
+				Data:        &models.Draft{ChatRoomID: chatRoomID, UpdatedAt: msg.Timestamp},
+				RecipientID: clientData.userID,
+			}
+		}
+	}
+}
+
+// socketMessage decodes a message frame sent by userID. The sender is always the user of the
+// connection, whatever the frame claims, since slow mode and the stored row go by sender_id.
+func socketMessage(data map[string]interface{}, userID uint) (models.Messages, error) {
+	var msg models.Messages
+	if err := mapToStruct(data, &msg); err != nil {
+		return msg, err
+	}
+	chatRoomID, ok := data["chat_room_id"].(float64)
+	if !ok {
+		return msg, errors.New("chat_room_id is missing")
 	}
+	msg.SenderID = userID
+	msg.ChatRoomID = uint(chatRoomID)
+	return msg, nil
+}
+
+// setDMFlag marks msg as a direct message when its chat room is one. Whatever is_dm the client sent
+// is ignored, since the flag decides who gets notified and how the message is stored.
+func setDMFlag(ctx context.Context, messageStorage storage.UserRepository, msg *models.Messages) error {
+	room, err := messageStorage.GetChatRoom(ctx, msg.ChatRoomID)
+	if err != nil {
+		return err
+	}
+	msg.IsDM = room.IsDM
+	return nil
 }
 
 func markMessageAsRead(userID uint, messageID uint, chatRoomID uint) error {
@@ -146,13 +233,36 @@ func mapToStruct(data map[string]interface{}, target interface{}) error {
 	return json.Unmarshal(encoded, target)
 }
 
-func handleMessages(service services.ChatRoomService) {
+// HandleMessages is the broadcast hub: it delivers every event sent on Broadcast to the connected
+// clients. main runs it once for the lifetime of the server.
+func HandleMessages(service services.ChatRoomService) {
 	for msg := range Broadcast {
 
-		if msg.Type == "delete" {
+		if msg.Type == models.EventSessionRevoked || msg.Type == models.EventAccessTokenRevoked {
+			closeRevokedConnections(msg)
+			continue
+		}
+
+		if msg.RecipientID != 0 {
+			sendToUser(msg.RecipientID, msg)
+			continue
+		}
+
+		if msg.Type == models.EventProfileUpdated || msg.Type == models.EventStatusUpdated {
+			broadcastToRoomPeers(service, msg)
+			continue
+		}
+
+		if roomEvents[msg.Type] {
+			// Room events carry their own payload, so relay them as they are
+			broadcastToChatRoom(service, msg)
+			continue
+		}
+
+		if msg.Type == "delete" || msg.Type == models.EventMessageExpired {
 			log.Println("Handling delete message")
 			// Handle deletion event
-			for client, clientData := range clients {
+			for client, clientData := range connectedClients() {
 				accessibleChatRooms, err := service.FetchUserChatRoomsByUserID(clientData.userID)
 				if err != nil {
 					log.Printf("Error fetching chat rooms for user %d: %v", clientData.userID, err)
@@ -166,12 +276,11 @@ func handleMessages(service services.ChatRoomService) {
 						MessageID:  msg.MessageID,
 						ChatRoomID: msg.ChatRoomID,
 						SenderID:   msg.SenderID,
-						Type:       "delete",
+						Type:       msg.Type,
 					}
-					if err := client.WriteJSON(deletionMsg); err != nil {
+					if err := clientData.writeJSON(client, deletionMsg); err != nil {
 						log.Printf("Error broadcasting message: %v", err)
-						client.Close()
-						delete(clients, client)
+						dropClient(client)
 					}
 				}
 			}
@@ -197,25 +306,136 @@ func handleMessages(service services.ChatRoomService) {
 		}
 		msg.Sender = sender
 
-		for client, clientData := range clients {
+		broadcastToChatRoom(service, msg)
+		notifyRecipients(service, msg)
+	}
+}
+
+// roomEvents are the message types that are relayed to room members without being stored as messages.
+var roomEvents = map[string]bool{
+	models.EventMemberJoin:  true,
+	models.EventMemberLeave: true,
+	models.EventModeration:  true,
+	models.EventRoomUpdated: true,
+	models.EventPin:         true,
+	models.EventUnpin:       true,
+	models.EventPollUpdated: true,
+}
+
+// broadcastToChatRoom writes msg to every connected client that is a member of msg.ChatRoomID.
+func broadcastToChatRoom(service services.ChatRoomService, msg models.Messages) {
+	for client, clientData := range connectedClients() {
+		accessibleChatRooms, err := service.FetchUserChatRoomsByUserID(clientData.userID)
+		if err != nil {
+			log.Printf("Error fetching chat rooms for user %d: %v", clientData.userID, err)
+			continue
+		}
+
+		chatRoomIDs := getChatRoomIDs(accessibleChatRooms)
+		if contains(chatRoomIDs, msg.ChatRoomID) {
+			if err := clientData.writeJSON(client, msg); err != nil {
+				log.Printf("Error broadcasting message: %v", err)
+				dropClient(client)
+			}
+		}
+	}
+}
+
+// broadcastToRoomPeers writes msg once to every connected client that shares a chat room with
+// msg.SenderID, and to the connections of msg.SenderID itself.
+func broadcastToRoomPeers(service services.ChatRoomService, msg models.Messages) {
+	senderRooms, err := service.FetchUserChatRoomsByUserID(msg.SenderID)
+	if err != nil {
+		log.Printf("Error fetching chat rooms for user %d: %v", msg.SenderID, err)
+		return
+	}
+	senderRoomIDs := getChatRoomIDs(senderRooms)
+
+	for client, clientData := range connectedClients() {
+		if clientData.userID != msg.SenderID {
 			accessibleChatRooms, err := service.FetchUserChatRoomsByUserID(clientData.userID)
 			if err != nil {
 				log.Printf("Error fetching chat rooms for user %d: %v", clientData.userID, err)
 				continue
 			}
-
-			chatRoomIDs := getChatRoomIDs(accessibleChatRooms)
-			if contains(chatRoomIDs, msg.ChatRoomID) {
-				if err := client.WriteJSON(msg); err != nil {
-					log.Printf("Error broadcasting message: %v", err)
-					client.Close()
-					delete(clients, client)
-				}
+			if !sharesChatRoom(senderRoomIDs, getChatRoomIDs(accessibleChatRooms)) {
+				continue
 			}
 		}
+		if err := clientData.writeJSON(client, msg); err != nil {
+			log.Printf("Error broadcasting message: %v", err)
+			dropClient(client)
+		}
+	}
+}
+
+// sendToUser writes msg to every connection of the given user.
+func sendToUser(userID uint, msg models.Messages) {
+	for client, clientData := range connectedClients() {
+		if clientData.userID != userID {
+			continue
+		}
+		if err := clientData.writeJSON(client, msg); err != nil {
+			log.Printf("Error sending message to user %d: %v", userID, err)
+			dropClient(client)
+		}
+	}
+}
+
+// closeRevokedConnections tells every connection of msg.RecipientID that was authenticated with
+// one of the sessions or access tokens listed in msg.Data that it was signed out, and closes it.
+func closeRevokedConnections(msg models.Messages) {
+	revoked := make(map[uint]bool)
+	for _, id := range msg.Data.([]uint) {
+		revoked[id] = true
+	}
+	for client, clientData := range connectedClients() {
+		if clientData.userID != msg.RecipientID {
+			continue
+		}
+		if msg.Type == models.EventSessionRevoked && !revoked[clientData.sessionID] ||
+			msg.Type == models.EventAccessTokenRevoked && !revoked[clientData.accessTokenID] {
+			continue
+		}
+		clientData.writeJSON(client, models.Messages{Type: msg.Type})
+		client.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "signed out"), time.Now().Add(writeWait))
+		dropClient(client)
+	}
+}
+
+// errorFrame is written back to a client whose WebSocket message was rejected.
+type errorFrame struct {
+	Type       string `json:"type"`
+	Error      string `json:"error"`
+	ChatRoomID uint   `json:"chat_room_id,omitempty"`
+	// RetryAfter is the number of seconds to wait before sending again.
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+func writeErrorFrame(conn *websocket.Conn, client *wsClient, chatRoomID uint, message string, retryAfter int) {
+	frame := errorFrame{
+		Type:       models.EventError,
+		Error:      message,
+		ChatRoomID: chatRoomID,
+		RetryAfter: retryAfter,
+	}
+	if err := client.writeJSON(conn, frame); err != nil {
+		log.Printf("Error sending error frame: %v", err)
 	}
 }
 
+// isUserOnline reports whether the user has at least one open WebSocket connection.
+func isUserOnline(userID uint) bool {
+	clientsMu.RLock()
+	defer clientsMu.RUnlock()
+	for _, clientData := range clients {
+		if clientData.userID == userID {
+			return true
+		}
+	}
+	return false
+}
+
 // Utility function to check if a slice contains a value
 func contains(slice []uint, value uint) bool {
 	for _, v := range slice {
@@ -226,6 +446,16 @@ func contains(slice []uint, value uint) bool {
 	return false
 }
 
+// sharesChatRoom reports whether the two lists of chat room IDs have one in common.
+func sharesChatRoom(a, b []uint) bool {
+	for _, id := range b {
+		if contains(a, id) {
+			return true
+		}
+	}
+	return false
+}
+
 func getChatRoomIDs(chatRooms []models.ChatRooms) []uint {
 	var ids []uint
 	for _, room := range chatRooms {
@@ -235,7 +465,8 @@ func getChatRoomIDs(chatRooms []models.ChatRooms) []uint {
 }
 
 // saveMessageToDB saves a message to the database with an incremented message ID unique to the chat room.
-func saveMessageToDB(msg *models.Messages) error {
+// Each of afterInsert runs in the same transaction once the message ID is known, for rows that belong to the message.
+func saveMessageToDB(msg *models.Messages, afterInsert ...func(tx pgx.Tx) error) error {
 	log.Println("Starting transaction to save message")
 	tx, err := database.DB.Begin(context.Background())
 	if err != nil {
@@ -270,12 +501,45 @@ func saveMessageToDB(msg *models.Messages) error {
 	msg.MessageID = lastMessageID + 1
 	log.Printf("New message ID: %d", msg.MessageID)
 
-	insertQuery := `INSERT INTO messages (message_id, sender_id, content, chat_room_id, is_dm, timestamp, type) 
-                    VALUES ($1, $2, $3, $4, $5, NOW(), $6) RETURNING timestamp`
-	err = tx.QueryRow(context.Background(), insertQuery, msg.MessageID, msg.SenderID, msg.Content, msg.ChatRoomID, msg.IsDM, msg.Type).Scan(&msg.Timestamp)
+	// A per-message TTL wins over the room's; without either the message never expires
+	insertQuery := `INSERT INTO messages (message_id, sender_id, content, chat_room_id, is_dm, timestamp, type, expires_at,
+                        forwarded_from_room_id, forwarded_from_message_id, forwarded_from_sender_id, quoted_message_id) 
+                    VALUES ($1, $2, $3, $4, $5, NOW(), $6, CASE
+                        WHEN $7::int > 0 THEN NOW() + make_interval(secs => $7::int)
+                        ELSE (SELECT NOW() + make_interval(secs => message_ttl_seconds) FROM chat_rooms WHERE id = $4)
+                    END, $8, $9, $10, $11) RETURNING timestamp, expires_at`
+	var forwardedRoomID, forwardedMessageID, forwardedSenderID, quotedMessageID *uint
+	if msg.ForwardedFrom != nil {
+		forwardedRoomID = &msg.ForwardedFrom.ChatRoomID
+		forwardedMessageID = &msg.ForwardedFrom.MessageID
+		forwardedSenderID = &msg.ForwardedFrom.SenderID
+	}
+	if msg.QuotedMessageID != 0 {
+		quotedMessageID = &msg.QuotedMessageID
+	}
+	var expiresAt sql.NullTime
+	err = tx.QueryRow(context.Background(), insertQuery, msg.MessageID, msg.SenderID, msg.Content, msg.ChatRoomID, msg.IsDM, msg.Type, msg.TTLSeconds,
+		forwardedRoomID, forwardedMessageID, forwardedSenderID, quotedMessageID).Scan(&msg.Timestamp, &expiresAt)
 	if err != nil {
 		log.Printf("Error inserting message into DB: %v", err)
 		return err
 	}
+	if expiresAt.Valid {
+		msg.ExpiresAt = &expiresAt.Time
+	}
+
+	// A new message brings archived rooms back into the members' room lists
+	_, err = tx.Exec(context.Background(), storage.UnarchiveChatRoomQuery, msg.ChatRoomID)
+	if err != nil {
+		log.Printf("Error unarchiving chat room %d: %v", msg.ChatRoomID, err)
+		return err
+	}
+
+	for _, fn := range afterInsert {
+		if err = fn(tx); err != nil {
+			log.Printf("Error saving data of message %d: %v", msg.MessageID, err)
+			return err
+		}
+	}
 	return nil
 }
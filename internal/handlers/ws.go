@@ -2,13 +2,15 @@ package handlers
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
-	"github.com/kontentski/chat/internal/auth"
 	"github.com/kontentski/chat/internal/database"
 	"github.com/kontentski/chat/internal/models"
 	"github.com/kontentski/chat/internal/services"
@@ -17,83 +19,133 @@ import (
 
 var (
 	upgrader = websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool {
-			return true // Allow all connections
-		},
+		CheckOrigin:     checkOrigin,
 		ReadBufferSize:  1024, // Adjust buffer size as needed
 		WriteBufferSize: 1024, // Adjust buffer size as needed
 	}
-	clients = make(map[*websocket.Conn]struct {
-		userID uint
-		name   string
-	})
+	clients = make(map[*websocket.Conn]*wsClient)
+	// clientsMu guards clients, which the hub, the connection goroutines and presence lookups share.
+	clientsMu  sync.RWMutex
 	Broadcast  = make(chan models.Messages, 100) // Broadcast channel
 	pongWait   = 60 * time.Second
 	pingPeriod = (pongWait * 9) / 10
 	writeWait  = 10 * time.Second
 )
 
-// HandleWebSocket handles WebSocket requests
-func HandleWebSocket(w http.ResponseWriter, r *http.Request, service services.ChatRoomService) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+// AllowedOrigins are the origins, besides the server's own, whose pages may open a WebSocket
+// connection, for example "https://chat.example.com".
+var AllowedOrigins []string
+
+// checkOrigin allows WebSocket upgrades from the server's own pages and from AllowedOrigins.
+// Browsers always send Origin, so a request without one is from a non-browser client, which
+// cannot be made to use a victim's cookie.
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
 	if err != nil {
-		log.Printf("Failed to upgrade to WebSocket: %v", err)
-		return
+		return false
 	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range AllowedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
 
-	defer conn.Close()
-
-	go handleConnection(conn)
+// wsClient is a connected WebSocket user. sessionID or accessTokenID is what the connection was
+// authenticated with, so the connection can be closed when that session or token is revoked.
+type wsClient struct {
+	userID        uint
+	name          string
+	sessionID     uint
+	accessTokenID uint
+	// writeMu serializes writes to the connection, gorilla/websocket allows only one writer at a time.
+	writeMu sync.Mutex
+}
 
+// writeJSON writes v to the client's connection.
+func (client *wsClient) writeJSON(conn *websocket.Conn, v interface{}) error {
+	client.writeMu.Lock()
+	defer client.writeMu.Unlock()
+	conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return conn.WriteJSON(v)
+}
 
-	session, err := auth.Store.Get(r, "auth-session")
-	if err != nil {
-		log.Printf("Failed to get session: %v", err)
-		return
+// connectedClients returns a snapshot of the open connections, so they can be written to without
+// holding clientsMu.
+func connectedClients() map[*websocket.Conn]*wsClient {
+	clientsMu.RLock()
+	defer clientsMu.RUnlock()
+	snapshot := make(map[*websocket.Conn]*wsClient, len(clients))
+	for conn, client := range clients {
+		snapshot[conn] = client
 	}
-	sess :=fmt.Sprintf("sessions/%v", session)
-	fmt.Println(sess)
+	return snapshot
+}
+
+// dropClient closes the connection and forgets it.
+func dropClient(conn *websocket.Conn) {
+	conn.Close()
+	clientsMu.Lock()
+	delete(clients, conn)
+	clientsMu.Unlock()
+}
 
-	userID, ok := session.Values["userID"].(uint)
+// HandleWebSocket handles WebSocket requests. The user was authenticated by AuthMiddleware, with
+// either the session cookie or a personal access token.
+func HandleWebSocket(c *gin.Context, service services.ChatRoomService) {
+	userID, ok := c.Get(services.UserIDKey)
 	if !ok {
-		log.Printf("userID %d\n\n\n", userID)
-		log.Println("No userID in session")
+		log.Println("No userID in request")
 		return
 	}
-	defer storage.UpdateLastSeen(userID)
 
-	Username, ok := session.Values["username"].(string)
-	if !ok {
-		log.Println("No username in session")
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("Failed to upgrade to WebSocket: %v", err)
 		return
 	}
 
-	var name string
-	err = database.DB.QueryRow(context.Background(), "SELECT name FROM users WHERE id = $1", userID).Scan(&name)
+	defer conn.Close()
+
+	go handleConnection(conn)
+
+	client := &wsClient{userID: userID.(uint)}
+	client.sessionID, _ = c.Value(services.SessionIDKey).(uint)
+	client.accessTokenID, _ = c.Value(services.AccessTokenIDKey).(uint)
+	defer storage.UpdateLastSeen(client.userID)
+
+	var Username string
+	err = database.DB.QueryRow(context.Background(), "SELECT username, COALESCE(name, '') FROM users WHERE id = $1", client.userID).Scan(&Username, &client.name)
 	if err != nil {
-		log.Printf("Failed to find user with ID %d: %v", userID, err)
+		log.Printf("Failed to find user with ID %d: %v", client.userID, err)
 		return
 	}
 
-	clients[conn] = struct {
-		userID uint
-		name   string
-	}{userID, name}
+	clientsMu.Lock()
+	clients[conn] = client
+	clientsMu.Unlock()
+	name := client.name
 
-	log.Printf("Client connected: userID=%d", userID)
+	log.Printf("Client connected: userID=%d", client.userID)
 
 	userInfo := map[string]interface{}{
-		"userID":   userID,
+		"userID":   client.userID,
 		"username": Username,
 		"name":     name,
 	}
-	if err := conn.WriteJSON(userInfo); err != nil {
+	if err := client.writeJSON(conn, userInfo); err != nil {
 		log.Printf("Error sending user info: %v", err)
 		return
 	}
 	messageStorage := &storage.PostgresRepository{DB: database.DB}
 
-	readMessages(conn, messageStorage)
-	handleMessages(service)
-
+	readMessages(conn, client, messageStorage, service)
 }
@@ -0,0 +1,117 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// smtpStandIn accepts one SMTP session on a local port and sends back the envelope and data it got.
+func smtpStandIn(t *testing.T) (string, <-chan []string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var lines []string
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost ESMTP stand-in")
+		inData := false
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			if inData {
+				if line == "." {
+					inData = false
+					reply("250 OK")
+					continue
+				}
+				lines = append(lines, line)
+				continue
+			}
+			switch command := strings.ToUpper(strings.Fields(line + " ")[0]); command {
+			case "EHLO", "HELO":
+				reply("250-localhost")
+				reply("250 AUTH PLAIN")
+			case "AUTH":
+				lines = append(lines, line)
+				reply("235 Authenticated")
+			case "MAIL", "RCPT":
+				lines = append(lines, line)
+				reply("250 OK")
+			case "DATA":
+				inData = true
+				reply("354 End data with <CR><LF>.<CR><LF>")
+			case "QUIT":
+				reply("221 Bye")
+				received <- lines
+				return
+			default:
+				reply("502 Unknown command")
+			}
+		}
+	}()
+	return listener.Addr().String(), received
+}
+
+func TestSMTPMailer(t *testing.T) {
+	addr, received := smtpStandIn(t)
+	mailer := &SMTPMailer{Addr: addr, From: "chat@example.com", Username: "chat", Password: "secret"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := mailer.Send(ctx, Message{To: "alice@example.com", Subject: "Réinitialiser", Body: "Hello\n.\nBye"})
+	assert.NoError(t, err)
+
+	lines := <-received
+	transcript := strings.Join(lines, "\n")
+	assert.Contains(t, transcript, "AUTH PLAIN")
+	assert.Contains(t, transcript, "MAIL FROM:<chat@example.com>")
+	assert.Contains(t, transcript, "RCPT TO:<alice@example.com>")
+	assert.Contains(t, transcript, "To: alice@example.com")
+	assert.Contains(t, transcript, "Subject: =?utf-8?q?R=C3=A9initialiser?=")
+	// The lone dot of the body was escaped on the wire
+	assert.Contains(t, transcript, "Hello\n..\nBye")
+}
+
+func TestMailersRejectHeaderInjection(t *testing.T) {
+	mailer := &FileMailer{Dir: t.TempDir(), From: "chat@example.com"}
+	err := mailer.Send(context.Background(), Message{To: "alice@example.com\r\nBcc: eve@example.com", Subject: "Hi"})
+	assert.ErrorIs(t, err, ErrInvalidHeader)
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer := &FileMailer{Dir: dir, From: "chat@example.com"}
+
+	assert.NoError(t, mailer.Send(context.Background(), Message{To: "alice@example.com", Subject: "Verify", Body: "Open the link"}))
+	assert.NoError(t, mailer.Send(context.Background(), Message{To: "bob@example.com", Subject: "Verify", Body: "Open the link"}))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+	data, err := os.ReadFile(files[0])
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "From: chat@example.com\r\n")
+	assert.Contains(t, string(data), "\r\n\r\nOpen the link")
+}
@@ -1,15 +1,36 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/sessions"
+	"github.com/kontentski/chat/internal/models"
 	"github.com/kontentski/chat/internal/services"
 )
 
-func AuthMiddleware(store *sessions.CookieStore) gin.HandlerFunc {
+// TokenAuthenticator resolves a bearer token to the personal access token it belongs to.
+type TokenAuthenticator interface {
+	AuthenticateToken(ctx context.Context, secret string) (*models.AccessToken, error)
+}
+
+// twoFactorPath prefixes the routes a session that must set up two-factor authentication can still use.
+const twoFactorPath = "/api/account/2fa"
+
+// AuthMiddleware accepts either an "Authorization: Bearer" personal access token or the auth
+// session cookie. A bearer token takes precedence over the cookie. Sessions pending a two-factor
+// code are not signed in yet, and sessions that must set up two-factor authentication can only
+// reach the enrollment routes.
+func AuthMiddleware(store sessions.Store, tokens TokenAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if secret, ok := bearerToken(c.Request); ok {
+			authenticateToken(c, tokens, secret)
+			return
+		}
+
 		session, _ := store.Get(c.Request, "auth-session")
 		user := session.Values[services.UserIDKey]
 		if user == nil {
@@ -17,7 +38,65 @@ func AuthMiddleware(store *sessions.CookieStore) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		if setup, _ := session.Values[services.TwoFactorSetupKey].(bool); setup && !strings.HasPrefix(c.Request.URL.Path, twoFactorPath) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication must be set up first", "two_factor_setup_required": true})
+			c.Abort()
+			return
+		}
 		c.Set("userID", user)
+		if sessionID, ok := session.Values[services.SessionIDKey]; ok {
+			c.Set(services.SessionIDKey, sessionID)
+		}
 		c.Next()
 	}
 }
+
+func authenticateToken(c *gin.Context, tokens TokenAuthenticator, secret string) {
+	token, err := tokens.AuthenticateToken(c.Request.Context(), secret)
+	if errors.Is(err, services.ErrInvalidAccessToken) {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		c.Abort()
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate access token"})
+		c.Abort()
+		return
+	}
+
+	scope := requiredScope(c.Request)
+	if !token.HasScope(scope) {
+		c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access token is missing the " + scope + " scope"})
+		c.Abort()
+		return
+	}
+
+	c.Set("userID", token.UserID)
+	c.Set(services.AccessTokenIDKey, token.ID)
+	c.Next()
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, secret, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(secret), true
+}
+
+// requiredScope maps a request to the scope a token needs for it: websocket for /ws, read for
+// safe methods and write for everything else.
+func requiredScope(r *http.Request) string {
+	if r.URL.Path == "/ws" {
+		return models.ScopeWebSocket
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return models.ScopeRead
+	default:
+		return models.ScopeWrite
+	}
+}
@@ -0,0 +1,15 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/storage"
+)
+
+// ClientIP hands gin's ClientIP to the session store, so sessions record the address the
+// trusted-proxy rules give rather than whatever X-Forwarded-For a client sends.
+func ClientIP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(storage.WithClientIP(c.Request.Context(), c.ClientIP()))
+		c.Next()
+	}
+}
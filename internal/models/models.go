@@ -2,39 +2,480 @@ package models
 
 import "time"
 
+// Roles a user can hold in a chat room.
+const (
+	RoleMember = "member"
+	RoleAdmin  = "admin"
+)
+
+// Kinds of images a chat room can have.
+const (
+	RoomImageAvatar = "avatar"
+	RoomImageBanner = "banner"
+)
+
+// Notification levels a member can choose for a chat room.
+const (
+	NotificationAll      = "all"
+	NotificationMentions = "mentions"
+	NotificationNone     = "none"
+)
+
+// Event types relayed over the WebSocket next to regular chat messages.
+const (
+	EventMemberJoin  = "member_join"
+	EventMemberLeave = "member_leave"
+	EventModeration  = "moderation"
+	EventRoomUpdated = "room_updated"
+	EventPin         = "pin"
+	EventUnpin       = "unpin"
+	EventError       = "error"
+	// EventMessageExpired is relayed like "delete" when a disappearing message is erased.
+	EventMessageExpired = "expired"
+	EventPollUpdated    = "poll_updated"
+	// EventDraftUpdated is sent only to the connections of the draft's owner.
+	EventDraftUpdated = "draft_updated"
+	// EventSessionRevoked is sent to the connections of a revoked session right before they are closed.
+	EventSessionRevoked = "session_revoked"
+	// EventAccessTokenRevoked is sent to the connections of a revoked access token right before they are closed.
+	EventAccessTokenRevoked = "token_revoked"
+	// EventProfileUpdated is sent to everyone who shares a chat room with the user whose profile changed.
+	EventProfileUpdated = "profile_updated"
+	// EventStatusUpdated is sent like EventProfileUpdated when a user's status changes.
+	EventStatusUpdated = "status_updated"
+	// EventNotification asks a user's clients to alert them about a direct message or a mention.
+	EventNotification = "notification"
+)
+
+// Reasons of notification events.
+const (
+	NotificationReasonDM      = "dm"
+	NotificationReasonMention = "mention"
+)
+
+// MessageTypePoll is the message type of polls; the message content is the poll question.
+const MessageTypePoll = "poll"
+
+// Moderation actions a room admin can take against a member.
+const (
+	ModerationKick   = "kick"
+	ModerationBan    = "ban"
+	ModerationUnban  = "unban"
+	ModerationMute   = "mute"
+	ModerationUnmute = "unmute"
+	// ModerationPromote makes a member an admin, ModerationDemote makes an admin a regular member again.
+	ModerationPromote = "promote"
+	ModerationDemote  = "demote"
+)
+
+// States of a scheduled message.
+const (
+	ScheduledPending   = "pending"
+	ScheduledSending   = "sending"
+	ScheduledSent      = "sent"
+	ScheduledCancelled = "cancelled"
+	ScheduledFailed    = "failed"
+)
+
+// Scopes of a personal access token. Read allows GET requests, write all other REST requests,
+// and websocket the /ws connection.
+const (
+	ScopeRead      = "read"
+	ScopeWrite     = "write"
+	ScopeWebSocket = "websocket"
+)
+
+// Reasons a bookmarked message can no longer be shown.
+const (
+	BookmarkMessageDeleted = "message_deleted"
+	BookmarkMessageExpired = "message_expired"
+	BookmarkNotMember      = "not_a_member"
+)
+
+// Users is a user account. Password holds the password hash and is never serialized; API responses
+// about users use UserProfile. ProfilePicture is either the URL of the picture at the sign-in
+// provider or, for uploaded avatars, their path in the bucket without the size suffix.
 type Users struct {
 	ID             uint      `json:"id"`
 	Username       string    `json:"username"`
 	Name           string    `json:"name"`
-	Password       string    `json:"password"`
+	Password       string    `json:"-"`
 	Email          string    `json:"email"`
 	ProfilePicture string    `json:"profile_picture"`
 	LastSeen       time.Time `json:"last_seen"`
 	CreatedAt      time.Time `json:"created_at"`
+	// EmailVerifiedAt is set once the user followed a verification or password reset link.
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+}
+
+// UserProfile is the part of a user's account shown by the profile API. Email and EmailVerifiedAt
+// are private and only set on the user's own profile. Avatars maps the edge length in pixels to the
+// URL of an uploaded avatar of that size.
+type UserProfile struct {
+	ID              uint           `json:"id"`
+	Username        string         `json:"username"`
+	Name            string         `json:"name"`
+	ProfilePicture  string         `json:"profile_picture"`
+	Avatars         map[int]string `json:"avatars,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+	Email           string         `json:"email,omitempty"`
+	EmailVerifiedAt *time.Time     `json:"email_verified_at,omitempty"`
 }
 
 type Messages struct {
-	MessageID  uint      `json:"message_id"`
-	SenderID   uint      `json:"sender_id"`
-	Content    string    `json:"content"`
-	Timestamp  time.Time `json:"timestamp"`
-	ChatRoomID uint      `json:"chat_room_id"`
-	IsDM       bool      `json:"is_dm"`
-	ReadAt     string    `json:"read_at"`
-	Sender     Users     `json:"sender"`
-	Type       string    `json:"type,omitempty"`
+	MessageID  uint        `json:"message_id"`
+	SenderID   uint        `json:"sender_id"`
+	Content    string      `json:"content"`
+	Timestamp  time.Time   `json:"timestamp"`
+	ChatRoomID uint        `json:"chat_room_id"`
+	IsDM       bool        `json:"is_dm"`
+	ReadAt     string      `json:"read_at"`
+	Sender     Users       `json:"sender"`
+	Type       string      `json:"type,omitempty"`
+	Data       interface{} `json:"data,omitempty"`
+	// TTLSeconds asks for the message to disappear this many seconds after it is sent.
+	TTLSeconds int `json:"ttl_seconds,omitempty"`
+	// ExpiresAt is when the content of a disappearing message is erased.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// ForwardedFrom points at the original message when this one was forwarded from another room.
+	ForwardedFrom *MessageReference `json:"forwarded_from,omitempty"`
+	// QuotedMessageID is a message of the same room that this one quotes inline.
+	QuotedMessageID uint              `json:"quoted_message_id,omitempty"`
+	Quote           *MessageReference `json:"quote,omitempty"`
+	// RecipientID limits delivery of an event to a single user's connections.
+	RecipientID uint `json:"-"`
 }
 
+// MessageReference identifies another message, for forwarded copies and quotes.
+// Content and Type are filled in for quotes; the content of quoted media is left out.
+type MessageReference struct {
+	ChatRoomID uint   `json:"chat_room_id"`
+	MessageID  uint   `json:"message_id"`
+	SenderID   uint   `json:"sender_id"`
+	SenderName string `json:"sender_name,omitempty"`
+	Content    string `json:"content,omitempty"`
+	Type       string `json:"type,omitempty"`
+}
+
+// ChatRooms is a chat room. IsDM is set for direct message conversations, the rooms whose messages are marked is_dm.
 type ChatRooms struct {
-	ID          uint   `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Type        string `json:"type"`
+	ID                uint                   `json:"id"`
+	Name              string                 `json:"name"`
+	Description       string                 `json:"description"`
+	Type              string                 `json:"type"`
+	SlowModeSeconds   int                    `json:"slow_mode_seconds"`
+	RetentionDays     *int                   `json:"retention_days"`
+	MessageTTLSeconds *int                   `json:"message_ttl_seconds"`
+	AvatarPath        string                 `json:"-"`
+	BannerPath        string                 `json:"-"`
+	AvatarURL         string                 `json:"avatar_url,omitempty"`
+	BannerURL         string                 `json:"banner_url,omitempty"`
+	IsDM              bool                   `json:"is_dm"`
+	Settings          ChatRoomMemberSettings `json:"settings"`
+}
+
+// UserStatus is the custom status other users see next to a user. DoNotDisturb reports whether the
+// user is in do-not-disturb right now.
+type UserStatus struct {
+	UserID       uint       `json:"user_id"`
+	Text         string     `json:"text,omitempty"`
+	Emoji        string     `json:"emoji,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	DoNotDisturb bool       `json:"do_not_disturb"`
+}
+
+// DoNotDisturb is when a user does not want to be notified. Enabled turns it on until Until, or
+// until it is turned off when Until is nil. Schedule turns it on every day on top of that.
+type DoNotDisturb struct {
+	Enabled  bool         `json:"enabled"`
+	Until    *time.Time   `json:"until,omitempty"`
+	Schedule *DNDSchedule `json:"schedule,omitempty"`
+}
+
+// DNDSchedule is a daily do-not-disturb period from Start to End, as HH:MM in the IANA TimeZone.
+// A period that ends before it starts runs over midnight.
+type DNDSchedule struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	TimeZone string `json:"time_zone"`
+}
+
+// StatusSettings is everything a user stored about their status.
+type StatusSettings struct {
+	UserID       uint         `json:"user_id"`
+	Text         string       `json:"text"`
+	Emoji        string       `json:"emoji"`
+	ExpiresAt    *time.Time   `json:"expires_at,omitempty"`
+	DoNotDisturb DoNotDisturb `json:"do_not_disturb"`
+}
+
+// NotificationTarget is a chat room member with what decides whether they are notified about a message.
+type NotificationTarget struct {
+	UserID            uint
+	Username          string
+	NotificationLevel string
+	MutedUntil        *time.Time
+	DoNotDisturb      DoNotDisturb
+}
+
+// Notification is a user to notify about a message and why, one of the NotificationReason constants.
+type Notification struct {
+	UserID uint   `json:"user_id"`
+	Reason string `json:"reason"`
+}
+
+// ChatRoomMemberSettings are one member's personal preferences for a chat room.
+type ChatRoomMemberSettings struct {
+	MutedUntil        *time.Time `json:"muted_until"`
+	Favorite          bool       `json:"favorite"`
+	Archived          bool       `json:"archived"`
+	NotificationLevel string     `json:"notification_level"`
+	CategoryID        *uint      `json:"category_id"`
+	Position          int        `json:"position"`
+}
+
+// RoomCategory is a user-defined group of chat rooms in the room list.
+type RoomCategory struct {
+	ID        uint      `json:"id"`
+	UserID    uint      `json:"user_id"`
+	Name      string    `json:"name"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type ChatRoomMembers struct {
-	ChatRoomID uint `json:"chat_room_id"`
-	UserID     uint `json:"user_id"`
+	ChatRoomID uint      `json:"chat_room_id"`
+	UserID     uint      `json:"user_id"`
+	Role       string    `json:"role"`
+	JoinedAt   time.Time `json:"joined_at"`
+}
+
+// ChatRoomMemberProfile is a room member together with the public part of their profile.
+type ChatRoomMemberProfile struct {
+	ChatRoomID     uint      `json:"chat_room_id"`
+	UserID         uint      `json:"user_id"`
+	Username       string    `json:"username"`
+	Name           string    `json:"name"`
+	ProfilePicture string    `json:"profile_picture"`
+	Role           string    `json:"role"`
+	JoinedAt       time.Time `json:"joined_at"`
+	LastSeen       time.Time `json:"last_seen"`
+	Online         bool      `json:"online"`
+	// Status is the member's custom status, if they set one or are in do-not-disturb.
+	Status *UserStatus `json:"status,omitempty"`
+}
+
+// ModerationAction is an audit record of an admin acting on a chat room member.
+type ModerationAction struct {
+	ID           uint       `json:"id"`
+	ChatRoomID   uint       `json:"chat_room_id"`
+	ModeratorID  uint       `json:"moderator_id"`
+	TargetUserID uint       `json:"target_user_id"`
+	Action       string     `json:"action"`
+	Reason       string     `json:"reason,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// PinnedMessage is a message pinned to the top of a chat room.
+type PinnedMessage struct {
+	ChatRoomID uint      `json:"chat_room_id"`
+	MessageID  uint      `json:"message_id"`
+	PinnedBy   Users     `json:"pinned_by"`
+	PinnedAt   time.Time `json:"pinned_at"`
+	Message    Messages  `json:"message"`
+}
+
+// PurgedMessages is what was deleted from a chat room by one retention run. Media lists the bucket
+// paths of the deleted media messages.
+type PurgedMessages struct {
+	Messages     int64
+	ReadReceipts int64
+	Media        []string
+}
+
+// RetentionReport describes what a retention run purged from one chat room.
+type RetentionReport struct {
+	ChatRoomID    uint      `json:"chat_room_id"`
+	RetentionDays int       `json:"retention_days"`
+	Cutoff        time.Time `json:"cutoff"`
+	Messages      int64     `json:"messages"`
+	ReadReceipts  int64     `json:"read_receipts"`
+	MediaObjects  int       `json:"media_objects"`
+}
+
+// ScheduledMessage is a message composed ahead of time and delivered to its chat room at SendAt.
+type ScheduledMessage struct {
+	ID            uint      `json:"id"`
+	ChatRoomID    uint      `json:"chat_room_id"`
+	SenderID      uint      `json:"sender_id"`
+	Content       string    `json:"content"`
+	Type          string    `json:"type,omitempty"`
+	IsDM          bool      `json:"is_dm"`
+	SendAt        time.Time `json:"send_at"`
+	Status        string    `json:"status"`
+	MessageID     *uint     `json:"message_id,omitempty"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Poll is attached to a message of type "poll". Voters are only listed for polls that are not anonymous.
+type Poll struct {
+	ID             uint         `json:"id"`
+	ChatRoomID     uint         `json:"chat_room_id"`
+	MessageID      uint         `json:"message_id"`
+	CreatorID      uint         `json:"creator_id"`
+	Question       string       `json:"question"`
+	MultipleChoice bool         `json:"multiple_choice"`
+	Anonymous      bool         `json:"anonymous"`
+	ClosesAt       *time.Time   `json:"closes_at,omitempty"`
+	ClosedAt       *time.Time   `json:"closed_at,omitempty"`
+	Closed         bool         `json:"closed"`
+	Options        []PollOption `json:"options"`
+	TotalVoters    int          `json:"total_voters"`
+	// MyVotes are the options chosen by the user the poll was loaded for.
+	MyVotes   []uint    `json:"my_votes,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type PollOption struct {
+	ID     uint   `json:"id"`
+	Text   string `json:"text"`
+	Votes  int    `json:"votes"`
+	Voters []uint `json:"voters,omitempty"`
+}
+
+// IsClosed reports whether the poll was closed or its close time has passed.
+func (p *Poll) IsClosed(now time.Time) bool {
+	return p.ClosedAt != nil || (p.ClosesAt != nil && !now.Before(*p.ClosesAt))
+}
+
+// MessageSearchFilter narrows a full-text message search. Zero values mean no filter.
+type MessageSearchFilter struct {
+	Query      string
+	ChatRoomID uint
+	SenderID   uint
+	From       *time.Time
+	To         *time.Time
+	Type       string
+	// After continues a search below the given result, in newest-first order.
+	After *MessageSearchCursor
+	Limit int
+}
+
+// MessageSearchCursor is the position of the last result of a search page.
+type MessageSearchCursor struct {
+	Timestamp  time.Time
+	ChatRoomID uint
+	MessageID  uint
+}
+
+// MessageSearchResult is a message matching a search, with the matches marked in Snippet.
+type MessageSearchResult struct {
+	MessageID    uint      `json:"message_id"`
+	ChatRoomID   uint      `json:"chat_room_id"`
+	ChatRoomName string    `json:"chat_room_name"`
+	Sender       Users     `json:"sender"`
+	Timestamp    time.Time `json:"timestamp"`
+	Type         string    `json:"type,omitempty"`
+	// Snippet is HTML-escaped content with matches wrapped in <mark> tags.
+	Snippet string  `json:"snippet"`
+	Rank    float32 `json:"rank"`
+}
+
+// Bookmark is a message a user saved to their personal list. When the message was deleted or
+// expired, or the user left its room, Available is false and Message is left out.
+type Bookmark struct {
+	ID                uint      `json:"id"`
+	ChatRoomID        uint      `json:"chat_room_id"`
+	MessageID         uint      `json:"message_id"`
+	Note              string    `json:"note"`
+	CreatedAt         time.Time `json:"created_at"`
+	ChatRoomName      string    `json:"chat_room_name"`
+	ChatRoomType      string    `json:"chat_room_type"`
+	Available         bool      `json:"available"`
+	UnavailableReason string    `json:"unavailable_reason,omitempty"`
+	Message           *Messages `json:"message,omitempty"`
+}
+
+// Draft is a user's unsent message in a chat room. An empty Content means there is no draft.
+type Draft struct {
+	ChatRoomID uint      `json:"chat_room_id"`
+	Content    string    `json:"content"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	// DeviceID is echoed from the update so the device that made it can ignore the event.
+	DeviceID string `json:"device_id,omitempty"`
+}
+
+// UserIdentity links an account at an OAuth or OpenID Connect provider to a user.
+type UserIdentity struct {
+	ID             uint      `json:"id"`
+	UserID         uint      `json:"user_id"`
+	Provider       string    `json:"provider"`
+	ProviderUserID string    `json:"provider_user_id"`
+	Email          string    `json:"email,omitempty"`
+	LinkedAt       time.Time `json:"linked_at"`
+}
+
+// Session is a signed-in browser or device of a user.
+type Session struct {
+	ID uint `json:"id"`
+	// Device is the user agent the session was started from.
+	Device     string    `json:"device"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current marks the session the request was made with.
+	Current bool `json:"current"`
+}
+
+// AccessToken is a personal access token for API clients and bots. Only a hash of the token is
+// stored, Token is filled in once, when the token is created.
+type AccessToken struct {
+	ID     uint   `json:"id"`
+	UserID uint   `json:"user_id"`
+	Name   string `json:"name"`
+	// Prefix is the start of the token, to tell tokens apart.
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	Token      string     `json:"token,omitempty"`
+}
+
+// HasScope reports whether the token was granted scope.
+func (t *AccessToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// TOTP is a user's authenticator secret. Two-factor authentication is on once ConfirmedAt is set.
+type TOTP struct {
+	UserID uint
+	Secret string
+	// LastUsedStep is the time step of the last accepted code, so codes cannot be replayed.
+	LastUsedStep int64
+	ConfirmedAt  *time.Time
+}
+
+// TOTPEnrollment is returned when a user starts enrolling an authenticator. URI is the otpauth://
+// provisioning URI to show as a QR code, Secret is for entering the key by hand.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type TwoFactorStatus struct {
+	Enabled bool `json:"enabled"`
+	// Required is set when the server requires two-factor authentication for everyone.
+	Required          bool `json:"required"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
 }
 
 type ReadMessages struct {
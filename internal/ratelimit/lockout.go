@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+type failures struct {
+	count       int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+// Lockout counts consecutive failures per key, such as a username or an IP address. Once a key
+// reaches Threshold failures it is locked for Base, and each further failure doubles the lock up to Max.
+// A key's failures are forgotten after Max passes without a new one.
+type Lockout struct {
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+	// Now is the clock used by the lockout, it defaults to time.Now.
+	Now func() time.Time
+
+	mu       sync.Mutex
+	failures map[string]*failures
+}
+
+func NewLockout(threshold int, base, max time.Duration) *Lockout {
+	if threshold < 1 {
+		threshold = 1
+	}
+	if max < base {
+		max = base
+	}
+	return &Lockout{
+		Threshold: threshold,
+		Base:      base,
+		Max:       max,
+		Now:       time.Now,
+		failures:  make(map[string]*failures),
+	}
+}
+
+// Locked reports whether key is locked and how long until it is unlocked.
+func (l *Lockout) Locked(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f := l.get(key, l.Now())
+	if f == nil {
+		return false, 0
+	}
+	if wait := f.lockedUntil.Sub(l.Now()); wait > 0 {
+		return true, wait
+	}
+	return false, 0
+}
+
+// Fail records a failed attempt for key. It returns how long key is now locked, or zero if it is not.
+func (l *Lockout) Fail(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.Now()
+	f := l.get(key, now)
+	if f == nil {
+		if len(l.failures) >= idleBuckets {
+			l.prune(now)
+		}
+		f = &failures{}
+		l.failures[key] = f
+	}
+	f.count++
+	f.last = now
+
+	if f.count < l.Threshold {
+		return 0
+	}
+	lock := l.Base
+	for i := l.Threshold; i < f.count && lock < l.Max; i++ {
+		lock *= 2
+	}
+	if lock > l.Max {
+		lock = l.Max
+	}
+	f.lockedUntil = now.Add(lock)
+	return lock
+}
+
+// Reset forgets the failures of key, for example after a successful attempt.
+func (l *Lockout) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.failures, key)
+}
+
+// get returns the failures of key, dropping them if they are stale.
+func (l *Lockout) get(key string, now time.Time) *failures {
+	f, ok := l.failures[key]
+	if !ok {
+		return nil
+	}
+	if l.stale(f, now) {
+		delete(l.failures, key)
+		return nil
+	}
+	return f
+}
+
+func (l *Lockout) stale(f *failures, now time.Time) bool {
+	return !now.Before(f.lockedUntil) && now.Sub(f.last) >= l.Max
+}
+
+// prune forgets every key whose failures are stale.
+func (l *Lockout) prune(now time.Time) {
+	for key, f := range l.failures {
+		if l.stale(f, now) {
+			delete(l.failures, key)
+		}
+	}
+}
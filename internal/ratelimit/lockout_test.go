@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockoutBacksOff(t *testing.T) {
+	now := time.Date(2024, 8, 5, 12, 0, 0, 0, time.UTC)
+	lockout := NewLockout(3, time.Minute, 5*time.Minute)
+	lockout.Now = func() time.Time { return now }
+
+	assert.Zero(t, lockout.Fail("alice"))
+	assert.Zero(t, lockout.Fail("alice"))
+	locked, _ := lockout.Locked("alice")
+	assert.False(t, locked)
+
+	assert.Equal(t, time.Minute, lockout.Fail("alice"))
+	locked, wait := lockout.Locked("alice")
+	assert.True(t, locked)
+	assert.Equal(t, time.Minute, wait)
+
+	// Other keys are not affected
+	locked, _ = lockout.Locked("bob")
+	assert.False(t, locked)
+
+	now = now.Add(time.Minute)
+	locked, _ = lockout.Locked("alice")
+	assert.False(t, locked)
+
+	assert.Equal(t, 2*time.Minute, lockout.Fail("alice"))
+	assert.Equal(t, 4*time.Minute, lockout.Fail("alice"))
+	assert.Equal(t, 5*time.Minute, lockout.Fail("alice"))
+}
+
+func TestLockoutResetAndExpiry(t *testing.T) {
+	now := time.Date(2024, 8, 5, 12, 0, 0, 0, time.UTC)
+	lockout := NewLockout(2, time.Minute, time.Hour)
+	lockout.Now = func() time.Time { return now }
+
+	lockout.Fail("alice")
+	lockout.Reset("alice")
+	assert.Zero(t, lockout.Fail("alice"))
+
+	// Failures are forgotten once Max passes without a new one
+	now = now.Add(time.Hour)
+	assert.Zero(t, lockout.Fail("alice"))
+	assert.Equal(t, time.Minute, lockout.Fail("alice"))
+}
@@ -0,0 +1,79 @@
+// Package ratelimit implements an in-memory token bucket limiter keyed by user ID and a lockout of
+// repeatedly failing keys.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// idleBuckets is the number of tracked users above which full buckets are dropped.
+const idleBuckets = 10000
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter allows each key Burst actions at once, refilled at Rate tokens per second.
+type Limiter struct {
+	Rate  float64
+	Burst int
+	// Now is the clock used by the limiter, it defaults to time.Now.
+	Now func() time.Time
+
+	mu      sync.Mutex
+	buckets map[uint]*bucket
+}
+
+func New(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		Rate:    rate,
+		Burst:   burst,
+		Now:     time.Now,
+		buckets: make(map[uint]*bucket),
+	}
+}
+
+// Allow takes a token from key's bucket. When the bucket is empty it returns false
+// and how long the caller has to wait until a token is available.
+func (l *Limiter) Allow(key uint) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.Now()
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= idleBuckets {
+			l.prune(now)
+		}
+		b = &bucket{tokens: float64(l.Burst), last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(l.Burst), b.tokens+now.Sub(b.last).Seconds()*l.Rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	if l.Rate <= 0 {
+		return false, time.Duration(math.MaxInt64)
+	}
+	wait := time.Duration((1 - b.tokens) / l.Rate * float64(time.Second))
+	return false, wait
+}
+
+// prune forgets every bucket that would be full by now.
+func (l *Limiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.Rate >= float64(l.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	now := time.Date(2024, 8, 5, 12, 0, 0, 0, time.UTC)
+	limiter := New(0.5, 2)
+	limiter.Now = func() time.Time { return now }
+
+	ok, _ := limiter.Allow(1)
+	assert.True(t, ok)
+	ok, _ = limiter.Allow(1)
+	assert.True(t, ok)
+
+	ok, wait := limiter.Allow(1)
+	assert.False(t, ok)
+	assert.Equal(t, 2*time.Second, wait)
+
+	// Other users have their own bucket
+	ok, _ = limiter.Allow(2)
+	assert.True(t, ok)
+
+	now = now.Add(time.Second)
+	ok, wait = limiter.Allow(1)
+	assert.False(t, ok)
+	assert.Equal(t, time.Second, wait)
+
+	now = now.Add(time.Second)
+	ok, _ = limiter.Allow(1)
+	assert.True(t, ok)
+}
+
+func TestLimiterRefillIsCappedAtBurst(t *testing.T) {
+	now := time.Date(2024, 8, 5, 12, 0, 0, 0, time.UTC)
+	limiter := New(1, 3)
+	limiter.Now = func() time.Time { return now }
+
+	limiter.Allow(1)
+	now = now.Add(time.Hour)
+
+	for i := 0; i < 3; i++ {
+		ok, _ := limiter.Allow(1)
+		assert.True(t, ok)
+	}
+	ok, _ := limiter.Allow(1)
+	assert.False(t, ok)
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/kontentski/chat/internal/auth"
 	"github.com/kontentski/chat/internal/handlers"
 	"github.com/kontentski/chat/internal/middleware"
+	"github.com/kontentski/chat/internal/models"
 	"github.com/kontentski/chat/internal/services"
 	"github.com/swaggo/gin-swagger"
 	"github.com/swaggo/files"
@@ -17,13 +18,24 @@ type Router struct {
 }
 
 func NewRouter(userService services.ChatRoomService) *Router {
+	engine := gin.Default()
+	// Trust no proxy until SetTrustedProxies says otherwise, so ClientIP is the address of the peer
+	// and a client can't pick its own IP with X-Forwarded-For.
+	engine.SetTrustedProxies(nil)
 	return &Router{
-		engine:      gin.Default(),
+		engine:      engine,
 		userService: userService,
 	}
 }
 
+// SetTrustedProxies sets the proxies, as IPs or CIDRs, whose X-Forwarded-For header gives the client IP.
+func (r *Router) SetTrustedProxies(proxies []string) error {
+	return r.engine.SetTrustedProxies(proxies)
+}
+
 func (r *Router) SetupRoutes() {
+	r.engine.Use(middleware.ClientIP(), middleware.CSRF(auth.Store))
+
 	r.engine.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	r.engine.Static("/homepage", "./homepage")
@@ -31,8 +43,8 @@ func (r *Router) SetupRoutes() {
 
 
 	// WebSocket endpoint
-	r.engine.GET("/ws", middleware.AuthMiddleware(auth.Store), func(ctx *gin.Context) {
-		handlers.HandleWebSocket(ctx.Writer, ctx.Request, r.userService)
+	r.engine.GET("/ws", middleware.AuthMiddleware(auth.Store, r.userService), func(ctx *gin.Context) {
+		handlers.HandleWebSocket(ctx, r.userService)
 	})
 
 	// Authentication routes
@@ -40,22 +52,35 @@ func (r *Router) SetupRoutes() {
 
 	//Protected routes
 	protected := r.engine.Group("/")
-	protected.Use(middleware.AuthMiddleware(auth.Store))
+	protected.Use(middleware.AuthMiddleware(auth.Store, r.userService))
 	r.registerProtectedRoutes(protected)
 
 }
 
 func (r *Router) registerAuthRoutes() {
 	r.engine.GET("/auth", handlers.AuthHandler)
-	r.engine.GET("/auth/callback", handlers.CallbackHandler)
+	r.engine.GET("/auth/callback", handlers.CallbackHandler(r.userService))
 	r.engine.GET("/auth/register/", handlers.RegisterHandler)
 	r.engine.POST("/auth/register", handlers.RegisterPostHandler)
+	r.engine.POST("/auth/login", handlers.LoginHandler(r.userService))
+	r.engine.POST("/auth/2fa", handlers.VerifyTwoFactorHandler(r.userService))
+	r.engine.POST("/auth/forgot-password", handlers.ForgotPasswordHandler(r.userService))
+	r.engine.POST("/auth/reset-password", handlers.ResetPasswordHandler(r.userService))
+	r.engine.POST("/auth/verify-email", handlers.VerifyEmailHandler(r.userService))
 	r.engine.POST("/auth/logout", handlers.LogoutHandler)
+	r.engine.GET("/auth/csrf", handlers.CSRFTokenHandler)
 }
 
 func (r *Router) registerProtectedRoutes(rg *gin.RouterGroup) {
 	//user routes
 	rg.POST("/users", handlers.CreateUser(r.userService))
+	rg.GET("/api/users/me", handlers.GetOwnProfileHandler(r.userService))
+	rg.PATCH("/api/users/me", handlers.UpdateProfileHandler(r.userService))
+	rg.POST("/api/users/me/avatar", handlers.UploadAvatarHandler(r.userService))
+	rg.GET("/api/users/me/status", handlers.GetStatusHandler(r.userService))
+	rg.PUT("/api/users/me/status", handlers.SetStatusHandler(r.userService))
+	rg.PUT("/api/users/me/do-not-disturb", handlers.SetDoNotDisturbHandler(r.userService))
+	rg.GET("/api/users/:id", handlers.GetUserProfileHandler(r.userService))
 
 	// Message routes
 	rg.GET("/messages/:chatRoomID", handlers.GetMessagesHandler(r.userService))
@@ -66,6 +91,79 @@ func (r *Router) registerProtectedRoutes(rg *gin.RouterGroup) {
 	rg.POST("/api/chatrooms/leave/:chatRoomID", handlers.LeaveTheChatRoomHandler(r.userService))
 	rg.GET("/api/chatrooms/search-users", handlers.SearchUsersHandler(r.userService))
 	rg.POST("/api/chatrooms/add-user", handlers.AddUserHandler(r.userService))
+	rg.GET("/api/chatrooms/:chatRoomID", handlers.GetChatRoomHandler(r.userService))
+	rg.POST("/api/chatrooms/:chatRoomID/avatar", handlers.UploadChatRoomImageHandler(r.userService, models.RoomImageAvatar))
+	rg.POST("/api/chatrooms/:chatRoomID/banner", handlers.UploadChatRoomImageHandler(r.userService, models.RoomImageBanner))
+	rg.PUT("/api/chatrooms/:chatRoomID/retention", handlers.SetRetentionHandler(r.userService))
+	rg.PUT("/api/chatrooms/:chatRoomID/message-ttl", handlers.SetMessageTTLHandler(r.userService))
+	rg.GET("/api/chatrooms/:chatRoomID/members", handlers.GetChatRoomMembersHandler(r.userService))
+	rg.PATCH("/api/chatrooms/:chatRoomID/settings", handlers.UpdateChatRoomSettingsHandler(r.userService))
+	rg.POST("/api/chatrooms/:chatRoomID/messages/:messageID/forward", handlers.ForwardMessageHandler(r.userService))
+	rg.GET("/api/chatrooms/:chatRoomID/draft", handlers.GetDraftHandler(r.userService))
+	rg.PUT("/api/chatrooms/:chatRoomID/draft", handlers.SaveDraftHandler(r.userService))
+
+	// Account routes
+	rg.GET("/auth/link", handlers.LinkProviderHandler)
+	rg.GET("/api/account/identities", handlers.GetIdentitiesHandler(r.userService))
+	rg.POST("/api/account/verify-email", handlers.SendVerificationEmailHandler(r.userService))
+	rg.PUT("/api/account/password", handlers.ChangePasswordHandler(r.userService))
+	rg.DELETE("/api/account/identities/:provider", handlers.UnlinkIdentityHandler(r.userService))
+	rg.GET("/api/sessions", handlers.GetSessionsHandler(r.userService))
+	rg.DELETE("/api/sessions", handlers.RevokeSessionsHandler(r.userService))
+	rg.DELETE("/api/sessions/:sessionID", handlers.RevokeSessionHandler(r.userService))
+	rg.POST("/api/tokens", handlers.CreateAccessTokenHandler(r.userService))
+	rg.GET("/api/tokens", handlers.GetAccessTokensHandler(r.userService))
+	rg.DELETE("/api/tokens/:tokenID", handlers.RevokeAccessTokenHandler(r.userService))
+	rg.GET("/api/account/2fa", handlers.GetTwoFactorStatusHandler(r.userService))
+	rg.DELETE("/api/account/2fa", handlers.DisableTOTPHandler(r.userService))
+	rg.POST("/api/account/2fa/enroll", handlers.EnrollTOTPHandler(r.userService))
+	rg.POST("/api/account/2fa/confirm", handlers.ConfirmTOTPHandler(r.userService))
+	rg.POST("/api/account/2fa/recovery-codes", handlers.RegenerateRecoveryCodesHandler(r.userService))
+
+	// Category routes
+	rg.GET("/api/categories", handlers.GetRoomCategoriesHandler(r.userService))
+	rg.POST("/api/categories", handlers.CreateRoomCategoryHandler(r.userService))
+	rg.PUT("/api/categories/order", handlers.ReorderRoomCategoriesHandler(r.userService))
+	rg.PATCH("/api/categories/:categoryID", handlers.RenameRoomCategoryHandler(r.userService))
+	rg.DELETE("/api/categories/:categoryID", handlers.DeleteRoomCategoryHandler(r.userService))
+	rg.PUT("/api/categories/:categoryID/rooms", handlers.MoveChatRoomsToCategoryHandler(r.userService))
+
+	// Moderation routes
+	rg.POST("/api/chatrooms/:chatRoomID/members/:userID/kick", handlers.ModerateMemberHandler(r.userService, models.ModerationKick))
+	rg.POST("/api/chatrooms/:chatRoomID/members/:userID/ban", handlers.ModerateMemberHandler(r.userService, models.ModerationBan))
+	rg.DELETE("/api/chatrooms/:chatRoomID/members/:userID/ban", handlers.ModerateMemberHandler(r.userService, models.ModerationUnban))
+	rg.POST("/api/chatrooms/:chatRoomID/members/:userID/mute", handlers.ModerateMemberHandler(r.userService, models.ModerationMute))
+	rg.DELETE("/api/chatrooms/:chatRoomID/members/:userID/mute", handlers.ModerateMemberHandler(r.userService, models.ModerationUnmute))
+	rg.POST("/api/chatrooms/:chatRoomID/members/:userID/admin", handlers.ModerateMemberHandler(r.userService, models.ModerationPromote))
+	rg.DELETE("/api/chatrooms/:chatRoomID/members/:userID/admin", handlers.ModerateMemberHandler(r.userService, models.ModerationDemote))
+	rg.GET("/api/chatrooms/:chatRoomID/moderation-log", handlers.GetModerationLogHandler(r.userService))
+	rg.PUT("/api/chatrooms/:chatRoomID/slow-mode", handlers.SetSlowModeHandler(r.userService))
+
+	// Pin routes
+	rg.GET("/api/chatrooms/:chatRoomID/pins", handlers.GetPinnedMessagesHandler(r.userService))
+	rg.POST("/api/chatrooms/:chatRoomID/pins", handlers.PinMessageHandler(r.userService))
+	rg.DELETE("/api/chatrooms/:chatRoomID/pins/:messageID", handlers.UnpinMessageHandler(r.userService))
+
+	// Search routes
+	rg.GET("/api/search/messages", handlers.SearchMessagesHandler(r.userService))
+
+	// Bookmark routes
+	rg.GET("/api/bookmarks", handlers.GetBookmarksHandler(r.userService))
+	rg.POST("/api/bookmarks", handlers.SaveBookmarkHandler(r.userService))
+	rg.DELETE("/api/bookmarks/:bookmarkID", handlers.DeleteBookmarkHandler(r.userService))
+
+	// Poll routes
+	rg.POST("/api/chatrooms/:chatRoomID/polls", handlers.CreatePollHandler(r.userService))
+	rg.GET("/api/chatrooms/:chatRoomID/polls/:pollID", handlers.GetPollHandler(r.userService))
+	rg.PUT("/api/chatrooms/:chatRoomID/polls/:pollID/votes", handlers.VotePollHandler(r.userService))
+	rg.DELETE("/api/chatrooms/:chatRoomID/polls/:pollID/votes", handlers.RetractPollVoteHandler(r.userService))
+	rg.POST("/api/chatrooms/:chatRoomID/polls/:pollID/close", handlers.ClosePollHandler(r.userService))
+
+	// Scheduled message routes
+	rg.GET("/api/scheduled", handlers.GetScheduledMessagesHandler(r.userService))
+	rg.DELETE("/api/scheduled/:scheduledID", handlers.CancelScheduledMessageHandler(r.userService))
+	rg.GET("/api/chatrooms/:chatRoomID/scheduled", handlers.GetScheduledMessagesHandler(r.userService))
+	rg.POST("/api/chatrooms/:chatRoomID/scheduled", handlers.ScheduleMessageHandler(r.userService))
 	rg.POST("/api/upload-media", handlers.UploadMediaHandler(r.userService))
 	r.engine.GET("/hello", func(c *gin.Context) {
 		c.String(200, "Hello, World!")
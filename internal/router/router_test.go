@@ -0,0 +1,204 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/auth"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/services"
+	"github.com/kontentski/chat/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testToken = "chat_pat_test"
+
+// newTestRouter sets up the real route table on top of mocked storage.
+func newTestRouter() (*Router, *storage.MockUser) {
+	gin.SetMode(gin.TestMode)
+	auth.Store = storage.NewSessionStore(storage.NewMockSessionBackend(), []byte("test-secret"))
+	mockRepo := new(storage.MockUser)
+	service := &services.UserChatRoomServiceImpl{
+		UserRepo:     mockRepo,
+		AuthRepo:     new(storage.MockUser),
+		MediaStorage: new(storage.MockBucketStorage),
+	}
+
+	r := NewRouter(service)
+	r.SetupRoutes()
+	return r, mockRepo
+}
+
+func sessionCookie(t *testing.T, values map[interface{}]interface{}) string {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	session, _ := auth.Store.Get(req, "auth-session")
+	for k, v := range values {
+		session.Values[k] = v
+	}
+	assert.NoError(t, session.Save(req, w))
+	return w.Header().Get("Set-Cookie")
+}
+
+func withBearer(mockRepo *storage.MockUser, scopes ...string) {
+	mockRepo.On("UseAccessToken", mock.Anything, testToken).Return(&models.AccessToken{ID: 9, UserID: 5, Scopes: scopes}, nil)
+}
+
+func serve(r *Router, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.engine.ServeHTTP(w, req)
+	return w
+}
+
+func bearerRequest(method, path string) *http.Request {
+	req, _ := http.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	return req
+}
+
+func TestAccessTokenRoutes(t *testing.T) {
+	r, mockRepo := newTestRouter()
+	mockRepo.On("GetAccessTokens", mock.Anything, uint(5)).Return([]models.AccessToken{{ID: 9, UserID: 5, Name: "cli"}}, nil)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/tokens", nil)
+	req.Header.Set("Cookie", sessionCookie(t, map[interface{}]interface{}{services.UserIDKey: uint(5)}))
+	w := serve(r, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"cli"`)
+}
+
+func TestAccessTokenCannotManageAccount(t *testing.T) {
+	r, mockRepo := newTestRouter()
+	withBearer(mockRepo, models.ScopeRead, models.ScopeWrite)
+
+	for _, route := range [][2]string{
+		{http.MethodGet, "/api/tokens"},
+		{http.MethodPost, "/api/tokens"},
+		{http.MethodDelete, "/api/sessions"},
+		{http.MethodDelete, "/api/sessions/3"},
+		{http.MethodDelete, "/api/tokens/9"},
+		{http.MethodDelete, "/api/account/identities/google"},
+	} {
+		w := serve(r, bearerRequest(route[0], route[1]))
+
+		assert.Equal(t, http.StatusForbidden, w.Code, route)
+	}
+	mockRepo.AssertNotCalled(t, "RevokeSessions", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "RevokeAccessToken", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "UnlinkIdentity", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBearerTokenListsChatRooms(t *testing.T) {
+	r, mockRepo := newTestRouter()
+	withBearer(mockRepo, models.ScopeRead)
+	mockRepo.On("FetchUserChatRooms", uint(5)).Return([]models.ChatRooms{{ID: 1, Name: "General"}}, nil)
+
+	w := serve(r, bearerRequest(http.MethodGet, "/api/chatrooms"))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"General"`)
+}
+
+func TestTwoFactorSetupOnlyReachesEnrollment(t *testing.T) {
+	r, mockRepo := newTestRouter()
+	mockRepo.On("GetTOTP", mock.Anything, uint(5)).Return(nil, pgx.ErrNoRows)
+	cookie := sessionCookie(t, map[interface{}]interface{}{services.UserIDKey: uint(5), services.TwoFactorSetupKey: true})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/account/2fa", nil)
+	req.Header.Set("Cookie", cookie)
+	w := serve(r, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"enabled":false`)
+
+	req, _ = http.NewRequest(http.MethodGet, "/api/chatrooms", nil)
+	req.Header.Set("Cookie", cookie)
+	w = serve(r, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), `"two_factor_setup_required":true`)
+	mockRepo.AssertNotCalled(t, "FetchUserChatRooms", mock.Anything)
+}
+
+func TestSendVerificationEmailRoute(t *testing.T) {
+	r, mockRepo := newTestRouter()
+	withBearer(mockRepo, models.ScopeWrite)
+
+	req, _ := http.NewRequest(http.MethodPost, "/api/account/verify-email", nil)
+	w := serve(r, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// The test service has no mailer, so reaching the handler is all that can be checked
+	w = serve(r, bearerRequest(http.MethodPost, "/api/account/verify-email"))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func clientIP(t *testing.T, r *Router) string {
+	r.engine.GET("/ip", func(c *gin.Context) {
+		c.String(http.StatusOK, c.ClientIP())
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/ip", nil)
+	req.RemoteAddr = "10.0.0.1:4321"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	w := serve(r, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	return w.Body.String()
+}
+
+func TestClientIPIgnoresForwardedForByDefault(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	assert.Equal(t, "10.0.0.1", clientIP(t, NewRouter(nil)))
+}
+
+func TestClientIPFromTrustedProxy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := NewRouter(nil)
+
+	assert.NoError(t, r.SetTrustedProxies([]string{"10.0.0.0/8"}))
+	assert.Equal(t, "203.0.113.7", clientIP(t, r))
+}
+
+// loginIP signs in through the router with a forged X-Forwarded-For and returns the IP the new session recorded.
+func loginIP(t *testing.T, r *Router, mockRepo *storage.MockUser) string {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("Secret123"), bcrypt.MinCost)
+	mockRepo.On("GetUserByUsername", mock.Anything, "alice").
+		Return(&models.Users{ID: 5, Username: "alice", Password: string(hash)}, nil)
+	mockRepo.On("GetTOTP", mock.Anything, uint(5)).Return(nil, pgx.ErrNoRows)
+
+	req, _ := http.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"username":"alice","password":"Secret123"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "10.0.0.1:4321"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	w := serve(r, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	backend := auth.Store.Backend.(*storage.MockSessionBackend)
+	assert.Len(t, backend.Sessions, 1)
+	for _, session := range backend.Sessions {
+		return session.IP
+	}
+	return ""
+}
+
+func TestSessionIPIgnoresForwardedForByDefault(t *testing.T) {
+	r, mockRepo := newTestRouter()
+
+	assert.Equal(t, "10.0.0.1", loginIP(t, r, mockRepo))
+}
+
+func TestSessionIPFromTrustedProxy(t *testing.T) {
+	r, mockRepo := newTestRouter()
+	assert.NoError(t, r.SetTrustedProxies([]string{"10.0.0.0/8"}))
+
+	assert.Equal(t, "203.0.113.7", loginIP(t, r, mockRepo))
+}
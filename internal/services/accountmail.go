@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/mailer"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/ratelimit"
+	"github.com/kontentski/chat/internal/signedtoken"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrWeakPassword        = errors.New("Password must be 8 characters long and contain at least one uppercase letter and one digit")
+	ErrInvalidAccountToken = errors.New("this link is invalid or has expired")
+	ErrEmailVerified       = errors.New("email is already verified")
+	ErrMailNotConfigured   = errors.New("sending email is not configured")
+	ErrMissingEmail        = errors.New("email is required")
+)
+
+// Purposes of the signed tokens in account emails, so one kind cannot be used as another.
+const (
+	purposeResetPassword = "reset-password"
+	purposeVerifyEmail   = "verify-email"
+)
+
+// AccountMailConfig configures the password reset and email verification emails.
+type AccountMailConfig struct {
+	Mailer mailer.Mailer
+	Signer *signedtoken.Signer
+	// BaseURL is the public address links in emails point to, such as https://chat.example.com.
+	BaseURL   string
+	ResetTTL  time.Duration
+	VerifyTTL time.Duration
+	// Limiter limits how many emails each user is sent. A nil limiter is not enforced.
+	Limiter *ratelimit.Limiter
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" form:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" form:"token"`
+	Password string `json:"password" form:"password"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" form:"token"`
+}
+
+// ValidPassword reports whether password is at least 8 characters long with an uppercase letter and a digit.
+func ValidPassword(password string) bool {
+	var hasUpper, hasDigit bool
+	for _, char := range password {
+		switch {
+		case unicode.IsUpper(char):
+			hasUpper = true
+		case unicode.IsDigit(char):
+			hasDigit = true
+		}
+	}
+	return hasUpper && hasDigit && len(password) >= 8
+}
+
+// ForgotPassword emails a password reset link to the user with the email address, if there is
+// one. It never tells whether the address belongs to an account, and sends in the background so
+// that the response time does not tell either.
+func (s *UserChatRoomServiceImpl) ForgotPassword(c *gin.Context) error {
+	if s.AccountMail.Mailer == nil {
+		return ErrMailNotConfigured
+	}
+	var input ForgotPasswordRequest
+	if err := c.ShouldBind(&input); err != nil || strings.TrimSpace(input.Email) == "" {
+		return ErrMissingEmail
+	}
+
+	user, err := s.UserRepo.GetUserByEmail(c.Request.Context(), strings.TrimSpace(input.Email))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// Accounts that never picked a username cannot sign in with a password
+	if user.Username == UnregisteredUsername {
+		return nil
+	}
+	if allowed, _ := s.allowMail(user.ID); !allowed {
+		return nil
+	}
+
+	token := s.AccountMail.Signer.Sign(purposeResetPassword, user.ID, user.Password, s.AccountMail.ResetTTL)
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen this link to choose a new password:\n\n%s\n\nThe link expires in %s. If you did not ask to reset your password, you can ignore this email.\n",
+			user.Username, s.accountLink("/homepage/account/reset-password.html", token), s.AccountMail.ResetTTL),
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := s.AccountMail.Mailer.Send(ctx, msg); err != nil {
+			log.Printf("Failed to send password reset email to user %d: %v", user.ID, err)
+		}
+	}()
+	return nil
+}
+
+// PasswordReset is the user whose password was reset and the credentials that were revoked.
+type PasswordReset struct {
+	UserID       uint
+	Sessions     []uint
+	AccessTokens []uint
+}
+
+// ResetPassword sets a new password with the token of a reset email. It signs the user out
+// everywhere and revokes their personal access tokens, which could belong to whoever knew the old password.
+func (s *UserChatRoomServiceImpl) ResetPassword(c *gin.Context) (*PasswordReset, error) {
+	var input ResetPasswordRequest
+	if err := c.ShouldBind(&input); err != nil {
+		return nil, ErrInvalidAccountToken
+	}
+	if !ValidPassword(input.Password) {
+		return nil, ErrWeakPassword
+	}
+
+	ctx := c.Request.Context()
+	user, err := s.userForToken(ctx, input.Token, purposeResetPassword, func(user *models.Users) string { return user.Password })
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	// The token is bound to the old hash, so it can only be used once
+	updated, err := s.UserRepo.UpdatePassword(ctx, user.ID, user.Password, string(hash))
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrInvalidAccountToken
+	}
+	// Following the link proves the user controls the address
+	if err := s.UserRepo.MarkEmailVerified(ctx, user.ID, user.Email); err != nil {
+		return nil, err
+	}
+	if s.LoginGuard.Accounts != nil {
+		s.LoginGuard.Accounts.Reset(user.Username)
+	}
+
+	reset := &PasswordReset{UserID: user.ID}
+	if reset.Sessions, err = s.UserRepo.RevokeSessions(ctx, user.ID, 0); err != nil {
+		return nil, err
+	}
+	if reset.AccessTokens, err = s.UserRepo.RevokeAccessTokens(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	return reset, nil
+}
+
+// SendVerificationEmail emails the current user a link that verifies their address.
+func (s *UserChatRoomServiceImpl) SendVerificationEmail(c *gin.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+	if s.AccountMail.Mailer == nil {
+		return ErrMailNotConfigured
+	}
+
+	user, err := s.UserRepo.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailVerified
+	}
+	if allowed, wait := s.allowMail(user.ID); !allowed {
+		return &RateLimitError{Reason: "too many emails sent", RetryAfter: wait}
+	}
+
+	token := s.AccountMail.Signer.Sign(purposeVerifyEmail, user.ID, user.Email, s.AccountMail.VerifyTTL)
+	return s.AccountMail.Mailer.Send(c.Request.Context(), mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen this link to verify your email address:\n\n%s\n\nThe link expires in %s.\n",
+			user.Username, s.accountLink("/homepage/account/verify-email.html", token), s.AccountMail.VerifyTTL),
+	})
+}
+
+// VerifyEmail marks the address of a verification email as verified.
+func (s *UserChatRoomServiceImpl) VerifyEmail(c *gin.Context) error {
+	var input VerifyEmailRequest
+	if err := c.ShouldBind(&input); err != nil {
+		return ErrInvalidAccountToken
+	}
+
+	ctx := c.Request.Context()
+	user, err := s.userForToken(ctx, input.Token, purposeVerifyEmail, func(user *models.Users) string { return user.Email })
+	if err != nil {
+		return err
+	}
+	return s.UserRepo.MarkEmailVerified(ctx, user.ID, user.Email)
+}
+
+// userForToken returns the user a signed token was issued to, checking it against the state the
+// token is bound to.
+func (s *UserChatRoomServiceImpl) userForToken(ctx context.Context, token, purpose string, state func(*models.Users) string) (*models.Users, error) {
+	if s.AccountMail.Signer == nil {
+		return nil, ErrInvalidAccountToken
+	}
+	userID, err := signedtoken.UserID(token)
+	if err != nil {
+		return nil, ErrInvalidAccountToken
+	}
+	user, err := s.UserRepo.GetUserByID(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvalidAccountToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.AccountMail.Signer.Verify(token, purpose, state(user)); err != nil {
+		return nil, ErrInvalidAccountToken
+	}
+	return user, nil
+}
+
+func (s *UserChatRoomServiceImpl) allowMail(userID uint) (bool, time.Duration) {
+	if s.AccountMail.Limiter == nil {
+		return true, 0
+	}
+	return s.AccountMail.Limiter.Allow(userID)
+}
+
+func (s *UserChatRoomServiceImpl) accountLink(path, token string) string {
+	return strings.TrimRight(s.AccountMail.BaseURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
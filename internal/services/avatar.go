@@ -0,0 +1,119 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/avatar"
+	"github.com/kontentski/chat/internal/models"
+)
+
+var (
+	ErrInvalidAvatar       = avatar.ErrUnsupportedFormat
+	ErrAvatarTooManyPixels = avatar.ErrTooManyPixels
+	ErrAvatarTooLarge      = errors.New("avatars must be smaller than 5 MB")
+)
+
+// maxAvatarSize is the largest avatar upload accepted, in bytes.
+const maxAvatarSize = 5 << 20
+
+// profilePictureSize is the avatar size that profile_picture fields point to.
+const profilePictureSize = 128
+
+// avatarDir is the bucket folder the avatars of the user are uploaded to.
+func avatarDir(userID uint) string {
+	return fmt.Sprintf("users/%d/avatar/", userID)
+}
+
+// uploadedAvatar reports whether picture is the bucket path of an avatar the user uploaded, rather
+// than the URL of a picture at the sign-in provider. Paths outside the user's own avatar folder are
+// never treated as theirs, so they are neither signed nor deleted.
+func uploadedAvatar(userID uint, picture string) bool {
+	return strings.HasPrefix(picture, avatarDir(userID))
+}
+
+// avatarFile returns the path of one size of the uploaded avatar stored at base.
+func avatarFile(base string, size int) string {
+	return fmt.Sprintf("%s-%d.png", base, size)
+}
+
+// avatarURL returns a signed URL of the given size of an avatar the user uploaded, the same way
+// media messages are signed. Provider picture URLs are returned as they are.
+func (s *UserChatRoomServiceImpl) avatarURL(userID uint, picture string, size int) string {
+	if !uploadedAvatar(userID, picture) {
+		return picture
+	}
+	signedURL, err := s.MediaStorage.GenerateSignedURL(avatarFile(picture, size))
+	if err != nil {
+		log.Printf("Error generating signed URL for avatar %s: %v", picture, err)
+		return ""
+	}
+	return signedURL
+}
+
+// signMemberAvatar replaces the stored profile picture of a member with a URL clients can load.
+func (s *UserChatRoomServiceImpl) signMemberAvatar(member *models.ChatRoomMemberProfile) {
+	member.ProfilePicture = s.avatarURL(member.UserID, member.ProfilePicture, profilePictureSize)
+}
+
+// UploadAvatar replaces the current user's profile picture with an uploaded image. The image is
+// cropped to a square and stored in every size of avatar.Sizes under users/<id>/avatar/.
+func (s *UserChatRoomServiceImpl) UploadAvatar(c *gin.Context) (*models.UserProfile, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file: %v", err)
+	}
+	defer file.Close()
+	if header.Size > maxAvatarSize {
+		return nil, ErrAvatarTooLarge
+	}
+
+	img, err := avatar.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+	square := avatar.Square(img)
+
+	ctx := c.Request.Context()
+	user, err := s.userByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	base := fmt.Sprintf("%s%d", avatarDir(userID), time.Now().Unix())
+	for _, size := range avatar.Sizes {
+		data, err := avatar.Encode(avatar.Resize(square, size))
+		if err != nil {
+			return nil, err
+		}
+		filePath := avatarFile(base, size)
+		if _, err := s.MediaStorage.UploadFileToBucket(bytes.NewReader(data), path.Base(filePath), filePath, ctx); err != nil {
+			return nil, fmt.Errorf("failed to upload file: %v", err)
+		}
+	}
+
+	updated, err := s.UserRepo.SetProfilePicture(ctx, userID, base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save avatar: %w", err)
+	}
+
+	if uploadedAvatar(userID, user.ProfilePicture) && user.ProfilePicture != base {
+		for _, size := range avatar.Sizes {
+			if err := s.MediaStorage.DeleteFile(ctx, avatarFile(user.ProfilePicture, size)); err != nil {
+				log.Printf("Error deleting old avatar of user %d: %v", userID, err)
+			}
+		}
+	}
+	return s.profileOf(updated, userID), nil
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+)
+
+var (
+	ErrBookmarkNotFound    = errors.New("bookmark not found")
+	ErrInvalidBookmarkNote = errors.New("note must be at most 500 characters")
+)
+
+const maxBookmarkNoteLength = 500
+
+type BookmarkRequest struct {
+	ChatRoomID uint   `json:"chat_room_id" binding:"required"`
+	MessageID  uint   `json:"message_id" binding:"required"`
+	Note       string `json:"note"`
+}
+
+// SaveBookmark saves a message of a room the current user belongs to. Saving it again replaces the note.
+func (s *UserChatRoomServiceImpl) SaveBookmark(c *gin.Context) (*models.Bookmark, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var input BookmarkRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		return nil, err
+	}
+	input.Note = strings.TrimSpace(input.Note)
+	if len([]rune(input.Note)) > maxBookmarkNoteLength {
+		return nil, ErrInvalidBookmarkNote
+	}
+	if !s.UserRepo.IsUserInChatRoom(userID, input.ChatRoomID) {
+		return nil, ErrNotAuthorized
+	}
+
+	ctx := c.Request.Context()
+	msg, err := s.UserRepo.GetMessage(ctx, input.ChatRoomID, input.MessageID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+	if msg.Type == models.EventMessageExpired {
+		return nil, ErrMessageNotFound
+	}
+	room, err := s.UserRepo.GetChatRoom(ctx, input.ChatRoomID)
+	if err != nil {
+		return nil, err
+	}
+
+	bookmark := &models.Bookmark{
+		ChatRoomID:   input.ChatRoomID,
+		MessageID:    input.MessageID,
+		Note:         input.Note,
+		ChatRoomName: room.Name,
+		ChatRoomType: room.Type,
+		Available:    true,
+		Message:      msg,
+	}
+	if err := s.UserRepo.SaveBookmark(ctx, userID, bookmark); err != nil {
+		return nil, fmt.Errorf("failed to save bookmark: %w", err)
+	}
+	s.signMediaContent(bookmark.Message)
+	return bookmark, nil
+}
+
+// GetBookmarks lists the current user's saved messages across rooms, newest first.
+func (s *UserChatRoomServiceImpl) GetBookmarks(c *gin.Context) ([]models.Bookmark, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	limit, offset := parsePagination(c)
+
+	bookmarks, err := s.UserRepo.GetBookmarks(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	for i := range bookmarks {
+		if bookmarks[i].Message != nil {
+			s.signMediaContent(bookmarks[i].Message)
+		}
+	}
+	if bookmarks == nil {
+		bookmarks = []models.Bookmark{}
+	}
+	return bookmarks, nil
+}
+
+// DeleteBookmark removes one of the current user's bookmarks.
+func (s *UserChatRoomServiceImpl) DeleteBookmark(c *gin.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+	bookmarkID, err := paramUint(c, "bookmarkID")
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.UserRepo.DeleteBookmark(c.Request.Context(), userID, bookmarkID)
+	if err != nil {
+		return fmt.Errorf("failed to delete bookmark: %w", err)
+	}
+	if !deleted {
+		return ErrBookmarkNotFound
+	}
+	return nil
+}
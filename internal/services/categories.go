@@ -0,0 +1,255 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+)
+
+var (
+	ErrCategoryNotFound    = errors.New("category not found")
+	ErrInvalidCategoryName = errors.New("category name must be between 1 and 100 characters")
+)
+
+// Kinds of groups in the grouped room list.
+const (
+	GroupCategory       = "category"
+	GroupUncategorized  = "uncategorized"
+	GroupDirectMessages = "direct_messages"
+)
+
+type RoomCategoryRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type ReorderCategoriesRequest struct {
+	CategoryIDs []uint `json:"category_ids" binding:"required"`
+}
+
+type MoveChatRoomsRequest struct {
+	ChatRoomIDs []uint `json:"chat_room_ids" binding:"required"`
+}
+
+// ChatRoomGroup is one section of the grouped room list.
+type ChatRoomGroup struct {
+	Kind       string             `json:"kind"`
+	CategoryID *uint              `json:"category_id,omitempty"`
+	Name       string             `json:"name"`
+	ChatRooms  []models.ChatRooms `json:"chat_rooms"`
+}
+
+// FetchGroupedChatRooms returns the user's rooms grouped by their categories in the user's order,
+// followed by the uncategorized rooms and the direct messages.
+func (s *UserChatRoomServiceImpl) FetchGroupedChatRooms(c *gin.Context) ([]ChatRoomGroup, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	chatRooms, err := s.FetchUserChatRooms(c)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.UserRepo.GetRoomCategories(c.Request.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]ChatRoomGroup, 0, len(categories)+2)
+	byCategory := make(map[uint]int, len(categories))
+	for _, category := range categories {
+		id := category.ID
+		byCategory[id] = len(groups)
+		groups = append(groups, ChatRoomGroup{Kind: GroupCategory, CategoryID: &id, Name: category.Name, ChatRooms: []models.ChatRooms{}})
+	}
+	uncategorized := ChatRoomGroup{Kind: GroupUncategorized, Name: "Rooms", ChatRooms: []models.ChatRooms{}}
+	directMessages := ChatRoomGroup{Kind: GroupDirectMessages, Name: "Direct messages", ChatRooms: []models.ChatRooms{}}
+
+	for _, room := range chatRooms {
+		switch {
+		case room.IsDM:
+			directMessages.ChatRooms = append(directMessages.ChatRooms, room)
+		case room.Settings.CategoryID != nil:
+			if i, ok := byCategory[*room.Settings.CategoryID]; ok {
+				groups[i].ChatRooms = append(groups[i].ChatRooms, room)
+				continue
+			}
+			uncategorized.ChatRooms = append(uncategorized.ChatRooms, room)
+		default:
+			uncategorized.ChatRooms = append(uncategorized.ChatRooms, room)
+		}
+	}
+
+	groups = append(groups, uncategorized, directMessages)
+	for i := range groups {
+		rooms := groups[i].ChatRooms
+		sort.SliceStable(rooms, func(a, b int) bool {
+			if rooms[a].Settings.Position != rooms[b].Settings.Position {
+				return rooms[a].Settings.Position < rooms[b].Settings.Position
+			}
+			return rooms[a].ID < rooms[b].ID
+		})
+	}
+	return groups, nil
+}
+
+func (s *UserChatRoomServiceImpl) GetRoomCategories(c *gin.Context) ([]models.RoomCategory, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.UserRepo.GetRoomCategories(c.Request.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+	if categories == nil {
+		categories = []models.RoomCategory{}
+	}
+	return categories, nil
+}
+
+func (s *UserChatRoomServiceImpl) CreateRoomCategory(c *gin.Context) (*models.RoomCategory, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	name, err := bindCategoryName(c)
+	if err != nil {
+		return nil, err
+	}
+
+	category := &models.RoomCategory{UserID: userID, Name: name}
+	if err := s.UserRepo.CreateRoomCategory(c.Request.Context(), category); err != nil {
+		return nil, fmt.Errorf("failed to create category: %w", err)
+	}
+	return category, nil
+}
+
+func (s *UserChatRoomServiceImpl) RenameRoomCategory(c *gin.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+	categoryID, err := paramUint(c, "categoryID")
+	if err != nil {
+		return err
+	}
+	name, err := bindCategoryName(c)
+	if err != nil {
+		return err
+	}
+
+	found, err := s.UserRepo.RenameRoomCategory(c.Request.Context(), userID, categoryID, name)
+	if err != nil {
+		return fmt.Errorf("failed to rename category: %w", err)
+	}
+	if !found {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
+
+func (s *UserChatRoomServiceImpl) DeleteRoomCategory(c *gin.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+	categoryID, err := paramUint(c, "categoryID")
+	if err != nil {
+		return err
+	}
+
+	found, err := s.UserRepo.DeleteRoomCategory(c.Request.Context(), userID, categoryID)
+	if err != nil {
+		return fmt.Errorf("failed to delete category: %w", err)
+	}
+	if !found {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
+
+// ReorderRoomCategories persists a new order of the user's categories.
+func (s *UserChatRoomServiceImpl) ReorderRoomCategories(c *gin.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+	var input ReorderCategoriesRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		return err
+	}
+
+	categories, err := s.UserRepo.GetRoomCategories(c.Request.Context(), userID)
+	if err != nil {
+		return err
+	}
+	owned := make(map[uint]bool, len(categories))
+	for _, category := range categories {
+		owned[category.ID] = true
+	}
+	for _, id := range input.CategoryIDs {
+		if !owned[id] {
+			return ErrCategoryNotFound
+		}
+	}
+
+	return s.UserRepo.ReorderRoomCategories(c.Request.Context(), userID, input.CategoryIDs)
+}
+
+// MoveChatRoomsToCategory places rooms into the category in the categoryID path parameter, in the
+// given order. Category 0 is the uncategorized group.
+func (s *UserChatRoomServiceImpl) MoveChatRoomsToCategory(c *gin.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+	categoryID, err := paramUint(c, "categoryID")
+	if err != nil {
+		return err
+	}
+	var input MoveChatRoomsRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		return err
+	}
+
+	var target *uint
+	if categoryID != 0 {
+		categories, err := s.UserRepo.GetRoomCategories(c.Request.Context(), userID)
+		if err != nil {
+			return err
+		}
+		for _, category := range categories {
+			if category.ID == categoryID {
+				target = &categoryID
+				break
+			}
+		}
+		if target == nil {
+			return ErrCategoryNotFound
+		}
+	}
+
+	for _, chatRoomID := range input.ChatRoomIDs {
+		if !s.UserRepo.IsUserInChatRoom(userID, chatRoomID) {
+			return ErrNotAuthorized
+		}
+	}
+
+	return s.UserRepo.MoveChatRoomsToCategory(c.Request.Context(), userID, target, input.ChatRoomIDs)
+}
+
+func bindCategoryName(c *gin.Context) (string, error) {
+	var input RoomCategoryRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		return "", err
+	}
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > 100 {
+		return "", ErrInvalidCategoryName
+	}
+	return name, nil
+}
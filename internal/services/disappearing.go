@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+)
+
+var ErrInvalidMessageTTL = errors.New("ttl must be between 1 second and 30 days")
+
+// maxMessageTTLSeconds is the longest a disappearing message can live, 30 days.
+const maxMessageTTLSeconds = 30 * 24 * 60 * 60
+
+// expiredBatchSize is the number of expired messages erased per run.
+const expiredBatchSize = 200
+
+type MessageTTLRequest struct {
+	Seconds *int `json:"seconds"`
+}
+
+// ValidateMessageTTL checks a per-message or per-room time-to-live in seconds.
+func ValidateMessageTTL(seconds int) error {
+	if seconds <= 0 || seconds > maxMessageTTLSeconds {
+		return ErrInvalidMessageTTL
+	}
+	return nil
+}
+
+// SetMessageTTL sets after how many seconds new messages in the chat room disappear; null turns it off.
+// Any member of a DM can change it, other rooms require a room admin.
+func (s *UserChatRoomServiceImpl) SetMessageTTL(c *gin.Context) (*models.ChatRooms, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	chatRoomID, err := paramUint(c, "chatRoomID")
+	if err != nil {
+		return nil, err
+	}
+
+	var input MessageTTLRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		return nil, err
+	}
+	if input.Seconds != nil {
+		if err := ValidateMessageTTL(*input.Seconds); err != nil {
+			return nil, err
+		}
+	}
+
+	room, err := s.UserRepo.GetChatRoom(c.Request.Context(), chatRoomID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrChatRoomNotFound
+		}
+		return nil, err
+	}
+	if room.IsDM {
+		if !s.UserRepo.IsUserInChatRoom(userID, chatRoomID) {
+			return nil, ErrNotAuthorized
+		}
+	} else if _, err := s.requireRoomAdmin(c, userID, chatRoomID); err != nil {
+		return nil, err
+	}
+
+	if err := s.UserRepo.SetMessageTTL(c.Request.Context(), chatRoomID, input.Seconds); err != nil {
+		return nil, fmt.Errorf("failed to set message ttl: %w", err)
+	}
+
+	room.MessageTTLSeconds = input.Seconds
+	s.signRoomImages(room)
+	return room, nil
+}
+
+// ExpireMessages erases the content of disappearing messages whose time is up and removes their
+// media from the bucket. It returns the erased messages so removal events can be broadcast.
+func (s *UserChatRoomServiceImpl) ExpireMessages(ctx context.Context) ([]models.Messages, error) {
+	expired, err := s.UserRepo.EraseExpiredMessages(ctx, expiredBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to erase expired messages: %w", err)
+	}
+
+	for i, msg := range expired {
+		if msg.Type == "media" && msg.Content != "" {
+			if err := s.MediaStorage.DeleteFile(ctx, msg.Content); err != nil {
+				log.Printf("Error deleting media of expired message %d in chat room %d: %v", msg.MessageID, msg.ChatRoomID, err)
+			}
+		}
+		expired[i].Content = ""
+		expired[i].Type = models.EventMessageExpired
+	}
+	return expired, nil
+}
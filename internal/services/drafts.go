@@ -0,0 +1,66 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+)
+
+var ErrDraftTooLong = errors.New("draft must be at most 10000 characters")
+
+const maxDraftLength = 10000
+
+type DraftRequest struct {
+	Content string `json:"content"`
+	// DeviceID identifies the device making the change; it is echoed in the draft_updated event.
+	DeviceID string `json:"device_id"`
+}
+
+// GetDraft returns the current user's draft in the chat room.
+func (s *UserChatRoomServiceImpl) GetDraft(c *gin.Context) (*models.Draft, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	chatRoomID, err := paramUint(c, "chatRoomID")
+	if err != nil {
+		return nil, err
+	}
+	if !s.UserRepo.IsUserInChatRoom(userID, chatRoomID) {
+		return nil, ErrNotAuthorized
+	}
+
+	return s.UserRepo.GetDraft(c.Request.Context(), chatRoomID, userID)
+}
+
+// SaveDraft replaces the current user's draft in the chat room; empty content clears it.
+func (s *UserChatRoomServiceImpl) SaveDraft(c *gin.Context) (*models.Draft, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	chatRoomID, err := paramUint(c, "chatRoomID")
+	if err != nil {
+		return nil, err
+	}
+
+	var input DraftRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		return nil, err
+	}
+	if len([]rune(input.Content)) > maxDraftLength {
+		return nil, ErrDraftTooLong
+	}
+	if !s.UserRepo.IsUserInChatRoom(userID, chatRoomID) {
+		return nil, ErrNotAuthorized
+	}
+
+	draft, err := s.UserRepo.SaveDraft(c.Request.Context(), chatRoomID, userID, input.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save draft: %w", err)
+	}
+	draft.DeviceID = input.DeviceID
+	return draft, nil
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+)
+
+var (
+	ErrQuotedNotFound  = errors.New("quoted message not found in this chat room")
+	ErrForwardSameRoom = errors.New("messages can only be forwarded to another chat room, quote them instead")
+	ErrCannotForward   = errors.New("polls and expired messages cannot be forwarded")
+)
+
+type ForwardRequest struct {
+	TargetChatRoomID uint `json:"target_chat_room_id" binding:"required"`
+}
+
+// GetQuote returns the reference shown with a message that quotes messageID of the same chat room.
+func (s *UserChatRoomServiceImpl) GetQuote(ctx context.Context, chatRoomID, messageID uint) (*models.MessageReference, error) {
+	quoted, err := s.UserRepo.GetMessage(ctx, chatRoomID, messageID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrQuotedNotFound
+		}
+		return nil, err
+	}
+
+	quote := &models.MessageReference{
+		ChatRoomID: chatRoomID,
+		MessageID:  messageID,
+		SenderID:   quoted.SenderID,
+		SenderName: quoted.Sender.Name,
+		Type:       quoted.Type,
+	}
+	if quoted.Type != "media" {
+		quote.Content = quoted.Content
+	}
+	return quote, nil
+}
+
+// PrepareForward builds a copy of the message in the messageID path parameter for the target room.
+// The user must be a member of both rooms and may send to the target. Media is copied into the
+// target room's folder so the retention and expiry of the source room do not affect the copy.
+// The copy keeps a reference to the original message, also when forwarding a forwarded message.
+func (s *UserChatRoomServiceImpl) PrepareForward(c *gin.Context) (*models.Messages, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	chatRoomID, err := paramUint(c, "chatRoomID")
+	if err != nil {
+		return nil, err
+	}
+	messageID, err := paramUint(c, "messageID")
+	if err != nil {
+		return nil, err
+	}
+
+	var input ForwardRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		return nil, err
+	}
+	if input.TargetChatRoomID == chatRoomID {
+		return nil, ErrForwardSameRoom
+	}
+	if !s.UserRepo.IsUserInChatRoom(userID, chatRoomID) || !s.UserRepo.IsUserInChatRoom(userID, input.TargetChatRoomID) {
+		return nil, ErrNotAuthorized
+	}
+
+	ctx := c.Request.Context()
+	original, err := s.UserRepo.GetMessage(ctx, chatRoomID, messageID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+	if original.Type == models.MessageTypePoll || original.Type == models.EventMessageExpired {
+		return nil, ErrCannotForward
+	}
+
+	mutedUntil, err := s.UserRepo.GetMutedUntil(ctx, input.TargetChatRoomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mutedUntil.After(time.Now()) {
+		return nil, ErrUserMuted
+	}
+	if err := s.CheckSendRate(ctx, userID, input.TargetChatRoomID); err != nil {
+		return nil, err
+	}
+
+	target, err := s.UserRepo.GetChatRoom(ctx, input.TargetChatRoomID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrChatRoomNotFound
+		}
+		return nil, err
+	}
+
+	origin := original.ForwardedFrom
+	if origin == nil {
+		origin = &models.MessageReference{
+			ChatRoomID: original.ChatRoomID,
+			MessageID:  original.MessageID,
+			SenderID:   original.SenderID,
+		}
+	}
+
+	content := original.Content
+	if original.Type == "media" {
+		ext := strings.ToLower(filepath.Ext(original.Content))
+		content = fmt.Sprintf("chatrooms/%d/%d%s", input.TargetChatRoomID, time.Now().UnixNano(), ext)
+		if err := s.MediaStorage.CopyFile(ctx, original.Content, content); err != nil {
+			return nil, fmt.Errorf("failed to copy media: %w", err)
+		}
+	}
+
+	return &models.Messages{
+		SenderID:      userID,
+		Content:       content,
+		ChatRoomID:    input.TargetChatRoomID,
+		IsDM:          target.IsDM,
+		Type:          original.Type,
+		ForwardedFrom: origin,
+	}, nil
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	ErrNotAuthorized = errors.New("user not authorized")
+	ErrNoUserID      = errors.New("no userID")
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// currentUserID returns the ID of the authenticated user set by the auth middleware.
+func currentUserID(c *gin.Context) (uint, error) {
+	userID, ok := c.Get(UserIDKey)
+	if !ok {
+		return 0, ErrNoUserID
+	}
+	id, ok := userID.(uint)
+	if !ok {
+		return 0, ErrNoUserID
+	}
+	return id, nil
+}
+
+// paramUint parses a numeric path parameter such as chatRoomID.
+func paramUint(c *gin.Context, name string) (uint, error) {
+	value, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s", name)
+	}
+	return uint(value), nil
+}
+
+// parsePagination reads the limit and offset query parameters, falling back to sane defaults.
+func parsePagination(c *gin.Context) (limit, offset int) {
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+	offset, err = strconv.Atoi(c.Query("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/storage"
+)
+
+var (
+	ErrIdentityTaken         = storage.ErrIdentityTaken
+	ErrProviderAlreadyLinked = storage.ErrProviderAlreadyLinked
+	ErrEmailTaken            = storage.ErrEmailTaken
+	ErrOAuthEmailRequired    = errors.New("the provider did not share an email address")
+	ErrIdentityNotFound      = errors.New("this provider is not linked to your account")
+	ErrLastLoginMethod       = errors.New("cannot unlink the only way to sign in, set a password or link another provider first")
+)
+
+// legacyEmailProvider is the provider every account was created with before identities were tracked.
+// Its logins are matched to those accounts by email; other providers never match by email, since
+// an unverified address would be enough to take over an account.
+const legacyEmailProvider = "google"
+
+// OAuthLogin returns the user linked to the provider identity, creating a new user for it when
+// there is none. The new user still has to pick a username and password.
+func (s *UserChatRoomServiceImpl) OAuthLogin(ctx context.Context, identity *models.UserIdentity, profile *models.Users) (*models.Users, error) {
+	user, err := s.UserRepo.GetUserByIdentity(ctx, identity.Provider, identity.ProviderUserID)
+	if err == nil {
+		if err := s.UserRepo.UpdateLastSeen(ctx, user.ID); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if profile.Email == "" {
+		return nil, ErrOAuthEmailRequired
+	}
+
+	if identity.Provider == legacyEmailProvider {
+		user, err := s.UserRepo.GetUnlinkedUserByEmail(ctx, profile.Email)
+		if err == nil {
+			identity.UserID = user.ID
+			if err := s.UserRepo.LinkIdentity(ctx, identity); err != nil {
+				return nil, err
+			}
+			if err := s.UserRepo.UpdateLastSeen(ctx, user.ID); err != nil {
+				return nil, err
+			}
+			return user, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	if err := s.UserRepo.CreateOAuthUser(ctx, profile, identity); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// LinkIdentity links another provider identity to identity.UserID. Linking an identity the user
+// already has is a no-op.
+func (s *UserChatRoomServiceImpl) LinkIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	owner, err := s.UserRepo.GetUserByIdentity(ctx, identity.Provider, identity.ProviderUserID)
+	if err == nil {
+		if owner.ID == identity.UserID {
+			return nil
+		}
+		return ErrIdentityTaken
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	return s.UserRepo.LinkIdentity(ctx, identity)
+}
+
+// GetIdentities lists the provider identities linked to the current user.
+func (s *UserChatRoomServiceImpl) GetIdentities(c *gin.Context) ([]models.UserIdentity, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	return s.UserRepo.GetIdentities(c.Request.Context(), userID)
+}
+
+// UnlinkIdentity removes the current user's identity of the provider in the path, unless the user
+// would be left without a password or another identity to sign in with.
+func (s *UserChatRoomServiceImpl) UnlinkIdentity(c *gin.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+	if err := requireSession(c); err != nil {
+		return err
+	}
+	provider := c.Param("provider")
+
+	identities, err := s.UserRepo.GetIdentities(c.Request.Context(), userID)
+	if err != nil {
+		return err
+	}
+	linked := false
+	for _, identity := range identities {
+		if identity.Provider == provider {
+			linked = true
+			break
+		}
+	}
+	if !linked {
+		return ErrIdentityNotFound
+	}
+
+	removed, err := s.UserRepo.UnlinkIdentity(c.Request.Context(), userID, provider)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrLastLoginMethod
+	}
+	return nil
+}
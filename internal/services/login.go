@@ -0,0 +1,166 @@
+package services
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/ratelimit"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrMissingCredentials = errors.New("username and password are required")
+	ErrWrongPassword      = errors.New("current password is incorrect")
+	ErrNotRegistered      = errors.New("pick a username and password at /auth/register first")
+)
+
+// UnregisteredUsername is given to users who signed in with OAuth but have not picked a username yet.
+const UnregisteredUsername = "system_default"
+
+type LoginRequest struct {
+	Username string `json:"username" form:"username"`
+	Password string `json:"password" form:"password"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" form:"current_password"`
+	NewPassword     string `json:"new_password" form:"new_password"`
+}
+
+// LoginGuard locks out usernames and client IPs after repeated failed logins, and users after
+// repeated wrong two-factor codes. A nil lockout is not enforced.
+type LoginGuard struct {
+	Accounts *ratelimit.Lockout
+	IPs      *ratelimit.Lockout
+	Codes    *ratelimit.Lockout
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// compareDummyHash spends as long as a real password check, so unknown usernames cannot be told apart by timing.
+func compareDummyHash(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+	})
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
+
+// Login checks a username and bcrypt password. Failed attempts count against both the username
+// and the client IP, and a locked username or IP gets a RateLimitError without checking the password.
+func (s *UserChatRoomServiceImpl) Login(c *gin.Context) (*models.Users, error) {
+	var input LoginRequest
+	if err := c.ShouldBind(&input); err != nil || input.Username == "" || input.Password == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	ip := c.ClientIP()
+	if err := checkLockout(s.LoginGuard.IPs, ip); err != nil {
+		return nil, err
+	}
+	if err := checkLockout(s.LoginGuard.Accounts, input.Username); err != nil {
+		return nil, err
+	}
+
+	user, err := s.UserRepo.GetUserByUsername(c.Request.Context(), input.Username)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if user == nil || user.Username == UnregisteredUsername {
+		compareDummyHash(input.Password)
+		s.loginFailed(ip, input.Username)
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
+		s.loginFailed(ip, input.Username)
+		return nil, ErrInvalidCredentials
+	}
+
+	if s.LoginGuard.Accounts != nil {
+		s.LoginGuard.Accounts.Reset(input.Username)
+	}
+	return user, nil
+}
+
+// ChangePassword replaces the current user's password after checking the current one. Like a
+// password reset it revokes the user's access tokens, and it signs out every other session.
+// Wrong current passwords count against the username the same way failed logins do.
+func (s *UserChatRoomServiceImpl) ChangePassword(c *gin.Context) (*PasswordReset, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireSession(c); err != nil {
+		return nil, err
+	}
+	var input ChangePasswordRequest
+	if err := c.ShouldBind(&input); err != nil || input.CurrentPassword == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	ctx := c.Request.Context()
+	user, err := s.userByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Username == UnregisteredUsername {
+		return nil, ErrNotRegistered
+	}
+	if err := checkLockout(s.LoginGuard.Accounts, user.Username); err != nil {
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.CurrentPassword)); err != nil {
+		s.loginFailed(c.ClientIP(), user.Username)
+		return nil, ErrWrongPassword
+	}
+	if !ValidPassword(input.NewPassword) {
+		return nil, ErrWeakPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	// Bound to the hash that was checked, so a concurrent change is not overwritten
+	updated, err := s.UserRepo.UpdatePassword(ctx, user.ID, user.Password, string(hash))
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrWrongPassword
+	}
+
+	reset := &PasswordReset{UserID: user.ID}
+	if reset.Sessions, err = s.UserRepo.RevokeSessions(ctx, user.ID, currentSessionID(c)); err != nil {
+		return nil, err
+	}
+	if reset.AccessTokens, err = s.UserRepo.RevokeAccessTokens(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	return reset, nil
+}
+
+func (s *UserChatRoomServiceImpl) loginFailed(ip, username string) {
+	if s.LoginGuard.IPs != nil {
+		s.LoginGuard.IPs.Fail(ip)
+	}
+	if s.LoginGuard.Accounts != nil {
+		s.LoginGuard.Accounts.Fail(username)
+	}
+}
+
+func checkLockout(lockout *ratelimit.Lockout, key string) error {
+	if lockout == nil {
+		return nil
+	}
+	if locked, wait := lockout.Locked(key); locked {
+		return &RateLimitError{Reason: "too many failed login attempts", RetryAfter: wait}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+)
+
+type ChatRoomMembersResponse struct {
+	Members []models.ChatRoomMemberProfile `json:"members"`
+	Total   int                            `json:"total"`
+	Limit   int                            `json:"limit"`
+	Offset  int                            `json:"offset"`
+}
+
+// GetChatRoomMembers returns a page of the members of a chat room. Only members may list the room.
+func (s *UserChatRoomServiceImpl) GetChatRoomMembers(c *gin.Context) (*ChatRoomMembersResponse, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	chatRoomID, err := paramUint(c, "chatRoomID")
+	if err != nil {
+		return nil, err
+	}
+	if !s.UserRepo.IsUserInChatRoom(userID, chatRoomID) {
+		return nil, ErrNotAuthorized
+	}
+
+	limit, offset := parsePagination(c)
+	members, err := s.UserRepo.GetChatRoomMembers(c.Request.Context(), chatRoomID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.UserRepo.CountChatRoomMembers(c.Request.Context(), chatRoomID)
+	if err != nil {
+		return nil, err
+	}
+	if members == nil {
+		members = []models.ChatRoomMemberProfile{}
+	}
+	userIDs := make([]uint, len(members))
+	for i := range members {
+		userIDs[i] = members[i].UserID
+	}
+	statuses, err := s.userStatuses(c.Request.Context(), userIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range members {
+		s.signMemberAvatar(&members[i])
+		members[i].Status = statuses[members[i].UserID]
+	}
+
+	return &ChatRoomMembersResponse{
+		Members: members,
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+	}, nil
+}
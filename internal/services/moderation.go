@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+)
+
+var (
+	ErrNotRoomAdmin       = errors.New("only room admins can do this")
+	ErrInvalidModeration  = errors.New("admins cannot moderate themselves or other admins")
+	ErrTargetNotMember    = errors.New("user is not part of the chat room")
+	ErrTargetNotAdmin     = errors.New("user is not an admin of the chat room")
+	ErrUserBanned         = errors.New("user is banned from the chat room")
+	ErrMuteDurationNeeded = errors.New("duration_seconds must be greater than zero")
+)
+
+type ModerationRequest struct {
+	Reason          string `json:"reason"`
+	DurationSeconds int    `json:"duration_seconds"`
+}
+
+type ModerationResponse struct {
+	Action *models.ModerationAction      `json:"action"`
+	Member *models.ChatRoomMemberProfile `json:"member,omitempty"`
+}
+
+// requireRoomAdmin checks that the user is an admin of the chat room and returns their membership.
+func (s *UserChatRoomServiceImpl) requireRoomAdmin(c *gin.Context, userID, chatRoomID uint) (*models.ChatRoomMemberProfile, error) {
+	member, err := s.UserRepo.GetChatRoomMember(c.Request.Context(), chatRoomID, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotAuthorized
+		}
+		return nil, err
+	}
+	if member.Role != models.RoleAdmin {
+		return nil, ErrNotRoomAdmin
+	}
+	return member, nil
+}
+
+// ModerateMember applies a moderation action from a room admin to the member in the userID path parameter.
+func (s *UserChatRoomServiceImpl) ModerateMember(c *gin.Context, action string) (*ModerationResponse, error) {
+	moderatorID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	chatRoomID, err := paramUint(c, "chatRoomID")
+	if err != nil {
+		return nil, err
+	}
+	targetID, err := paramUint(c, "userID")
+	if err != nil {
+		return nil, err
+	}
+
+	var input ModerationRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := s.requireRoomAdmin(c, moderatorID, chatRoomID); err != nil {
+		return nil, err
+	}
+	if targetID == moderatorID {
+		return nil, ErrInvalidModeration
+	}
+
+	target, err := s.UserRepo.GetChatRoomMember(c.Request.Context(), chatRoomID, targetID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	// Bans may be placed on anyone, every other action needs a current member
+	if target == nil && action != models.ModerationBan && action != models.ModerationUnban {
+		return nil, ErrTargetNotMember
+	}
+	// Admins can only be demoted, and only admins can be
+	if target != nil && target.Role == models.RoleAdmin && action != models.ModerationDemote {
+		return nil, ErrInvalidModeration
+	}
+	if action == models.ModerationDemote && target.Role != models.RoleAdmin {
+		return nil, ErrTargetNotAdmin
+	}
+
+	record := &models.ModerationAction{
+		ChatRoomID:   chatRoomID,
+		ModeratorID:  moderatorID,
+		TargetUserID: targetID,
+		Action:       action,
+		Reason:       input.Reason,
+	}
+	if action == models.ModerationMute {
+		if input.DurationSeconds <= 0 {
+			return nil, ErrMuteDurationNeeded
+		}
+		expiresAt := time.Now().Add(time.Duration(input.DurationSeconds) * time.Second)
+		record.ExpiresAt = &expiresAt
+	}
+
+	if err := s.UserRepo.ApplyModerationAction(c.Request.Context(), record); err != nil {
+		return nil, fmt.Errorf("failed to %s user: %w", action, err)
+	}
+
+	if target != nil {
+		switch action {
+		case models.ModerationPromote:
+			target.Role = models.RoleAdmin
+		case models.ModerationDemote:
+			target.Role = models.RoleMember
+		}
+		s.signMemberAvatar(target)
+	}
+	return &ModerationResponse{Action: record, Member: target}, nil
+}
+
+// GetModerationLog lists the moderation actions taken in a chat room, newest first. Only admins can read it.
+func (s *UserChatRoomServiceImpl) GetModerationLog(c *gin.Context) ([]models.ModerationAction, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	chatRoomID, err := paramUint(c, "chatRoomID")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.requireRoomAdmin(c, userID, chatRoomID); err != nil {
+		return nil, err
+	}
+
+	limit, offset := parsePagination(c)
+	actions, err := s.UserRepo.GetModerationActions(c.Request.Context(), chatRoomID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if actions == nil {
+		actions = []models.ModerationAction{}
+	}
+	return actions, nil
+}
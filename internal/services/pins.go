@@ -0,0 +1,128 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+)
+
+var (
+	ErrMessageNotFound = errors.New("message not found")
+	ErrNotPinned       = errors.New("message is not pinned")
+)
+
+type PinRequest struct {
+	MessageID uint `json:"message_id" binding:"required"`
+}
+
+// PinMessage pins a message of the chat room and returns the pin as stored, which for an already
+// pinned message is the original one. Only room admins can pin.
+func (s *UserChatRoomServiceImpl) PinMessage(c *gin.Context) (*models.PinnedMessage, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	chatRoomID, err := paramUint(c, "chatRoomID")
+	if err != nil {
+		return nil, err
+	}
+
+	var input PinRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.requireRoomAdmin(c, userID, chatRoomID); err != nil {
+		return nil, err
+	}
+
+	msg, err := s.UserRepo.GetMessage(c.Request.Context(), chatRoomID, input.MessageID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+
+	pin, err := s.UserRepo.PinMessage(c.Request.Context(), chatRoomID, input.MessageID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pin message: %w", err)
+	}
+	s.signMediaContent(msg)
+	pin.Message = *msg
+	return pin, nil
+}
+
+// UnpinMessage removes the pin from the message in the messageID path parameter. Only room admins can unpin.
+func (s *UserChatRoomServiceImpl) UnpinMessage(c *gin.Context) (*models.PinnedMessage, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	chatRoomID, err := paramUint(c, "chatRoomID")
+	if err != nil {
+		return nil, err
+	}
+	messageID, err := paramUint(c, "messageID")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.requireRoomAdmin(c, userID, chatRoomID); err != nil {
+		return nil, err
+	}
+
+	removed, err := s.UserRepo.UnpinMessage(c.Request.Context(), chatRoomID, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpin message: %w", err)
+	}
+	if !removed {
+		return nil, ErrNotPinned
+	}
+
+	return &models.PinnedMessage{ChatRoomID: chatRoomID, MessageID: messageID}, nil
+}
+
+// GetPinnedMessages lists the pinned messages of a chat room in the order they were pinned.
+func (s *UserChatRoomServiceImpl) GetPinnedMessages(c *gin.Context) ([]models.PinnedMessage, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	chatRoomID, err := paramUint(c, "chatRoomID")
+	if err != nil {
+		return nil, err
+	}
+	if !s.UserRepo.IsUserInChatRoom(userID, chatRoomID) {
+		return nil, ErrNotAuthorized
+	}
+
+	pins, err := s.UserRepo.GetPinnedMessages(c.Request.Context(), chatRoomID)
+	if err != nil {
+		return nil, err
+	}
+	if pins == nil {
+		pins = []models.PinnedMessage{}
+	}
+	for i := range pins {
+		s.signMediaContent(&pins[i].Message)
+	}
+	return pins, nil
+}
+
+// signMediaContent replaces the bucket path of a media message with a signed URL.
+func (s *UserChatRoomServiceImpl) signMediaContent(msg *models.Messages) {
+	if msg.Type != "media" {
+		return
+	}
+	signedURL, err := s.MediaStorage.GenerateSignedURL(msg.Content)
+	if err != nil {
+		log.Printf("Error generating signed URL for message %d: %v", msg.MessageID, err)
+		return
+	}
+	msg.Content = signedURL
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+)
+
+var (
+	ErrInvalidPollQuestion = errors.New("question must be between 1 and 300 characters")
+	ErrInvalidPollOptions  = errors.New("a poll needs between 2 and 10 distinct options of at most 100 characters")
+	ErrInvalidPollClose    = errors.New("closes_at must be in the future and at most one year ahead")
+	ErrInvalidVote         = errors.New("option_ids must name options of this poll, and only one unless the poll is multiple choice")
+	ErrPollNotFound        = errors.New("poll not found")
+	ErrPollClosed          = errors.New("poll is closed")
+	ErrUserMuted           = errors.New("you are muted in this chat room")
+)
+
+const (
+	maxPollQuestionLength = 300
+	maxPollOptionLength   = 100
+	minPollOptions        = 2
+	maxPollOptions        = 10
+)
+
+type CreatePollRequest struct {
+	Question       string     `json:"question"`
+	Options        []string   `json:"options"`
+	MultipleChoice bool       `json:"multiple_choice"`
+	Anonymous      bool       `json:"anonymous"`
+	ClosesAt       *time.Time `json:"closes_at"`
+}
+
+type PollVoteRequest struct {
+	OptionIDs []uint `json:"option_ids"`
+}
+
+// PreparePoll validates a new poll for the chat room and checks that the current user may post it,
+// the same way a WebSocket message is checked. It returns the poll message with the poll as its
+// Data; the caller stores the two together.
+func (s *UserChatRoomServiceImpl) PreparePoll(c *gin.Context) (*models.Messages, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	chatRoomID, err := paramUint(c, "chatRoomID")
+	if err != nil {
+		return nil, err
+	}
+
+	var input CreatePollRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		return nil, err
+	}
+	poll, err := newPoll(input, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.UserRepo.IsUserInChatRoom(userID, chatRoomID) {
+		return nil, ErrNotAuthorized
+	}
+	mutedUntil, err := s.UserRepo.GetMutedUntil(c.Request.Context(), chatRoomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mutedUntil.After(time.Now()) {
+		return nil, ErrUserMuted
+	}
+	if err := s.CheckSendRate(c.Request.Context(), userID, chatRoomID); err != nil {
+		return nil, err
+	}
+	room, err := s.UserRepo.GetChatRoom(c.Request.Context(), chatRoomID)
+	if err != nil {
+		return nil, err
+	}
+
+	poll.ChatRoomID = chatRoomID
+	poll.CreatorID = userID
+	return &models.Messages{
+		SenderID:   userID,
+		ChatRoomID: chatRoomID,
+		Content:    poll.Question,
+		IsDM:       room.IsDM,
+		Type:       models.MessageTypePoll,
+		Data:       poll,
+	}, nil
+}
+
+func newPoll(input CreatePollRequest, now time.Time) (*models.Poll, error) {
+	question := strings.TrimSpace(input.Question)
+	if question == "" || len([]rune(question)) > maxPollQuestionLength {
+		return nil, ErrInvalidPollQuestion
+	}
+	if len(input.Options) < minPollOptions || len(input.Options) > maxPollOptions {
+		return nil, ErrInvalidPollOptions
+	}
+	if input.ClosesAt != nil && (!input.ClosesAt.After(now) || input.ClosesAt.Sub(now) > maxScheduleAhead) {
+		return nil, ErrInvalidPollClose
+	}
+
+	poll := &models.Poll{
+		Question:       question,
+		MultipleChoice: input.MultipleChoice,
+		Anonymous:      input.Anonymous,
+		ClosesAt:       input.ClosesAt,
+	}
+	seen := make(map[string]bool)
+	for _, text := range input.Options {
+		text = strings.TrimSpace(text)
+		key := strings.ToLower(text)
+		if text == "" || len([]rune(text)) > maxPollOptionLength || seen[key] {
+			return nil, ErrInvalidPollOptions
+		}
+		seen[key] = true
+		poll.Options = append(poll.Options, models.PollOption{Text: text})
+	}
+	return poll, nil
+}
+
+// GetPoll returns the poll in the pollID path parameter with its tallies and the current user's votes.
+func (s *UserChatRoomServiceImpl) GetPoll(c *gin.Context) (*models.Poll, error) {
+	userID, chatRoomID, pollID, err := s.pollParams(c)
+	if err != nil {
+		return nil, err
+	}
+	return s.loadPoll(c, chatRoomID, pollID, userID)
+}
+
+// VotePoll replaces the current user's votes on a poll with the options in the request.
+func (s *UserChatRoomServiceImpl) VotePoll(c *gin.Context) (*models.Poll, error) {
+	userID, chatRoomID, pollID, err := s.pollParams(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var input PollVoteRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		return nil, err
+	}
+
+	poll, err := s.loadPoll(c, chatRoomID, pollID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if poll.Closed {
+		return nil, ErrPollClosed
+	}
+	if !validVote(poll, input.OptionIDs) {
+		return nil, ErrInvalidVote
+	}
+
+	if err := s.UserRepo.SetPollVotes(c.Request.Context(), pollID, userID, input.OptionIDs); err != nil {
+		return nil, fmt.Errorf("failed to save vote: %w", err)
+	}
+	return s.loadPoll(c, chatRoomID, pollID, userID)
+}
+
+// RetractPollVote removes the current user's votes from a poll.
+func (s *UserChatRoomServiceImpl) RetractPollVote(c *gin.Context) (*models.Poll, error) {
+	userID, chatRoomID, pollID, err := s.pollParams(c)
+	if err != nil {
+		return nil, err
+	}
+
+	poll, err := s.loadPoll(c, chatRoomID, pollID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if poll.Closed {
+		return nil, ErrPollClosed
+	}
+
+	if err := s.UserRepo.SetPollVotes(c.Request.Context(), pollID, userID, nil); err != nil {
+		return nil, fmt.Errorf("failed to retract vote: %w", err)
+	}
+	return s.loadPoll(c, chatRoomID, pollID, userID)
+}
+
+// ClosePoll closes a poll to new votes. Only its creator or a room admin can close it.
+func (s *UserChatRoomServiceImpl) ClosePoll(c *gin.Context) (*models.Poll, error) {
+	userID, chatRoomID, pollID, err := s.pollParams(c)
+	if err != nil {
+		return nil, err
+	}
+
+	poll, err := s.loadPoll(c, chatRoomID, pollID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if poll.CreatorID != userID {
+		if _, err := s.requireRoomAdmin(c, userID, chatRoomID); err != nil {
+			return nil, err
+		}
+	}
+	if poll.Closed {
+		return poll, nil
+	}
+
+	if err := s.UserRepo.ClosePoll(c.Request.Context(), pollID); err != nil {
+		return nil, fmt.Errorf("failed to close poll: %w", err)
+	}
+	return s.loadPoll(c, chatRoomID, pollID, userID)
+}
+
+func (s *UserChatRoomServiceImpl) pollParams(c *gin.Context) (userID, chatRoomID, pollID uint, err error) {
+	if userID, err = currentUserID(c); err != nil {
+		return
+	}
+	if chatRoomID, err = paramUint(c, "chatRoomID"); err != nil {
+		return
+	}
+	if pollID, err = paramUint(c, "pollID"); err != nil {
+		return
+	}
+	if !s.UserRepo.IsUserInChatRoom(userID, chatRoomID) {
+		err = ErrNotAuthorized
+	}
+	return
+}
+
+func (s *UserChatRoomServiceImpl) loadPoll(c *gin.Context, chatRoomID, pollID, userID uint) (*models.Poll, error) {
+	poll, err := s.UserRepo.GetPoll(c.Request.Context(), chatRoomID, pollID, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrPollNotFound
+		}
+		return nil, err
+	}
+	return poll, nil
+}
+
+// validVote reports whether optionIDs is a non-empty set of options of the poll that its choice mode allows.
+func validVote(poll *models.Poll, optionIDs []uint) bool {
+	if len(optionIDs) == 0 || (!poll.MultipleChoice && len(optionIDs) > 1) {
+		return false
+	}
+	options := make(map[uint]bool, len(poll.Options))
+	for _, option := range poll.Options {
+		options[option.ID] = true
+	}
+	seen := make(map[uint]bool, len(optionIDs))
+	for _, id := range optionIDs {
+		if !options[id] || seen[id] {
+			return false
+		}
+		seen[id] = true
+	}
+	return true
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/avatar"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/storage"
+)
+
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrUsernameTaken   = storage.ErrUsernameTaken
+	ErrEmailInUse      = storage.ErrEmailInUse
+	ErrInvalidUsername = errors.New("username must be 3 to 50 letters, digits, dots, dashes or underscores")
+	ErrInvalidName     = errors.New("name must be at most 100 characters")
+	ErrInvalidEmail    = errors.New("invalid email address")
+)
+
+// CreateUserRequest is the body of POST /users.
+// Profile pictures can only be set by uploading an avatar or signing in with a provider.
+type CreateUserRequest struct {
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	Email    string `json:"email"`
+}
+
+// UpdateProfileRequest changes the fields that are set and leaves the others as they are.
+type UpdateProfileRequest struct {
+	Name     *string `json:"name"`
+	Username *string `json:"username"`
+	Email    *string `json:"email"`
+}
+
+// profileOf returns the fields of user that viewerID may see. Only users themselves see their email.
+func (s *UserChatRoomServiceImpl) profileOf(user *models.Users, viewerID uint) *models.UserProfile {
+	profile := &models.UserProfile{
+		ID:             user.ID,
+		Username:       user.Username,
+		Name:           user.Name,
+		ProfilePicture: s.avatarURL(user.ID, user.ProfilePicture, profilePictureSize),
+		CreatedAt:      user.CreatedAt,
+	}
+	if uploadedAvatar(user.ID, user.ProfilePicture) {
+		profile.Avatars = make(map[int]string, len(avatar.Sizes))
+		for _, size := range avatar.Sizes {
+			profile.Avatars[size] = s.avatarURL(user.ID, user.ProfilePicture, size)
+		}
+	}
+	if viewerID == user.ID {
+		profile.Email = user.Email
+		profile.EmailVerifiedAt = user.EmailVerifiedAt
+	}
+	return profile
+}
+
+// PublicProfile returns the profile of a user as other users see it.
+func PublicProfile(profile *models.UserProfile) *models.UserProfile {
+	public := *profile
+	public.Email = ""
+	public.EmailVerifiedAt = nil
+	return &public
+}
+
+func (s *UserChatRoomServiceImpl) userByID(ctx context.Context, userID uint) (*models.Users, error) {
+	user, err := s.UserRepo.GetUserByID(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	return user, err
+}
+
+// GetOwnProfile returns the current user's profile, including their private fields.
+func (s *UserChatRoomServiceImpl) GetOwnProfile(c *gin.Context) (*models.UserProfile, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userByID(c.Request.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.profileOf(user, userID), nil
+}
+
+// GetUserProfile returns the profile of the user in the id path parameter as the current user may see it.
+func (s *UserChatRoomServiceImpl) GetUserProfile(c *gin.Context) (*models.UserProfile, error) {
+	viewerID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := paramUint(c, "id")
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	user, err := s.userByID(c.Request.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.profileOf(user, viewerID), nil
+}
+
+// UpdateProfile changes the current user's name, username or email. A new email address has to be
+// verified again. Since the email address is where password resets go, it can only be changed with
+// a session, not with an access token.
+func (s *UserChatRoomServiceImpl) UpdateProfile(c *gin.Context) (*models.UserProfile, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	var input UpdateProfileRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		return nil, err
+	}
+
+	ctx := c.Request.Context()
+	user, err := s.userByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	name, username, email := user.Name, user.Username, user.Email
+	if input.Name != nil {
+		name = strings.TrimSpace(*input.Name)
+		if utf8.RuneCountInString(name) > 100 {
+			return nil, ErrInvalidName
+		}
+	}
+	if input.Username != nil {
+		username = strings.TrimSpace(*input.Username)
+		if !validUsername(username) {
+			return nil, ErrInvalidUsername
+		}
+	}
+	if input.Email != nil && !strings.EqualFold(strings.TrimSpace(*input.Email), user.Email) {
+		if err := requireSession(c); err != nil {
+			return nil, err
+		}
+		email, err = normalizeEmail(*input.Email)
+		if err != nil {
+			return nil, err
+		}
+		// The unique index is case-sensitive, while sign-in by email is not
+		other, err := s.UserRepo.GetUserByEmail(ctx, email)
+		if err == nil && other.ID != userID {
+			return nil, ErrEmailInUse
+		}
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	updated, err := s.UserRepo.UpdateProfile(ctx, userID, name, username, email)
+	if err != nil {
+		return nil, err
+	}
+	return s.profileOf(updated, userID), nil
+}
+
+// validUsername reports whether username is 3 to 50 letters, digits, dots, dashes or underscores,
+// and not the reserved placeholder of unfinished registrations.
+func validUsername(username string) bool {
+	if username == UnregisteredUsername || utf8.RuneCountInString(username) < 3 || utf8.RuneCountInString(username) > 50 {
+		return false
+	}
+	for _, r := range username {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.' && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeEmail returns the bare address of email, or ErrInvalidEmail.
+func normalizeEmail(email string) (string, error) {
+	address, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil || address.Name != "" || len(address.Address) > 100 {
+		return "", ErrInvalidEmail
+	}
+	return address.Address, nil
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+)
+
+var ErrInvalidSlowMode = errors.New("seconds must be between 0 and 21600")
+
+// maxSlowModeSeconds caps slow mode at six hours.
+const maxSlowModeSeconds = 6 * 60 * 60
+
+// RateLimitError is returned when a user sends faster than the room or the global limit allows.
+type RateLimitError struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s, retry in %d seconds", e.Reason, e.RetryAfterSeconds())
+}
+
+// RetryAfterSeconds rounds RetryAfter up to whole seconds, as used by the Retry-After header.
+func (e *RateLimitError) RetryAfterSeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+type SlowModeRequest struct {
+	Seconds int `json:"seconds"`
+}
+
+// CheckSendRate enforces the room's slow mode and the global per-user send limit on a new message.
+// Room admins are exempt from both.
+func (s *UserChatRoomServiceImpl) CheckSendRate(ctx context.Context, userID, chatRoomID uint) error {
+	if s.isRoomAdmin(ctx, userID, chatRoomID) {
+		return nil
+	}
+
+	slowMode, sinceLast, err := s.UserRepo.GetSlowModeState(ctx, chatRoomID, userID)
+	if err != nil {
+		return err
+	}
+	if slowMode > 0 && sinceLast >= 0 && sinceLast < slowMode {
+		return &RateLimitError{Reason: "slow mode is enabled in this chat room", RetryAfter: slowMode - sinceLast}
+	}
+
+	if s.SendLimiter != nil {
+		if ok, wait := s.SendLimiter.Allow(userID); !ok {
+			return &RateLimitError{Reason: "you are sending messages too fast", RetryAfter: wait}
+		}
+	}
+	return nil
+}
+
+func (s *UserChatRoomServiceImpl) isRoomAdmin(ctx context.Context, userID, chatRoomID uint) bool {
+	member, err := s.UserRepo.GetChatRoomMember(ctx, chatRoomID, userID)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("Error fetching member %d of chat room %d: %v", userID, chatRoomID, err)
+		}
+		return false
+	}
+	return member.Role == models.RoleAdmin
+}
+
+// SetSlowMode sets the minimum number of seconds between two messages of the same member. Zero turns it off.
+func (s *UserChatRoomServiceImpl) SetSlowMode(c *gin.Context) (*models.ChatRooms, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	chatRoomID, err := paramUint(c, "chatRoomID")
+	if err != nil {
+		return nil, err
+	}
+
+	var input SlowModeRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		return nil, err
+	}
+	if input.Seconds < 0 || input.Seconds > maxSlowModeSeconds {
+		return nil, ErrInvalidSlowMode
+	}
+
+	if _, err := s.requireRoomAdmin(c, userID, chatRoomID); err != nil {
+		return nil, err
+	}
+	if err := s.UserRepo.SetSlowMode(c.Request.Context(), chatRoomID, input.Seconds); err != nil {
+		return nil, fmt.Errorf("failed to set slow mode: %w", err)
+	}
+
+	room, err := s.UserRepo.GetChatRoom(c.Request.Context(), chatRoomID)
+	if err != nil {
+		return nil, err
+	}
+	s.signRoomImages(room)
+	return room, nil
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+)
+
+var ErrInvalidRetention = errors.New("days must be between 0 and 3650")
+
+// maxRetentionDays caps the retention period at ten years.
+const maxRetentionDays = 3650
+
+// RetentionRequest sets the retention period of a room. A null value falls back to the global default
+// and 0 keeps messages forever.
+type RetentionRequest struct {
+	Days *int `json:"days"`
+}
+
+// SetRetention sets how long messages are kept in a chat room. Only room admins can change it.
+func (s *UserChatRoomServiceImpl) SetRetention(c *gin.Context) (*models.ChatRooms, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	chatRoomID, err := paramUint(c, "chatRoomID")
+	if err != nil {
+		return nil, err
+	}
+
+	var input RetentionRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		return nil, err
+	}
+	if input.Days != nil && (*input.Days < 0 || *input.Days > maxRetentionDays) {
+		return nil, ErrInvalidRetention
+	}
+
+	if _, err := s.requireRoomAdmin(c, userID, chatRoomID); err != nil {
+		return nil, err
+	}
+	if err := s.UserRepo.SetRetentionDays(c.Request.Context(), chatRoomID, input.Days); err != nil {
+		return nil, fmt.Errorf("failed to set retention: %w", err)
+	}
+
+	room, err := s.UserRepo.GetChatRoom(c.Request.Context(), chatRoomID)
+	if err != nil {
+		return nil, err
+	}
+	s.signRoomImages(room)
+	return room, nil
+}
+
+// PurgeExpiredMessages deletes messages and their read receipts that are older than the retention period
+// of each room, and the media of the deleted messages. Rooms without their own setting use defaultDays;
+// 0 keeps everything.
+func (s *UserChatRoomServiceImpl) PurgeExpiredMessages(ctx context.Context, defaultDays int) ([]models.RetentionReport, error) {
+	policies, err := s.UserRepo.GetRetentionPolicies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retention policies: %w", err)
+	}
+
+	var reports []models.RetentionReport
+	for chatRoomID, roomDays := range policies {
+		days := defaultDays
+		if roomDays != nil {
+			days = *roomDays
+		}
+		if days <= 0 {
+			continue
+		}
+
+		report := models.RetentionReport{
+			ChatRoomID:    chatRoomID,
+			RetentionDays: days,
+			Cutoff:        time.Now().AddDate(0, 0, -days),
+		}
+
+		purged, err := s.UserRepo.PurgeMessagesOlderThan(ctx, chatRoomID, days)
+		if err != nil {
+			log.Printf("Error purging messages of chat room %d: %v", chatRoomID, err)
+			continue
+		}
+		report.Messages, report.ReadReceipts = purged.Messages, purged.ReadReceipts
+
+		for _, path := range purged.Media {
+			if err := s.MediaStorage.DeleteFile(ctx, path); err != nil {
+				log.Printf("Error deleting media %s of chat room %d: %v", path, chatRoomID, err)
+				continue
+			}
+			report.MediaObjects++
+		}
+
+		if report.Messages > 0 || report.ReadReceipts > 0 || report.MediaObjects > 0 {
+			log.Printf("Retention purged %d messages, %d read receipts and %d media objects from chat room %d (older than %d days)",
+				report.Messages, report.ReadReceipts, report.MediaObjects, chatRoomID, days)
+			reports = append(reports, report)
+		}
+	}
+
+	return reports, nil
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+)
+
+var (
+	ErrChatRoomNotFound  = errors.New("chat room not found")
+	ErrInvalidRoomImage  = errors.New("room images must be .jpg, .jpeg, .png or .gif files")
+	ErrRoomImageTooLarge = errors.New("room images must be smaller than 5 MB")
+)
+
+// maxRoomImageSize is the largest avatar or banner accepted, in bytes.
+const maxRoomImageSize = 5 << 20
+
+var roomImageExtensions = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".gif":  true,
+}
+
+// GetChatRoom returns the details of a chat room, including the current user's settings. Only members can see it.
+func (s *UserChatRoomServiceImpl) GetChatRoom(c *gin.Context) (*models.ChatRooms, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	chatRoomID, err := paramUint(c, "chatRoomID")
+	if err != nil {
+		return nil, err
+	}
+	if !s.UserRepo.IsUserInChatRoom(userID, chatRoomID) {
+		return nil, ErrNotAuthorized
+	}
+
+	room, err := s.UserRepo.GetChatRoom(c.Request.Context(), chatRoomID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrChatRoomNotFound
+		}
+		return nil, err
+	}
+	settings, err := s.UserRepo.GetChatRoomSettings(c.Request.Context(), chatRoomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	room.Settings = *settings
+
+	s.signRoomImages(room)
+	return room, nil
+}
+
+// UploadChatRoomImage replaces the avatar or banner of a chat room. Only room admins can change them.
+// The image is stored under chatrooms/<id>/meta/, and the image it replaces is deleted from the bucket.
+func (s *UserChatRoomServiceImpl) UploadChatRoomImage(c *gin.Context, kind string) (*models.ChatRooms, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	chatRoomID, err := paramUint(c, "chatRoomID")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.requireRoomAdmin(c, userID, chatRoomID); err != nil {
+		return nil, err
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file: %v", err)
+	}
+	defer file.Close()
+
+	ext := strings.ToLower(filepath.Ext(header.Filename))
+	if !roomImageExtensions[ext] {
+		return nil, ErrInvalidRoomImage
+	}
+	if header.Size > maxRoomImageSize {
+		return nil, ErrRoomImageTooLarge
+	}
+
+	filePath := fmt.Sprintf("chatrooms/%d/meta/%s-%d%s", chatRoomID, kind, time.Now().Unix(), ext)
+	filePath, err = s.MediaStorage.UploadFileToBucket(file, header.Filename, filePath, c.Request.Context())
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload file: %v", err)
+	}
+
+	previous, err := s.UserRepo.SetChatRoomImage(c.Request.Context(), chatRoomID, kind, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save room %s: %w", kind, err)
+	}
+	if previous != "" && previous != filePath {
+		if err := s.MediaStorage.DeleteFile(c.Request.Context(), previous); err != nil {
+			log.Printf("Error deleting previous %s %s of chat room %d: %v", kind, previous, chatRoomID, err)
+		}
+	}
+
+	room, err := s.UserRepo.GetChatRoom(c.Request.Context(), chatRoomID)
+	if err != nil {
+		return nil, err
+	}
+	s.signRoomImages(room)
+	return room, nil
+}
+
+// signRoomImages fills in signed URLs for the avatar and banner of the room, the same way media messages are signed.
+func (s *UserChatRoomServiceImpl) signRoomImages(room *models.ChatRooms) {
+	if room.AvatarPath != "" {
+		signedURL, err := s.MediaStorage.GenerateSignedURL(room.AvatarPath)
+		if err != nil {
+			log.Printf("Error generating signed URL for avatar of chat room %d: %v", room.ID, err)
+		} else {
+			room.AvatarURL = signedURL
+		}
+	}
+	if room.BannerPath != "" {
+		signedURL, err := s.MediaStorage.GenerateSignedURL(room.BannerPath)
+		if err != nil {
+			log.Printf("Error generating signed URL for banner of chat room %d: %v", room.ID, err)
+		} else {
+			room.BannerURL = signedURL
+		}
+	}
+}
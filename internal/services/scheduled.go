@@ -0,0 +1,141 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+)
+
+var (
+	ErrEmptyMessage         = errors.New("content is required")
+	ErrScheduleInPast       = errors.New("send_at must be in the future")
+	ErrScheduleTooFar       = errors.New("messages can be scheduled at most one year ahead")
+	ErrInvalidScheduledType = errors.New("only text and media messages can be scheduled")
+	ErrScheduledNotFound    = errors.New("scheduled message not found")
+)
+
+// maxScheduleAhead is how far in the future a message can be scheduled.
+const maxScheduleAhead = 365 * 24 * time.Hour
+
+type ScheduleMessageRequest struct {
+	Content string    `json:"content"`
+	Type    string    `json:"type"`
+	SendAt  time.Time `json:"send_at" binding:"required"`
+}
+
+// ScheduleMessage stores a message that the dispatcher delivers to the chat room at send_at.
+// Media messages take the file path returned by the upload endpoint as their content.
+func (s *UserChatRoomServiceImpl) ScheduleMessage(c *gin.Context) (*models.ScheduledMessage, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	chatRoomID, err := paramUint(c, "chatRoomID")
+	if err != nil {
+		return nil, err
+	}
+
+	var input ScheduleMessageRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		return nil, err
+	}
+	input.Content = strings.TrimSpace(input.Content)
+	if input.Content == "" {
+		return nil, ErrEmptyMessage
+	}
+	switch input.Type {
+	case "":
+	case "media":
+		if !strings.HasPrefix(input.Content, fmt.Sprintf("chatrooms/%d/", chatRoomID)) {
+			return nil, ErrInvalidScheduledType
+		}
+	default:
+		return nil, ErrInvalidScheduledType
+	}
+	now := time.Now()
+	if !input.SendAt.After(now) {
+		return nil, ErrScheduleInPast
+	}
+	if input.SendAt.Sub(now) > maxScheduleAhead {
+		return nil, ErrScheduleTooFar
+	}
+
+	if !s.UserRepo.IsUserInChatRoom(userID, chatRoomID) {
+		return nil, ErrNotAuthorized
+	}
+	room, err := s.UserRepo.GetChatRoom(c.Request.Context(), chatRoomID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrChatRoomNotFound
+		}
+		return nil, err
+	}
+
+	scheduled := &models.ScheduledMessage{
+		ChatRoomID: chatRoomID,
+		SenderID:   userID,
+		Content:    input.Content,
+		Type:       input.Type,
+		IsDM:       room.IsDM,
+		SendAt:     input.SendAt,
+	}
+	if err := s.UserRepo.CreateScheduledMessage(c.Request.Context(), scheduled); err != nil {
+		return nil, fmt.Errorf("failed to schedule message: %w", err)
+	}
+	return scheduled, nil
+}
+
+// GetScheduledMessages lists the current user's pending messages, limited to one chat room
+// when the chatRoomID path parameter is present.
+func (s *UserChatRoomServiceImpl) GetScheduledMessages(c *gin.Context) ([]models.ScheduledMessage, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var chatRoomID uint
+	if c.Param("chatRoomID") != "" {
+		chatRoomID, err = paramUint(c, "chatRoomID")
+		if err != nil {
+			return nil, err
+		}
+		if !s.UserRepo.IsUserInChatRoom(userID, chatRoomID) {
+			return nil, ErrNotAuthorized
+		}
+	}
+
+	scheduled, err := s.UserRepo.GetScheduledMessages(c.Request.Context(), userID, chatRoomID)
+	if err != nil {
+		return nil, err
+	}
+	if scheduled == nil {
+		scheduled = []models.ScheduledMessage{}
+	}
+	return scheduled, nil
+}
+
+// CancelScheduledMessage cancels one of the current user's pending messages.
+func (s *UserChatRoomServiceImpl) CancelScheduledMessage(c *gin.Context) (*models.ScheduledMessage, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	scheduledID, err := paramUint(c, "scheduledID")
+	if err != nil {
+		return nil, err
+	}
+
+	scheduled, err := s.UserRepo.CancelScheduledMessage(c.Request.Context(), scheduledID, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrScheduledNotFound
+		}
+		return nil, err
+	}
+	return scheduled, nil
+}
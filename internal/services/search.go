@@ -0,0 +1,174 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+)
+
+var (
+	ErrInvalidSearchQuery  = errors.New("q must be between 1 and 200 characters")
+	ErrInvalidSearchFilter = errors.New("invalid search filter")
+	ErrInvalidCursor       = errors.New("invalid cursor")
+)
+
+const (
+	maxSearchQueryLength = 200
+	defaultSearchLimit   = 20
+	maxSearchLimit       = 100
+)
+
+type MessageSearchResponse struct {
+	Results []models.MessageSearchResult `json:"results"`
+	// NextCursor is passed as cursor to fetch the next page; it is empty on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// SearchMessages runs a full-text search over the messages of the rooms the current user belongs to.
+// Results are newest first and paginated with an opaque cursor.
+func (s *UserChatRoomServiceImpl) SearchMessages(c *gin.Context) (*MessageSearchResponse, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := parseMessageSearchFilter(c)
+	if err != nil {
+		return nil, err
+	}
+	if filter.ChatRoomID != 0 && !s.UserRepo.IsUserInChatRoom(userID, filter.ChatRoomID) {
+		return nil, ErrNotAuthorized
+	}
+
+	// Fetch one extra result to know whether there is another page
+	limit := filter.Limit
+	filter.Limit++
+	results, err := s.UserRepo.SearchMessages(c.Request.Context(), userID, *filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+
+	response := &MessageSearchResponse{Results: results}
+	if len(results) > limit {
+		response.Results = results[:limit]
+		last := response.Results[limit-1]
+		response.NextCursor = encodeSearchCursor(models.MessageSearchCursor{
+			Timestamp:  last.Timestamp,
+			ChatRoomID: last.ChatRoomID,
+			MessageID:  last.MessageID,
+		})
+	}
+	if response.Results == nil {
+		response.Results = []models.MessageSearchResult{}
+	}
+	return response, nil
+}
+
+func parseMessageSearchFilter(c *gin.Context) (*models.MessageSearchFilter, error) {
+	filter := &models.MessageSearchFilter{
+		Query: strings.TrimSpace(c.Query("q")),
+		Type:  c.Query("type"),
+		Limit: defaultSearchLimit,
+	}
+	if filter.Query == "" || len([]rune(filter.Query)) > maxSearchQueryLength {
+		return nil, ErrInvalidSearchQuery
+	}
+
+	var err error
+	if filter.ChatRoomID, err = queryUint(c, "chat_room_id"); err != nil {
+		return nil, err
+	}
+	if filter.SenderID, err = queryUint(c, "sender_id"); err != nil {
+		return nil, err
+	}
+	if filter.From, err = queryTime(c, "from"); err != nil {
+		return nil, err
+	}
+	if filter.To, err = queryTime(c, "to"); err != nil {
+		return nil, err
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("%w: limit", ErrInvalidSearchFilter)
+		}
+		filter.Limit = min(limit, maxSearchLimit)
+	}
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := decodeSearchCursor(value)
+		if err != nil {
+			return nil, err
+		}
+		filter.After = cursor
+	}
+	return filter, nil
+}
+
+// queryUint parses an optional numeric query parameter, returning 0 when it is missing.
+func queryUint(c *gin.Context, name string) (uint, error) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidSearchFilter, name)
+	}
+	return uint(id), nil
+}
+
+// queryTime parses an optional RFC 3339 timestamp or YYYY-MM-DD date query parameter.
+// Message timestamps are stored without a time zone, so the value is compared in UTC.
+func queryTime(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if t, err = time.Parse(time.DateOnly, value); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSearchFilter, name)
+		}
+	}
+	t = t.UTC()
+	return &t, nil
+}
+
+func encodeSearchCursor(cursor models.MessageSearchCursor) string {
+	raw := fmt.Sprintf("%d.%d.%d", cursor.Timestamp.UnixNano(), cursor.ChatRoomID, cursor.MessageID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeSearchCursor(value string) (*models.MessageSearchCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parts := strings.Split(string(raw), ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	chatRoomID, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	messageID, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &models.MessageSearchCursor{
+		Timestamp:  time.Unix(0, nanos).UTC(),
+		ChatRoomID: uint(chatRoomID),
+		MessageID:  uint(messageID),
+	}, nil
+}
@@ -2,37 +2,112 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log"
-	"net/http"
 	"path/filepath"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/ratelimit"
 	"github.com/kontentski/chat/internal/storage"
 )
 
 type ChatRoomService interface {
-	FetchUserChatRooms(req *http.Request) ([]models.ChatRooms, error)
+	FetchUserChatRooms(c *gin.Context) ([]models.ChatRooms, error)
 	FetchUserChatRoomsByUserID(userID uint) ([]models.ChatRooms, error)
 	GetMessages(c *gin.Context) ([]models.Messages, error)
 	DeleteMessage(c *gin.Context) (*DeleteMessageResponse, error)
-	LeaveChatRoom(c *gin.Context) error
+	LeaveChatRoom(c *gin.Context) (*models.ChatRoomMemberProfile, error)
 	SearchUsers(c *gin.Context) (*[]UsersListResponse, error)
-	AddUserToChatRoom(c *gin.Context) error
+	AddUserToChatRoom(c *gin.Context) (*models.ChatRoomMemberProfile, error)
 	UploadMedia(c *gin.Context) (string, error)
 	GenerateSignedURL(filePath string) (string, error)
 	CreateUser(user *models.Users) error
+	GetOwnProfile(c *gin.Context) (*models.UserProfile, error)
+	GetUserProfile(c *gin.Context) (*models.UserProfile, error)
+	UpdateProfile(c *gin.Context) (*models.UserProfile, error)
+	UploadAvatar(c *gin.Context) (*models.UserProfile, error)
+	GetStatus(c *gin.Context) (*StatusResponse, error)
+	SetStatus(c *gin.Context) (*models.UserStatus, error)
+	SetDoNotDisturb(c *gin.Context) (*models.UserStatus, error)
+	MessageNotifications(ctx context.Context, msg *models.Messages) ([]models.Notification, error)
+	GetChatRoomMembers(c *gin.Context) (*ChatRoomMembersResponse, error)
+	ModerateMember(c *gin.Context, action string) (*ModerationResponse, error)
+	GetModerationLog(c *gin.Context) ([]models.ModerationAction, error)
+	CheckSendRate(ctx context.Context, userID, chatRoomID uint) error
+	SetSlowMode(c *gin.Context) (*models.ChatRooms, error)
+	PinMessage(c *gin.Context) (*models.PinnedMessage, error)
+	UnpinMessage(c *gin.Context) (*models.PinnedMessage, error)
+	GetPinnedMessages(c *gin.Context) ([]models.PinnedMessage, error)
+	UpdateChatRoomSettings(c *gin.Context) (*models.ChatRoomMemberSettings, error)
+	FetchGroupedChatRooms(c *gin.Context) ([]ChatRoomGroup, error)
+	GetRoomCategories(c *gin.Context) ([]models.RoomCategory, error)
+	CreateRoomCategory(c *gin.Context) (*models.RoomCategory, error)
+	RenameRoomCategory(c *gin.Context) error
+	DeleteRoomCategory(c *gin.Context) error
+	ReorderRoomCategories(c *gin.Context) error
+	MoveChatRoomsToCategory(c *gin.Context) error
+	GetChatRoom(c *gin.Context) (*models.ChatRooms, error)
+	UploadChatRoomImage(c *gin.Context, kind string) (*models.ChatRooms, error)
+	SetRetention(c *gin.Context) (*models.ChatRooms, error)
+	PurgeExpiredMessages(ctx context.Context, defaultDays int) ([]models.RetentionReport, error)
+	ScheduleMessage(c *gin.Context) (*models.ScheduledMessage, error)
+	GetScheduledMessages(c *gin.Context) ([]models.ScheduledMessage, error)
+	CancelScheduledMessage(c *gin.Context) (*models.ScheduledMessage, error)
+	SetMessageTTL(c *gin.Context) (*models.ChatRooms, error)
+	ExpireMessages(ctx context.Context) ([]models.Messages, error)
+	PreparePoll(c *gin.Context) (*models.Messages, error)
+	GetPoll(c *gin.Context) (*models.Poll, error)
+	VotePoll(c *gin.Context) (*models.Poll, error)
+	RetractPollVote(c *gin.Context) (*models.Poll, error)
+	ClosePoll(c *gin.Context) (*models.Poll, error)
+	GetQuote(ctx context.Context, chatRoomID, messageID uint) (*models.MessageReference, error)
+	PrepareForward(c *gin.Context) (*models.Messages, error)
+	SearchMessages(c *gin.Context) (*MessageSearchResponse, error)
+	SaveBookmark(c *gin.Context) (*models.Bookmark, error)
+	GetBookmarks(c *gin.Context) ([]models.Bookmark, error)
+	DeleteBookmark(c *gin.Context) error
+	GetDraft(c *gin.Context) (*models.Draft, error)
+	SaveDraft(c *gin.Context) (*models.Draft, error)
+	Login(c *gin.Context) (*models.Users, error)
+	ChangePassword(c *gin.Context) (*PasswordReset, error)
+	OAuthLogin(ctx context.Context, identity *models.UserIdentity, profile *models.Users) (*models.Users, error)
+	LinkIdentity(ctx context.Context, identity *models.UserIdentity) error
+	GetIdentities(c *gin.Context) ([]models.UserIdentity, error)
+	UnlinkIdentity(c *gin.Context) error
+	GetSessions(c *gin.Context) ([]models.Session, error)
+	RevokeSession(c *gin.Context) (uint, error)
+	RevokeSessions(c *gin.Context) ([]uint, error)
+	CreateAccessToken(c *gin.Context) (*models.AccessToken, error)
+	GetAccessTokens(c *gin.Context) ([]models.AccessToken, error)
+	RevokeAccessToken(c *gin.Context) (uint, error)
+	AuthenticateToken(ctx context.Context, secret string) (*models.AccessToken, error)
+	LoginTwoFactorState(ctx context.Context, userID uint) (string, error)
+	VerifyTwoFactor(ctx context.Context, userID uint, code string) error
+	GetTwoFactorStatus(c *gin.Context) (*models.TwoFactorStatus, error)
+	EnrollTOTP(c *gin.Context, account string) (*models.TOTPEnrollment, error)
+	ConfirmTOTP(c *gin.Context) ([]string, error)
+	DisableTOTP(c *gin.Context) error
+	RegenerateRecoveryCodes(c *gin.Context) ([]string, error)
+	ForgotPassword(c *gin.Context) error
+	ResetPassword(c *gin.Context) (*PasswordReset, error)
+	SendVerificationEmail(c *gin.Context) error
+	VerifyEmail(c *gin.Context) error
 }
 
 type UserChatRoomServiceImpl struct {
     UserRepo     storage.UserRepository
     AuthRepo     storage.AuthRepository
     MediaStorage storage.BucketStorage
+    SendLimiter  *ratelimit.Limiter
+    LoginGuard   LoginGuard
+    TwoFactor    TwoFactorConfig
+    AccountMail  AccountMailConfig
 }
 
 type DeleteMessageResponse struct {
@@ -41,37 +116,53 @@ type DeleteMessageResponse struct {
 	SenderID   uint
 }
 type UsersListResponse struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
-	Name     string `json:"name"`
+	UserID   string             `json:"user_id"`
+	Username string             `json:"username"`
+	Name     string             `json:"name"`
+	Status   *models.UserStatus `json:"status,omitempty"`
 }
 
 const UserIDKey = "userID"
 
+// SessionIDKey holds the ID of the session a request was authenticated with.
+const SessionIDKey = storage.SessionIDKey
 
-func NewUserChatRoomService(userRepo storage.UserRepository, authRepo storage.AuthRepository, mediaStorage storage.BucketStorage) ChatRoomService {
+
+func NewUserChatRoomService(userRepo storage.UserRepository, authRepo storage.AuthRepository, mediaStorage storage.BucketStorage, sendLimiter *ratelimit.Limiter, loginGuard LoginGuard, twoFactor TwoFactorConfig, accountMail AccountMailConfig) ChatRoomService {
 	return &UserChatRoomServiceImpl{
 		UserRepo:     userRepo,
 		AuthRepo:     authRepo,
 		MediaStorage: mediaStorage,
+		SendLimiter:  sendLimiter,
+		LoginGuard:   loginGuard,
+		TwoFactor:    twoFactor,
+		AccountMail:  accountMail,
 	}
 }
 
-// FetchUserChatRooms retrieves the user's chat rooms by processing the session.
-func (s *UserChatRoomServiceImpl) FetchUserChatRooms(req *http.Request) ([]models.ChatRooms, error) {
-	// Retrieve session values via the AuthInterface
-	sessionValues, err := s.AuthRepo.GetSession(req)
+// FetchUserChatRooms retrieves the chat rooms of the signed-in user.
+func (s *UserChatRoomServiceImpl) FetchUserChatRooms(c *gin.Context) ([]models.ChatRooms, error) {
+	userID, err := currentUserID(c)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get session: %w", err)
+		return nil, err
 	}
 
-	// Extract user ID from session values
-	userID, ok := sessionValues["userID"].(uint)
-	if !ok {
-		return nil, fmt.Errorf("unauthorized: userID not found in session")
+	chatRooms, err := s.UserRepo.FetchUserChatRooms(userID)
+	if err != nil {
+		return nil, err
 	}
 
-	return s.UserRepo.FetchUserChatRooms(userID)
+	// Archived rooms are only listed on request
+	includeArchived := c.Query("include_archived") == "true"
+	visible := make([]models.ChatRooms, 0, len(chatRooms))
+	for _, room := range chatRooms {
+		if room.Settings.Archived && !includeArchived {
+			continue
+		}
+		s.signRoomImages(&room)
+		visible = append(visible, room)
+	}
+	return visible, nil
 }
 
 func (s *UserChatRoomServiceImpl) FetchUserChatRoomsByUserID(userID uint) ([]models.ChatRooms, error) {
@@ -109,6 +200,13 @@ func (s *UserChatRoomServiceImpl) GetMessages(c *gin.Context) ([]models.Messages
 				continue
 			}
 			messages[i].Content = signedURL
+		} else if msg.Type == models.MessageTypePoll {
+			poll, err := s.UserRepo.GetPollByMessage(c.Request.Context(), msg.ChatRoomID, msg.MessageID, IntuserID)
+			if err != nil {
+				log.Printf("Error fetching poll of message %d: %v", msg.MessageID, err)
+				continue
+			}
+			messages[i].Data = poll
 		}
 	}
 
@@ -153,27 +251,35 @@ func (s *UserChatRoomServiceImpl) DeleteMessage(c *gin.Context) (*DeleteMessageR
 	}, nil
 }
 
-func (s *UserChatRoomServiceImpl) LeaveChatRoom(c *gin.Context) error {
+// LeaveChatRoom removes the current user from the chat room and returns the membership that was removed.
+func (s *UserChatRoomServiceImpl) LeaveChatRoom(c *gin.Context) (*models.ChatRoomMemberProfile, error) {
 	chatRoomIDStr := c.Param("chatRoomID")
 	userID, ok := c.Get(UserIDKey)
 	if !ok {
-		return fmt.Errorf("no userID")
+		return nil, fmt.Errorf("no userID")
 	}
-	fmt.Printf("chatroooooooooooon %s\n\n\n", chatRoomIDStr)
 	IntuserID := userID.(uint)
 	chatRoomID, err := strconv.Atoi(chatRoomIDStr)
 	if err != nil {
-		return fmt.Errorf("invalid chatRoomID")
+		return nil, fmt.Errorf("invalid chatRoomID")
 	}
 	if !s.UserRepo.IsUserInChatRoom(IntuserID, uint(chatRoomID)) {
-		return errors.New("user is not part of the chat room")
+		return nil, errors.New("user is not part of the chat room")
+	}
+
+	member, err := s.UserRepo.GetChatRoomMember(c.Request.Context(), uint(chatRoomID), IntuserID)
+	if err != nil {
+		log.Printf("Error fetching member %d of chat room %d: %v", IntuserID, chatRoomID, err)
+		member = &models.ChatRoomMemberProfile{ChatRoomID: uint(chatRoomID), UserID: IntuserID}
+	} else {
+		s.signMemberAvatar(member)
 	}
 
 	err = s.UserRepo.DeleteUserFromChatRoom(c, IntuserID, uint(chatRoomID))
 	if err != nil {
-		return errors.New("failed to leave the chat room")
+		return nil, errors.New("failed to leave the chat room")
 	}
-	return nil
+	return member, nil
 }
 
 func (s *UserChatRoomServiceImpl) SearchUsers(c *gin.Context) (*[]UsersListResponse, error) {
@@ -187,21 +293,31 @@ func (s *UserChatRoomServiceImpl) SearchUsers(c *gin.Context) (*[]UsersListRespo
 	if err != nil {
 		return nil, fmt.Errorf("failed to search users: %w", err)
 	}
+	userIDs := make([]uint, len(users))
+	for i, user := range users {
+		userIDs[i] = user.ID
+	}
+	statuses, err := s.userStatuses(c.Request.Context(), userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
 	var usersListResponse []UsersListResponse
 	for _, user := range users {
 		usersListResponse = append(usersListResponse, UsersListResponse{
 			UserID:   fmt.Sprint(user.ID),
 			Username: user.Username,
 			Name:     user.Name,
+			Status:   statuses[user.ID],
 		})
 	}
 	return &usersListResponse, nil
 }
 
-func (s *UserChatRoomServiceImpl) AddUserToChatRoom(c *gin.Context) error {
+// AddUserToChatRoom adds a user to the chat room and returns the new member's profile.
+func (s *UserChatRoomServiceImpl) AddUserToChatRoom(c *gin.Context) (*models.ChatRoomMemberProfile, error) {
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	fmt.Printf("Raw request body: %s\n", string(body))
 	c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
@@ -214,15 +330,34 @@ func (s *UserChatRoomServiceImpl) AddUserToChatRoom(c *gin.Context) error {
 
 	if err := c.ShouldBindJSON(&input); err != nil {
 		fmt.Printf("input walues: %v\n\n\n", input)
-		return err
+		return nil, err
+	}
+
+	addedUserID, err := strconv.ParseUint(input.UserID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user_id")
+	}
+
+	banned, err := s.UserRepo.IsUserBanned(c.Request.Context(), input.ChatRoomID, uint(addedUserID))
+	if err != nil {
+		return nil, err
+	}
+	if banned {
+		return nil, ErrUserBanned
 	}
 
 	err = s.UserRepo.AddUserToTheChatRoom(c, input.UserID, input.ChatRoomID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	member, err := s.UserRepo.GetChatRoomMember(c.Request.Context(), input.ChatRoomID, uint(addedUserID))
+	if err != nil {
+		log.Printf("Error fetching new member %d of chat room %d: %v", addedUserID, input.ChatRoomID, err)
+		return &models.ChatRoomMemberProfile{ChatRoomID: input.ChatRoomID, UserID: uint(addedUserID)}, nil
+	}
+	s.signMemberAvatar(member)
+	return member, nil
 }
 
 func (s *UserChatRoomServiceImpl) UploadMedia(c *gin.Context) (string, error) {
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// currentSessionID returns the ID of the session the request was authenticated with, or 0.
+func currentSessionID(c *gin.Context) uint {
+	sessionID, _ := c.Get(SessionIDKey)
+	id, _ := sessionID.(uint)
+	return id
+}
+
+// GetSessions lists the current user's active sessions and marks the one the request was made with.
+func (s *UserChatRoomServiceImpl) GetSessions(c *gin.Context) ([]models.Session, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	userSessions, err := s.UserRepo.GetSessions(c.Request.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+	current := currentSessionID(c)
+	for i := range userSessions {
+		userSessions[i].Current = userSessions[i].ID == current
+	}
+	return userSessions, nil
+}
+
+// RevokeSession ends one of the current user's sessions and returns its ID.
+func (s *UserChatRoomServiceImpl) RevokeSession(c *gin.Context) (uint, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return 0, err
+	}
+	if err := requireSession(c); err != nil {
+		return 0, err
+	}
+	sessionID, err := paramUint(c, "sessionID")
+	if err != nil {
+		return 0, err
+	}
+
+	revoked, err := s.UserRepo.RevokeSession(c.Request.Context(), userID, sessionID)
+	if err != nil {
+		return 0, err
+	}
+	if !revoked {
+		return 0, ErrSessionNotFound
+	}
+	return sessionID, nil
+}
+
+// RevokeSessions ends every session of the current user except the one the request was made with,
+// or all of them when include_current=true. It returns the IDs of the revoked sessions.
+func (s *UserChatRoomServiceImpl) RevokeSessions(c *gin.Context) ([]uint, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireSession(c); err != nil {
+		return nil, err
+	}
+
+	keep := currentSessionID(c)
+	if c.Query("include_current") == "true" {
+		keep = 0
+	}
+	return s.UserRepo.RevokeSessions(c.Request.Context(), userID, keep)
+}
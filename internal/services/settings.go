@@ -0,0 +1,81 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+)
+
+var (
+	ErrInvalidNotificationLevel = errors.New("notification_level must be one of all, mentions or none")
+	ErrInvalidMutedUntil        = errors.New("muted_until must be an RFC 3339 timestamp or an empty string")
+)
+
+// ChatRoomSettingsRequest is a partial update, fields that are left out keep their value.
+// An empty muted_until unmutes the room.
+type ChatRoomSettingsRequest struct {
+	MutedUntil        *string `json:"muted_until"`
+	Favorite          *bool   `json:"favorite"`
+	Archived          *bool   `json:"archived"`
+	NotificationLevel *string `json:"notification_level"`
+}
+
+// UpdateChatRoomSettings changes the current user's personal settings for a chat room.
+func (s *UserChatRoomServiceImpl) UpdateChatRoomSettings(c *gin.Context) (*models.ChatRoomMemberSettings, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	chatRoomID, err := paramUint(c, "chatRoomID")
+	if err != nil {
+		return nil, err
+	}
+
+	var input ChatRoomSettingsRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		return nil, err
+	}
+
+	if !s.UserRepo.IsUserInChatRoom(userID, chatRoomID) {
+		return nil, ErrNotAuthorized
+	}
+
+	settings, err := s.UserRepo.GetChatRoomSettings(c.Request.Context(), chatRoomID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.MutedUntil != nil {
+		if *input.MutedUntil == "" {
+			settings.MutedUntil = nil
+		} else {
+			mutedUntil, err := time.Parse(time.RFC3339, *input.MutedUntil)
+			if err != nil {
+				return nil, ErrInvalidMutedUntil
+			}
+			settings.MutedUntil = &mutedUntil
+		}
+	}
+	if input.Favorite != nil {
+		settings.Favorite = *input.Favorite
+	}
+	if input.Archived != nil {
+		settings.Archived = *input.Archived
+	}
+	if input.NotificationLevel != nil {
+		switch *input.NotificationLevel {
+		case models.NotificationAll, models.NotificationMentions, models.NotificationNone:
+			settings.NotificationLevel = *input.NotificationLevel
+		default:
+			return nil, ErrInvalidNotificationLevel
+		}
+	}
+
+	if err := s.UserRepo.UpsertChatRoomSettings(c.Request.Context(), chatRoomID, userID, settings); err != nil {
+		return nil, fmt.Errorf("failed to save settings: %w", err)
+	}
+	return settings, nil
+}
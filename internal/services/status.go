@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kontentski/chat/internal/models"
+)
+
+var (
+	ErrInvalidStatusText   = errors.New("status text must be at most 100 characters")
+	ErrInvalidStatusEmoji  = errors.New("status emoji must be a single emoji")
+	ErrInvalidStatusExpiry = errors.New("expires_at must be in the future")
+	ErrInvalidDNDUntil     = errors.New("until must be in the future")
+	ErrInvalidDNDSchedule  = errors.New("schedule start and end must be different HH:MM times and time_zone an IANA time zone such as Europe/Kyiv")
+)
+
+// mentionPattern finds @username mentions in message content.
+var mentionPattern = regexp.MustCompile(`@([\pL\pN._-]+)`)
+
+// StatusRequest sets the current user's status. Empty text and emoji clear it.
+type StatusRequest struct {
+	Text      string     `json:"text"`
+	Emoji     string     `json:"emoji"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// StatusResponse is the current user's status as others see it, together with their do-not-disturb settings.
+type StatusResponse struct {
+	Status       *models.UserStatus  `json:"status"`
+	DoNotDisturb models.DoNotDisturb `json:"do_not_disturb"`
+}
+
+// dndActive reports whether do-not-disturb is on at now.
+func dndActive(dnd models.DoNotDisturb, now time.Time) bool {
+	if dnd.Enabled && (dnd.Until == nil || now.Before(*dnd.Until)) {
+		return true
+	}
+	if dnd.Schedule == nil {
+		return false
+	}
+	start, errStart := time.Parse("15:04", dnd.Schedule.Start)
+	end, errEnd := time.Parse("15:04", dnd.Schedule.End)
+	location, errZone := time.LoadLocation(dnd.Schedule.TimeZone)
+	if errStart != nil || errEnd != nil || errZone != nil {
+		return false
+	}
+
+	local := now.In(location)
+	minute := local.Hour()*60 + local.Minute()
+	from, to := start.Hour()*60+start.Minute(), end.Hour()*60+end.Minute()
+	if from <= to {
+		return minute >= from && minute < to
+	}
+	return minute >= from || minute < to
+}
+
+// publicStatus returns what other users see of the stored status at now, or nil if there is
+// nothing to show. An expired status is no longer shown.
+func publicStatus(settings *models.StatusSettings, now time.Time) *models.UserStatus {
+	status := &models.UserStatus{
+		UserID:       settings.UserID,
+		DoNotDisturb: dndActive(settings.DoNotDisturb, now),
+	}
+	if settings.ExpiresAt == nil || now.Before(*settings.ExpiresAt) {
+		status.Text = settings.Text
+		status.Emoji = settings.Emoji
+		status.ExpiresAt = settings.ExpiresAt
+	}
+	if status.Text == "" && status.Emoji == "" && !status.DoNotDisturb {
+		return nil
+	}
+	return status
+}
+
+// userStatuses returns the public statuses of those of the users who have one, by user ID.
+func (s *UserChatRoomServiceImpl) userStatuses(ctx context.Context, userIDs []uint) (map[uint]*models.UserStatus, error) {
+	statuses := make(map[uint]*models.UserStatus)
+	if len(userIDs) == 0 {
+		return statuses, nil
+	}
+	stored, err := s.UserRepo.GetStatusSettingsForUsers(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range stored {
+		if status := publicStatus(&stored[i], now); status != nil {
+			statuses[status.UserID] = status
+		}
+	}
+	return statuses, nil
+}
+
+// GetStatus returns the current user's status and do-not-disturb settings.
+func (s *UserChatRoomServiceImpl) GetStatus(c *gin.Context) (*StatusResponse, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := s.UserRepo.GetStatusSettings(c.Request.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+	return &StatusResponse{
+		Status:       publicStatus(settings, time.Now()),
+		DoNotDisturb: settings.DoNotDisturb,
+	}, nil
+}
+
+// SetStatus replaces the current user's status text and emoji, which disappear at expires_at if it is set.
+// It returns the status as others now see it.
+func (s *UserChatRoomServiceImpl) SetStatus(c *gin.Context) (*models.UserStatus, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	var input StatusRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		return nil, err
+	}
+
+	text := strings.TrimSpace(input.Text)
+	emoji := strings.TrimSpace(input.Emoji)
+	if utf8.RuneCountInString(text) > 100 {
+		return nil, ErrInvalidStatusText
+	}
+	// Emoji with skin tones or joined with zero-width joiners take several code points
+	if utf8.RuneCountInString(emoji) > 16 || strings.ContainsAny(emoji, " \t\n") {
+		return nil, ErrInvalidStatusEmoji
+	}
+	now := time.Now()
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(now) {
+		return nil, ErrInvalidStatusExpiry
+	}
+	if text == "" && emoji == "" {
+		input.ExpiresAt = nil
+	}
+
+	ctx := c.Request.Context()
+	if err := s.UserRepo.SetStatus(ctx, userID, text, emoji, input.ExpiresAt); err != nil {
+		return nil, fmt.Errorf("failed to save status: %w", err)
+	}
+	return s.currentPublicStatus(ctx, userID, now)
+}
+
+// SetDoNotDisturb replaces the current user's do-not-disturb settings and returns their status as
+// others now see it.
+func (s *UserChatRoomServiceImpl) SetDoNotDisturb(c *gin.Context) (*models.UserStatus, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	var input models.DoNotDisturb
+	if err := c.ShouldBindJSON(&input); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !input.Enabled {
+		input.Until = nil
+	} else if input.Until != nil && !input.Until.After(now) {
+		return nil, ErrInvalidDNDUntil
+	}
+	if input.Schedule != nil {
+		start, errStart := time.Parse("15:04", input.Schedule.Start)
+		end, errEnd := time.Parse("15:04", input.Schedule.End)
+		_, errZone := time.LoadLocation(input.Schedule.TimeZone)
+		if errStart != nil || errEnd != nil || errZone != nil || start.Equal(end) || input.Schedule.TimeZone == "" {
+			return nil, ErrInvalidDNDSchedule
+		}
+		input.Schedule.Start, input.Schedule.End = start.Format("15:04"), end.Format("15:04")
+	}
+
+	ctx := c.Request.Context()
+	if err := s.UserRepo.SetDoNotDisturb(ctx, userID, input); err != nil {
+		return nil, fmt.Errorf("failed to save do-not-disturb: %w", err)
+	}
+	return s.currentPublicStatus(ctx, userID, now)
+}
+
+// currentPublicStatus returns the user's status as others see it at now, with an empty status
+// rather than nil so that clients can clear what they show.
+func (s *UserChatRoomServiceImpl) currentPublicStatus(ctx context.Context, userID uint, now time.Time) (*models.UserStatus, error) {
+	settings, err := s.UserRepo.GetStatusSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if status := publicStatus(settings, now); status != nil {
+		return status, nil
+	}
+	return &models.UserStatus{UserID: userID}, nil
+}
+
+// MessageNotifications returns whom to alert about msg: the other member of a direct message,
+// and the members mentioned as @username in other messages. Members in do-not-disturb, who muted the
+// room or set its notification level to none are left out; they still receive the message itself.
+func (s *UserChatRoomServiceImpl) MessageNotifications(ctx context.Context, msg *models.Messages) ([]models.Notification, error) {
+	targets, err := s.UserRepo.GetNotificationTargets(ctx, msg.ChatRoomID)
+	if err != nil {
+		return nil, err
+	}
+
+	mentioned := make(map[string]bool)
+	if !msg.IsDM {
+		for _, match := range mentionPattern.FindAllStringSubmatch(msg.Content, -1) {
+			mentioned[strings.ToLower(strings.TrimRight(match[1], "."))] = true
+		}
+	}
+
+	now := time.Now()
+	var notifications []models.Notification
+	for _, target := range targets {
+		if target.UserID == msg.SenderID {
+			continue
+		}
+		reason := models.NotificationReasonDM
+		if !msg.IsDM {
+			if !mentioned[strings.ToLower(target.Username)] {
+				continue
+			}
+			reason = models.NotificationReasonMention
+		}
+		if target.NotificationLevel == models.NotificationNone ||
+			target.MutedUntil != nil && target.MutedUntil.After(now) ||
+			dndActive(target.DoNotDisturb, now) {
+			continue
+		}
+		notifications = append(notifications, models.Notification{UserID: target.UserID, Reason: reason})
+	}
+	return notifications, nil
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+)
+
+var (
+	ErrInvalidAccessToken  = errors.New("invalid or expired access token")
+	ErrAccessTokenNotFound = errors.New("access token not found")
+	ErrInvalidTokenName    = errors.New("name must be between 1 and 100 characters")
+	ErrInvalidTokenScopes  = errors.New("scopes must be one or more of read, write and websocket")
+	ErrInvalidTokenExpiry  = errors.New("expires_in_days must be between 1 and 365")
+	ErrSessionRequired     = errors.New("this can only be done from a signed-in session, not with an access token")
+)
+
+// AccessTokenIDKey holds the ID of the access token a request was authenticated with.
+const AccessTokenIDKey = "accessTokenID"
+
+// accessTokenPrefix starts every access token, so leaked tokens are easy to recognise.
+const accessTokenPrefix = "chat_pat_"
+
+const (
+	defaultTokenExpiryDays = 30
+	maxTokenExpiryDays     = 365
+)
+
+var validScopes = map[string]bool{
+	models.ScopeRead:      true,
+	models.ScopeWrite:     true,
+	models.ScopeWebSocket: true,
+}
+
+type AccessTokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresInDays defaults to 30.
+	ExpiresInDays int `json:"expires_in_days"`
+}
+
+// requireSession rejects requests authenticated with an access token, so a token cannot be used
+// to mint or list other tokens, or to take over the account by signing out its sessions.
+func requireSession(c *gin.Context) error {
+	if _, ok := c.Get(AccessTokenIDKey); ok {
+		return ErrSessionRequired
+	}
+	return nil
+}
+
+// CreateAccessToken creates a personal access token for the current user. The token itself is only
+// part of this response.
+func (s *UserChatRoomServiceImpl) CreateAccessToken(c *gin.Context) (*models.AccessToken, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireSession(c); err != nil {
+		return nil, err
+	}
+
+	var input AccessTokenRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		return nil, err
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" || len([]rune(input.Name)) > 100 {
+		return nil, ErrInvalidTokenName
+	}
+	if input.ExpiresInDays == 0 {
+		input.ExpiresInDays = defaultTokenExpiryDays
+	}
+	if input.ExpiresInDays < 1 || input.ExpiresInDays > maxTokenExpiryDays {
+		return nil, ErrInvalidTokenExpiry
+	}
+
+	scopes := []string{}
+	seen := make(map[string]bool)
+	for _, scope := range input.Scopes {
+		if !validScopes[scope] {
+			return nil, ErrInvalidTokenScopes
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, ErrInvalidTokenScopes
+	}
+
+	secret, err := newAccessTokenSecret()
+	if err != nil {
+		return nil, err
+	}
+	token := &models.AccessToken{
+		UserID: userID,
+		Name:   input.Name,
+		Prefix: secret[:len(accessTokenPrefix)+6],
+		Scopes: scopes,
+	}
+	if err := s.UserRepo.CreateAccessToken(c.Request.Context(), token, secret, input.ExpiresInDays); err != nil {
+		return nil, err
+	}
+	token.Token = secret
+	return token, nil
+}
+
+// GetAccessTokens lists the current user's unexpired access tokens.
+func (s *UserChatRoomServiceImpl) GetAccessTokens(c *gin.Context) ([]models.AccessToken, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireSession(c); err != nil {
+		return nil, err
+	}
+	return s.UserRepo.GetAccessTokens(c.Request.Context(), userID)
+}
+
+// RevokeAccessToken deletes one of the current user's access tokens and returns its ID.
+func (s *UserChatRoomServiceImpl) RevokeAccessToken(c *gin.Context) (uint, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return 0, err
+	}
+	if err := requireSession(c); err != nil {
+		return 0, err
+	}
+	tokenID, err := paramUint(c, "tokenID")
+	if err != nil {
+		return 0, err
+	}
+
+	revoked, err := s.UserRepo.RevokeAccessToken(c.Request.Context(), userID, tokenID)
+	if err != nil {
+		return 0, err
+	}
+	if !revoked {
+		return 0, ErrAccessTokenNotFound
+	}
+	return tokenID, nil
+}
+
+// AuthenticateToken returns the unexpired access token with the given secret.
+func (s *UserChatRoomServiceImpl) AuthenticateToken(ctx context.Context, secret string) (*models.AccessToken, error) {
+	if !strings.HasPrefix(secret, accessTokenPrefix) {
+		return nil, ErrInvalidAccessToken
+	}
+	token, err := s.UserRepo.UseAccessToken(ctx, secret)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvalidAccessToken
+	}
+	return token, err
+}
+
+func newAccessTokenSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return accessTokenPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
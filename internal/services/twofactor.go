@@ -0,0 +1,323 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+	"github.com/kontentski/chat/internal/totp"
+)
+
+var (
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled  = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotEnrolled = errors.New("start two-factor enrollment first")
+	ErrTwoFactorRequired    = errors.New("two-factor authentication is required on this server")
+	ErrTwoFactorExpired     = errors.New("two-factor sign-in expired, sign in again")
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+)
+
+// Session keys of the two-factor sign-in. A session that passed the password or provider check of
+// a user with two-factor authentication holds PendingUserIDKey instead of UserIDKey until a code is
+// entered. TwoFactorSetupKey marks a session that must enroll an authenticator before anything else.
+const (
+	PendingUserIDKey  = "pendingUserID"
+	PendingSinceKey   = "pendingSince"
+	TwoFactorSetupKey = "twoFactorSetup"
+)
+
+const recoveryCodeCount = 10
+
+// Two-factor states of a sign-in, returned by LoginTwoFactorState.
+const (
+	TwoFactorNone    = ""
+	TwoFactorPending = "pending"
+	TwoFactorSetup   = "setup"
+)
+
+// TwoFactorConfig configures TOTP two-factor authentication.
+type TwoFactorConfig struct {
+	// Issuer names the service in authenticator apps.
+	Issuer string
+	// Required makes every user enroll an authenticator before they can use the app.
+	Required bool
+	// Now is the clock codes are checked against, it defaults to time.Now.
+	Now func() time.Time
+}
+
+func (t TwoFactorConfig) now() time.Time {
+	if t.Now == nil {
+		return time.Now()
+	}
+	return t.Now()
+}
+
+type TwoFactorCodeRequest struct {
+	// Code is a six digit authenticator code, or a recovery code where accepted.
+	Code string `json:"code" form:"code"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// confirmedTOTP returns the user's authenticator secret if two-factor authentication is on.
+func (s *UserChatRoomServiceImpl) confirmedTOTP(ctx context.Context, userID uint) (*models.TOTP, error) {
+	secret, err := s.UserRepo.GetTOTP(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if secret.ConfirmedAt == nil {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	return secret, nil
+}
+
+// LoginTwoFactorState tells a sign-in of the user whether it must be completed with a code
+// (TwoFactorPending), whether the user must enroll first (TwoFactorSetup), or neither.
+func (s *UserChatRoomServiceImpl) LoginTwoFactorState(ctx context.Context, userID uint) (string, error) {
+	_, err := s.confirmedTOTP(ctx, userID)
+	switch {
+	case err == nil:
+		return TwoFactorPending, nil
+	case !errors.Is(err, ErrTwoFactorNotEnabled):
+		return "", err
+	case s.TwoFactor.Required:
+		return TwoFactorSetup, nil
+	default:
+		return TwoFactorNone, nil
+	}
+}
+
+// VerifyTwoFactor checks the authenticator or recovery code that completes a pending sign-in of the
+// user. Repeated wrong codes lock the user out for a growing period.
+func (s *UserChatRoomServiceImpl) VerifyTwoFactor(ctx context.Context, userID uint, code string) error {
+	secret, err := s.confirmedTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.checkTwoFactorCode(ctx, secret, code, true)
+}
+
+// GetTwoFactorStatus reports whether the current user has two-factor authentication on.
+func (s *UserChatRoomServiceImpl) GetTwoFactorStatus(c *gin.Context) (*models.TwoFactorStatus, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.TwoFactorStatus{Required: s.TwoFactor.Required}
+	if _, err := s.confirmedTOTP(c.Request.Context(), userID); errors.Is(err, ErrTwoFactorNotEnabled) {
+		return status, nil
+	} else if err != nil {
+		return nil, err
+	}
+	status.Enabled = true
+	status.RecoveryCodesLeft, err = s.UserRepo.CountRecoveryCodes(c.Request.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// EnrollTOTP starts enrolling an authenticator for the current user, labelled with account in the
+// app. Enrollment replaces an unconfirmed secret and ends with ConfirmTOTP.
+func (s *UserChatRoomServiceImpl) EnrollTOTP(c *gin.Context, account string) (*models.TOTPEnrollment, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireSession(c); err != nil {
+		return nil, err
+	}
+	if _, err := s.confirmedTOTP(c.Request.Context(), userID); err == nil {
+		return nil, ErrTwoFactorEnabled
+	} else if !errors.Is(err, ErrTwoFactorNotEnabled) {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.UserRepo.SaveTOTPSecret(c.Request.Context(), userID, secret); err != nil {
+		return nil, err
+	}
+	return &models.TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.ProvisioningURI(s.TwoFactor.Issuer, account, secret),
+	}, nil
+}
+
+// ConfirmTOTP turns two-factor authentication on once the user enters a code from the enrolled
+// authenticator, and returns their recovery codes. The codes are not shown again.
+func (s *UserChatRoomServiceImpl) ConfirmTOTP(c *gin.Context) ([]string, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireSession(c); err != nil {
+		return nil, err
+	}
+	var input TwoFactorCodeRequest
+	if err := c.ShouldBind(&input); err != nil {
+		return nil, err
+	}
+
+	secret, err := s.UserRepo.GetTOTP(c.Request.Context(), userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if secret.ConfirmedAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+	step, ok := totp.Verify(secret.Secret, input.Code, s.TwoFactor.now(), secret.LastUsedStep)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	confirmed, err := s.UserRepo.ConfirmTOTP(c.Request.Context(), userID, step, codes)
+	if err != nil {
+		return nil, err
+	}
+	if !confirmed {
+		return nil, ErrTwoFactorEnabled
+	}
+	return codes, nil
+}
+
+// DisableTOTP turns two-factor authentication off for the current user after checking an
+// authenticator or recovery code. It is refused when the server requires two-factor authentication.
+func (s *UserChatRoomServiceImpl) DisableTOTP(c *gin.Context) error {
+	if s.TwoFactor.Required {
+		return ErrTwoFactorRequired
+	}
+	secret, err := s.checkCurrentUserCode(c, true)
+	if err != nil {
+		return err
+	}
+	return s.UserRepo.DeleteTOTP(c.Request.Context(), secret.UserID)
+}
+
+// RegenerateRecoveryCodes replaces the current user's recovery codes after checking an
+// authenticator code.
+func (s *UserChatRoomServiceImpl) RegenerateRecoveryCodes(c *gin.Context) ([]string, error) {
+	secret, err := s.checkCurrentUserCode(c, false)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.UserRepo.ReplaceRecoveryCodes(c.Request.Context(), secret.UserID, codes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// checkCurrentUserCode checks the code in the request body against the current user's authenticator,
+// or their recovery codes if allowRecovery is set.
+func (s *UserChatRoomServiceImpl) checkCurrentUserCode(c *gin.Context, allowRecovery bool) (*models.TOTP, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireSession(c); err != nil {
+		return nil, err
+	}
+	var input TwoFactorCodeRequest
+	if err := c.ShouldBind(&input); err != nil {
+		return nil, err
+	}
+
+	secret, err := s.confirmedTOTP(c.Request.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkTwoFactorCode(c.Request.Context(), secret, input.Code, allowRecovery); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// checkTwoFactorCode accepts an unused authenticator code or, if allowRecovery is set, an unused
+// recovery code. Wrong codes count against LoginGuard.Codes.
+func (s *UserChatRoomServiceImpl) checkTwoFactorCode(ctx context.Context, secret *models.TOTP, code string, allowRecovery bool) error {
+	key := fmt.Sprint(secret.UserID)
+	if err := checkLockout(s.LoginGuard.Codes, key); err != nil {
+		return err
+	}
+
+	ok, err := s.useTwoFactorCode(ctx, secret, code, allowRecovery)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if s.LoginGuard.Codes != nil {
+			s.LoginGuard.Codes.Fail(key)
+		}
+		return ErrInvalidTwoFactorCode
+	}
+	if s.LoginGuard.Codes != nil {
+		s.LoginGuard.Codes.Reset(key)
+	}
+	return nil
+}
+
+func (s *UserChatRoomServiceImpl) useTwoFactorCode(ctx context.Context, secret *models.TOTP, code string, allowRecovery bool) (bool, error) {
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) == totp.Digits {
+		step, ok := totp.Verify(secret.Secret, code, s.TwoFactor.now(), secret.LastUsedStep)
+		if !ok {
+			return false, nil
+		}
+		return s.UserRepo.UseTOTPStep(ctx, secret.UserID, step)
+	}
+	if !allowRecovery {
+		return false, nil
+	}
+	return s.UserRepo.UseRecoveryCode(ctx, secret.UserID, normalizeRecoveryCode(code))
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRecoveryCodes returns one-time codes of ten base32 characters, written as "abcde-fghij".
+func newRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	buf := make([]byte, 7)
+	for i := range codes {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(recoveryEncoding.EncodeToString(buf))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	if len(code) != 10 {
+		return code
+	}
+	return code[:5] + "-" + code[5:]
+}
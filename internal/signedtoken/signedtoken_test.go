@@ -0,0 +1,43 @@
+package signedtoken
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	signer := New([]byte("test-key"))
+	signer.Now = func() time.Time { return now }
+
+	token := signer.Sign("reset-password", 42, "hash-1", time.Hour)
+	userID, err := UserID(token)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(42), userID)
+	assert.NoError(t, signer.Verify(token, "reset-password", "hash-1"))
+
+	// Bound to the purpose, the state and the key
+	assert.ErrorIs(t, signer.Verify(token, "verify-email", "hash-1"), ErrInvalid)
+	assert.ErrorIs(t, signer.Verify(token, "reset-password", "hash-2"), ErrInvalid)
+	assert.ErrorIs(t, New([]byte("other-key")).Verify(token, "reset-password", "hash-1"), ErrInvalid)
+
+	now = now.Add(time.Hour)
+	assert.ErrorIs(t, signer.Verify(token, "reset-password", "hash-1"), ErrExpired)
+}
+
+func TestVerifyRejectsTamperedTokens(t *testing.T) {
+	signer := New([]byte("test-key"))
+	token := signer.Sign("reset-password", 42, "hash-1", time.Hour)
+	other := signer.Sign("reset-password", 43, "hash-1", time.Hour)
+
+	// Another user's payload with this token's signature
+	payload, _, _ := strings.Cut(other, ".")
+	_, signature, _ := strings.Cut(token, ".")
+	forged := payload + "." + signature
+	for _, bad := range []string{"", "no-dot", "!!.!!", token[:10] + "." + token[11:], forged} {
+		assert.ErrorIs(t, signer.Verify(bad, "reset-password", "hash-1"), ErrInvalid, bad)
+	}
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/kontentski/chat/internal/models"
+)
+
+// SaveBookmark saves a message for the user, or updates the note if it is already saved.
+func (r *PostgresRepository) SaveBookmark(ctx context.Context, userID uint, bookmark *models.Bookmark) error {
+	return r.DB.QueryRow(ctx, SaveBookmarkQuery, userID, bookmark.ChatRoomID, bookmark.MessageID, bookmark.Note).
+		Scan(&bookmark.ID, &bookmark.CreatedAt)
+}
+
+// DeleteBookmark removes one of the user's bookmarks and reports whether it existed.
+func (r *PostgresRepository) DeleteBookmark(ctx context.Context, userID, bookmarkID uint) (bool, error) {
+	tag, err := r.DB.Exec(ctx, DeleteBookmarkQuery, userID, bookmarkID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetBookmarks returns the user's bookmarks, newest first. Messages that were deleted or expired, or
+// belong to a room the user has left, are marked unavailable and their content is not returned.
+func (r *PostgresRepository) GetBookmarks(ctx context.Context, userID uint, limit, offset int) ([]models.Bookmark, error) {
+	rows, err := r.DB.Query(ctx, GetBookmarksQuery, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bookmarks []models.Bookmark
+	for rows.Next() {
+		var bookmark models.Bookmark
+		var isMember, messageExists bool
+		var senderID sql.NullInt64
+		var username, name, content, msgType sql.NullString
+		var timestamp sql.NullTime
+		var isDM sql.NullBool
+		if err := rows.Scan(
+			&bookmark.ID,
+			&bookmark.ChatRoomID,
+			&bookmark.MessageID,
+			&bookmark.Note,
+			&bookmark.CreatedAt,
+			&bookmark.ChatRoomName,
+			&bookmark.ChatRoomType,
+			&isMember,
+			&messageExists,
+			&senderID,
+			&username,
+			&name,
+			&content,
+			&timestamp,
+			&isDM,
+			&msgType,
+		); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case !isMember:
+			bookmark.UnavailableReason = models.BookmarkNotMember
+		case !messageExists:
+			bookmark.UnavailableReason = models.BookmarkMessageDeleted
+		case msgType.String == models.EventMessageExpired:
+			bookmark.UnavailableReason = models.BookmarkMessageExpired
+		default:
+			bookmark.Available = true
+			bookmark.Message = &models.Messages{
+				MessageID:  bookmark.MessageID,
+				ChatRoomID: bookmark.ChatRoomID,
+				SenderID:   uint(senderID.Int64),
+				Sender:     models.Users{ID: uint(senderID.Int64), Username: username.String, Name: name.String},
+				Content:    content.String,
+				Timestamp:  timestamp.Time,
+				IsDM:       isDM.Bool,
+				Type:       msgType.String,
+			}
+		}
+		bookmarks = append(bookmarks, bookmark)
+	}
+
+	return bookmarks, rows.Err()
+}
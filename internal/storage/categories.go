@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kontentski/chat/internal/models"
+)
+
+func (r *PostgresRepository) GetRoomCategories(ctx context.Context, userID uint) ([]models.RoomCategory, error) {
+	rows, err := r.DB.Query(ctx, GetRoomCategoriesQuery, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []models.RoomCategory
+	for rows.Next() {
+		var category models.RoomCategory
+		if err := rows.Scan(&category.ID, &category.UserID, &category.Name, &category.Position, &category.CreatedAt); err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, rows.Err()
+}
+
+// CreateRoomCategory adds the category after the user's existing ones.
+func (r *PostgresRepository) CreateRoomCategory(ctx context.Context, category *models.RoomCategory) error {
+	return r.DB.QueryRow(ctx, CreateRoomCategoryQuery, category.UserID, category.Name).
+		Scan(&category.ID, &category.Position, &category.CreatedAt)
+}
+
+func (r *PostgresRepository) RenameRoomCategory(ctx context.Context, userID, categoryID uint, name string) (bool, error) {
+	tag, err := r.DB.Exec(ctx, RenameRoomCategoryQuery, userID, categoryID, name)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// DeleteRoomCategory removes the category, its rooms fall back to the uncategorized group.
+func (r *PostgresRepository) DeleteRoomCategory(ctx context.Context, userID, categoryID uint) (bool, error) {
+	tag, err := r.DB.Exec(ctx, DeleteRoomCategoryQuery, userID, categoryID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ReorderRoomCategories stores the position of each category as its index in categoryIDs.
+func (r *PostgresRepository) ReorderRoomCategories(ctx context.Context, userID uint, categoryIDs []uint) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error: Failed to start transaction %w ", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for position, categoryID := range categoryIDs {
+		if _, err := tx.Exec(ctx, SetRoomCategoryPositionQuery, userID, categoryID, position); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error: Failed to commit transaction %w ", err)
+	}
+	return nil
+}
+
+// MoveChatRoomsToCategory puts the rooms into the category, ordered as in chatRoomIDs.
+// A nil categoryID moves them to the uncategorized group.
+func (r *PostgresRepository) MoveChatRoomsToCategory(ctx context.Context, userID uint, categoryID *uint, chatRoomIDs []uint) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error: Failed to start transaction %w ", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for position, chatRoomID := range chatRoomIDs {
+		if _, err := tx.Exec(ctx, SetChatRoomCategoryQuery, chatRoomID, userID, categoryID, position); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error: Failed to commit transaction %w ", err)
+	}
+	return nil
+}
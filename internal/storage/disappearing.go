@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/kontentski/chat/internal/models"
+)
+
+// SetMessageTTL sets after how many seconds new messages in the room disappear. nil turns it off.
+func (r *PostgresRepository) SetMessageTTL(ctx context.Context, chatRoomID uint, seconds *int) error {
+	_, err := r.DB.Exec(ctx, SetMessageTTLQuery, chatRoomID, seconds)
+	return err
+}
+
+// EraseExpiredMessages erases the content of up to limit messages whose expiry has passed.
+// The returned messages still carry their original content and type.
+func (r *PostgresRepository) EraseExpiredMessages(ctx context.Context, limit int) ([]models.Messages, error) {
+	rows, err := r.DB.Query(ctx, EraseExpiredMessagesQuery, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []models.Messages
+	for rows.Next() {
+		var msg models.Messages
+		var expiresAt time.Time
+		if err := rows.Scan(
+			&msg.MessageID,
+			&msg.ChatRoomID,
+			&msg.SenderID,
+			&msg.Content,
+			&msg.Type,
+			&msg.IsDM,
+			&expiresAt,
+		); err != nil {
+			return nil, err
+		}
+		msg.ExpiresAt = &expiresAt
+		messages = append(messages, msg)
+	}
+
+	return messages, rows.Err()
+}
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+)
+
+// GetDraft returns the user's draft in the chat room, with empty content when there is none.
+func (r *PostgresRepository) GetDraft(ctx context.Context, chatRoomID, userID uint) (*models.Draft, error) {
+	draft := &models.Draft{ChatRoomID: chatRoomID}
+	err := r.DB.QueryRow(ctx, GetDraftQuery, chatRoomID, userID).Scan(&draft.Content, &draft.UpdatedAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	return draft, nil
+}
+
+// SaveDraft stores the user's draft in the chat room. Empty content deletes the draft.
+func (r *PostgresRepository) SaveDraft(ctx context.Context, chatRoomID, userID uint, content string) (*models.Draft, error) {
+	draft := &models.Draft{ChatRoomID: chatRoomID, Content: content}
+	if content == "" {
+		if _, err := r.DB.Exec(ctx, DeleteDraftQuery, chatRoomID, userID); err != nil {
+			return nil, err
+		}
+		draft.UpdatedAt = time.Now()
+		return draft, nil
+	}
+
+	if err := r.DB.QueryRow(ctx, UpsertDraftQuery, chatRoomID, userID, content).Scan(&draft.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return draft, nil
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"database/sql"
+
+	"github.com/kontentski/chat/internal/models"
+)
+
+// messageReferenceColumns holds the nullable forwarding and quote columns of a message row.
+type messageReferenceColumns struct {
+	forwardedRoomID    sql.NullInt64
+	forwardedMessageID sql.NullInt64
+	forwardedSenderID  sql.NullInt64
+	quotedMessageID    sql.NullInt64
+	quoteSenderID      sql.NullInt64
+	quoteSenderName    sql.NullString
+	quoteContent       sql.NullString
+	quoteType          sql.NullString
+}
+
+// forwardedTargets are the scan targets of the forwarded_from_* and quoted_message_id columns.
+func (c *messageReferenceColumns) forwardedTargets() []interface{} {
+	return []interface{}{&c.forwardedRoomID, &c.forwardedMessageID, &c.forwardedSenderID, &c.quotedMessageID}
+}
+
+// quoteTargets are the scan targets of the joined quoted message.
+func (c *messageReferenceColumns) quoteTargets() []interface{} {
+	return []interface{}{&c.quoteSenderID, &c.quoteSenderName, &c.quoteContent, &c.quoteType}
+}
+
+func (c *messageReferenceColumns) apply(msg *models.Messages) {
+	if c.forwardedRoomID.Valid && c.forwardedMessageID.Valid {
+		msg.ForwardedFrom = &models.MessageReference{
+			ChatRoomID: uint(c.forwardedRoomID.Int64),
+			MessageID:  uint(c.forwardedMessageID.Int64),
+			SenderID:   uint(c.forwardedSenderID.Int64),
+		}
+	}
+	if c.quotedMessageID.Valid {
+		msg.QuotedMessageID = uint(c.quotedMessageID.Int64)
+		// The quoted message may have been deleted since, then only its ID is left
+		if c.quoteSenderID.Valid {
+			msg.Quote = &models.MessageReference{
+				ChatRoomID: msg.ChatRoomID,
+				MessageID:  msg.QuotedMessageID,
+				SenderID:   uint(c.quoteSenderID.Int64),
+				SenderName: c.quoteSenderName.String,
+				Type:       c.quoteType.String,
+			}
+			if c.quoteType.String != "media" {
+				msg.Quote.Content = c.quoteContent.String
+			}
+		}
+	}
+}
@@ -19,6 +19,8 @@ import (
 type BucketStorage interface {
 	UploadFileToBucket(file io.Reader, originalFileName, filePath string, c context.Context) (string, error)
 	GenerateSignedURL(filePath string) (string, error)
+	DeleteFile(ctx context.Context, filePath string) error
+	CopyFile(ctx context.Context, srcPath, dstPath string) error
 }
 
 type GoogleUpload struct {
@@ -111,6 +113,33 @@ func (GoogleUpload) GenerateSignedURL(filePath string) (string, error) {
 	return url, nil
 }
 
+func (GoogleUpload) DeleteFile(ctx context.Context, filePath string) error {
+	client, err := NewStorageClient()
+	if err != nil {
+		return fmt.Errorf("failed to create storage client: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.Bucket(bucketName).Object(filePath).Delete(ctx); err != nil && err != buck.ErrObjectNotExist {
+		return fmt.Errorf("failed to delete %s: %v", filePath, err)
+	}
+	return nil
+}
+
+func (GoogleUpload) CopyFile(ctx context.Context, srcPath, dstPath string) error {
+	client, err := NewStorageClient()
+	if err != nil {
+		return fmt.Errorf("failed to create storage client: %v", err)
+	}
+	defer client.Close()
+
+	bucket := client.Bucket(bucketName)
+	if _, err := bucket.Object(dstPath).CopierFrom(bucket.Object(srcPath)).Run(ctx); err != nil {
+		return fmt.Errorf("failed to copy %s to %s: %v", srcPath, dstPath, err)
+	}
+	return nil
+}
+
 func getContentType(filename string) string {
 	ext := filepath.Ext(filename)
 	switch strings.ToLower(ext) {
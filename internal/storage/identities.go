@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/kontentski/chat/internal/models"
+)
+
+var (
+	ErrIdentityTaken         = errors.New("this provider account is already linked to another user")
+	ErrProviderAlreadyLinked = errors.New("an account of this provider is already linked")
+	ErrEmailTaken            = errors.New("an account with this email already exists, sign in and link the provider instead")
+)
+
+// identityError translates unique violations on users and user_identities into the errors above.
+func identityError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+		return err
+	}
+	switch pgErr.ConstraintName {
+	case "user_identities_provider_account_key":
+		return ErrIdentityTaken
+	case "user_identities_user_provider_key":
+		return ErrProviderAlreadyLinked
+	case "users_email_key":
+		return ErrEmailTaken
+	default:
+		return err
+	}
+}
+
+func scanUser(row pgx.Row) (*models.Users, error) {
+	var user models.Users
+	err := row.Scan(&user.ID, &user.Username, &user.Name, &user.Email, &user.ProfilePicture, &user.Password, &user.CreatedAt, &user.EmailVerifiedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserByIdentity returns the user linked to the provider account, or pgx.ErrNoRows if there is none.
+func (r *PostgresRepository) GetUserByIdentity(ctx context.Context, provider, providerUserID string) (*models.Users, error) {
+	return scanUser(r.DB.QueryRow(ctx, GetUserByIdentityQuery, provider, providerUserID))
+}
+
+// GetUnlinkedUserByEmail returns the user with the email if they have no linked identities yet, which is
+// the case for accounts created before identities were tracked. It returns pgx.ErrNoRows otherwise.
+func (r *PostgresRepository) GetUnlinkedUserByEmail(ctx context.Context, email string) (*models.Users, error) {
+	return scanUser(r.DB.QueryRow(ctx, GetUnlinkedUserByEmailQuery, email))
+}
+
+// CreateOAuthUser creates a user who still has to pick a username and password, linked to the identity.
+func (r *PostgresRepository) CreateOAuthUser(ctx context.Context, user *models.Users, identity *models.UserIdentity) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, CreateOAuthUserQuery, user.Name, user.Email, user.ProfilePicture).
+		Scan(&user.ID, &user.Username, &user.Password, &user.CreatedAt)
+	if err != nil {
+		return identityError(err)
+	}
+
+	identity.UserID = user.ID
+	err = tx.QueryRow(ctx, LinkIdentityQuery, identity.UserID, identity.Provider, identity.ProviderUserID, identity.Email).
+		Scan(&identity.ID, &identity.LinkedAt)
+	if err != nil {
+		return identityError(err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// LinkIdentity links the provider account to identity.UserID.
+func (r *PostgresRepository) LinkIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	err := r.DB.QueryRow(ctx, LinkIdentityQuery, identity.UserID, identity.Provider, identity.ProviderUserID, identity.Email).
+		Scan(&identity.ID, &identity.LinkedAt)
+	return identityError(err)
+}
+
+func (r *PostgresRepository) GetIdentities(ctx context.Context, userID uint) ([]models.UserIdentity, error) {
+	rows, err := r.DB.Query(ctx, GetIdentitiesQuery, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []models.UserIdentity{}
+	for rows.Next() {
+		var identity models.UserIdentity
+		if err := rows.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.ProviderUserID, &identity.Email, &identity.LinkedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+// UnlinkIdentity removes the user's identity of the provider. It reports false, leaving the identity in
+// place, when it is the only way left for the user to sign in.
+func (r *PostgresRepository) UnlinkIdentity(ctx context.Context, userID uint, provider string) (bool, error) {
+	tag, err := r.DB.Exec(ctx, UnlinkIdentityQuery, userID, provider)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *PostgresRepository) UpdateLastSeen(ctx context.Context, userID uint) error {
+	_, err := r.DB.Exec(ctx, UpdateLastSeenQuery, userID)
+	return err
+}
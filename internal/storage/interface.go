@@ -18,12 +18,90 @@ type UserRepository interface {
 	CreateUser(user *models.Users) error
 	IsUserInChatRoom(userID, chatRoomID uint) bool
 	IsUserExists(username string) bool
+	GetUserByUsername(ctx context.Context, username string) (*models.Users, error)
+	GetUserByID(ctx context.Context, userID uint) (*models.Users, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.Users, error)
+	UpdatePassword(ctx context.Context, userID uint, oldHash, newHash string) (bool, error)
+	MarkEmailVerified(ctx context.Context, userID uint, email string) error
+	UpdateProfile(ctx context.Context, userID uint, name, username, email string) (*models.Users, error)
+	SetProfilePicture(ctx context.Context, userID uint, picture string) (*models.Users, error)
+	GetStatusSettings(ctx context.Context, userID uint) (*models.StatusSettings, error)
+	GetStatusSettingsForUsers(ctx context.Context, userIDs []uint) ([]models.StatusSettings, error)
+	SetStatus(ctx context.Context, userID uint, text, emoji string, expiresAt *time.Time) error
+	SetDoNotDisturb(ctx context.Context, userID uint, dnd models.DoNotDisturb) error
+	GetNotificationTargets(ctx context.Context, chatRoomID uint) ([]models.NotificationTarget, error)
+	UpdateLastSeen(ctx context.Context, userID uint) error
+	GetUserByIdentity(ctx context.Context, provider, providerUserID string) (*models.Users, error)
+	GetUnlinkedUserByEmail(ctx context.Context, email string) (*models.Users, error)
+	CreateOAuthUser(ctx context.Context, user *models.Users, identity *models.UserIdentity) error
+	LinkIdentity(ctx context.Context, identity *models.UserIdentity) error
+	GetIdentities(ctx context.Context, userID uint) ([]models.UserIdentity, error)
+	UnlinkIdentity(ctx context.Context, userID uint, provider string) (bool, error)
+	GetSessions(ctx context.Context, userID uint) ([]models.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID uint) (bool, error)
+	RevokeSessions(ctx context.Context, userID, exceptID uint) ([]uint, error)
+	CreateAccessToken(ctx context.Context, token *models.AccessToken, secret string, expiresInDays int) error
+	GetAccessTokens(ctx context.Context, userID uint) ([]models.AccessToken, error)
+	UseAccessToken(ctx context.Context, secret string) (*models.AccessToken, error)
+	RevokeAccessToken(ctx context.Context, userID, tokenID uint) (bool, error)
+	RevokeAccessTokens(ctx context.Context, userID uint) ([]uint, error)
+	GetTOTP(ctx context.Context, userID uint) (*models.TOTP, error)
+	SaveTOTPSecret(ctx context.Context, userID uint, secret string) error
+	UseTOTPStep(ctx context.Context, userID uint, step int64) (bool, error)
+	ConfirmTOTP(ctx context.Context, userID uint, step int64, recoveryCodes []string) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID uint, recoveryCodes []string) error
+	UseRecoveryCode(ctx context.Context, userID uint, code string) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userID uint) (int, error)
+	DeleteTOTP(ctx context.Context, userID uint) error
 	AddUserToTheChatRoom(ctx context.Context, userID string, chatRoomID uint) error
 	SearchUsers(ctx context.Context, q string) ([]models.Users, error)
 	DeleteUserFromChatRoom(ctx context.Context, IntuserID, chatRoomID uint) error
 	DeleteMessage(ctx context.Context, messageID, chatRoomID uint) error
 	GetMessages(ctx context.Context, userID uint, chatRoomID string) ([]models.Messages, error)
 	FetchUserChatRooms(userID uint) ([]models.ChatRooms, error)
+	GetChatRoomMembers(ctx context.Context, chatRoomID uint, limit, offset int) ([]models.ChatRoomMemberProfile, error)
+	GetChatRoomMember(ctx context.Context, chatRoomID, userID uint) (*models.ChatRoomMemberProfile, error)
+	CountChatRoomMembers(ctx context.Context, chatRoomID uint) (int, error)
+	ApplyModerationAction(ctx context.Context, action *models.ModerationAction) error
+	IsUserBanned(ctx context.Context, chatRoomID, userID uint) (bool, error)
+	GetMutedUntil(ctx context.Context, chatRoomID, userID uint) (time.Time, error)
+	GetModerationActions(ctx context.Context, chatRoomID uint, limit, offset int) ([]models.ModerationAction, error)
+	SetSlowMode(ctx context.Context, chatRoomID uint, seconds int) error
+	GetSlowModeState(ctx context.Context, chatRoomID, userID uint) (slowMode, sinceLastMessage time.Duration, err error)
+	GetMessage(ctx context.Context, chatRoomID, messageID uint) (*models.Messages, error)
+	PinMessage(ctx context.Context, chatRoomID, messageID, pinnedBy uint) (*models.PinnedMessage, error)
+	UnpinMessage(ctx context.Context, chatRoomID, messageID uint) (bool, error)
+	GetPinnedMessages(ctx context.Context, chatRoomID uint) ([]models.PinnedMessage, error)
+	GetChatRoomSettings(ctx context.Context, chatRoomID, userID uint) (*models.ChatRoomMemberSettings, error)
+	UpsertChatRoomSettings(ctx context.Context, chatRoomID, userID uint, settings *models.ChatRoomMemberSettings) error
+	GetRoomCategories(ctx context.Context, userID uint) ([]models.RoomCategory, error)
+	CreateRoomCategory(ctx context.Context, category *models.RoomCategory) error
+	RenameRoomCategory(ctx context.Context, userID, categoryID uint, name string) (bool, error)
+	DeleteRoomCategory(ctx context.Context, userID, categoryID uint) (bool, error)
+	ReorderRoomCategories(ctx context.Context, userID uint, categoryIDs []uint) error
+	MoveChatRoomsToCategory(ctx context.Context, userID uint, categoryID *uint, chatRoomIDs []uint) error
+	GetChatRoom(ctx context.Context, chatRoomID uint) (*models.ChatRooms, error)
+	SetChatRoomImage(ctx context.Context, chatRoomID uint, kind, filePath string) (string, error)
+	SetRetentionDays(ctx context.Context, chatRoomID uint, days *int) error
+	GetRetentionPolicies(ctx context.Context) (map[uint]*int, error)
+	PurgeMessagesOlderThan(ctx context.Context, chatRoomID uint, days int) (*models.PurgedMessages, error)
+	SetMessageTTL(ctx context.Context, chatRoomID uint, seconds *int) error
+	EraseExpiredMessages(ctx context.Context, limit int) ([]models.Messages, error)
+	GetPoll(ctx context.Context, chatRoomID, pollID, userID uint) (*models.Poll, error)
+	GetPollByMessage(ctx context.Context, chatRoomID, messageID, userID uint) (*models.Poll, error)
+	SetPollVotes(ctx context.Context, pollID, userID uint, optionIDs []uint) error
+	ClosePoll(ctx context.Context, pollID uint) error
+	SearchMessages(ctx context.Context, userID uint, filter models.MessageSearchFilter) ([]models.MessageSearchResult, error)
+	SaveBookmark(ctx context.Context, userID uint, bookmark *models.Bookmark) error
+	DeleteBookmark(ctx context.Context, userID, bookmarkID uint) (bool, error)
+	GetBookmarks(ctx context.Context, userID uint, limit, offset int) ([]models.Bookmark, error)
+	GetDraft(ctx context.Context, chatRoomID, userID uint) (*models.Draft, error)
+	SaveDraft(ctx context.Context, chatRoomID, userID uint, content string) (*models.Draft, error)
+	CreateScheduledMessage(ctx context.Context, scheduled *models.ScheduledMessage) error
+	GetScheduledMessages(ctx context.Context, senderID, chatRoomID uint) ([]models.ScheduledMessage, error)
+	CancelScheduledMessage(ctx context.Context, scheduledID, senderID uint) (*models.ScheduledMessage, error)
+	ClaimDueScheduledMessages(ctx context.Context, limit int, staleAfter time.Duration) ([]models.ScheduledMessage, error)
+	MarkScheduledMessageFailed(ctx context.Context, scheduledID uint, reason string) error
 }
 
 type AuthRepository interface {
@@ -132,9 +210,11 @@ func (r *PostgresRepository) GetMessages(ctx context.Context, userID uint, chatR
 		var msg models.Messages
 		var readAt sql.NullTime
 		var msgType sql.NullString  // Use sql.NullString for the type field
+		var expiresAt sql.NullTime
+		var refs messageReferenceColumns
 
 		// Scan the row into the message struct
-		if err := rows.Scan(
+		dest := []interface{}{
 			&msg.MessageID, 
 			&msg.SenderID, 
 			&msg.Sender.Username, 
@@ -145,7 +225,11 @@ func (r *PostgresRepository) GetMessages(ctx context.Context, userID uint, chatR
 			&msg.IsDM, 
 			&msgType,  // Scan into msgType (sql.NullString)
 			&readAt,
-		); err != nil {
+			&expiresAt,
+		}
+		dest = append(dest, refs.forwardedTargets()...)
+		dest = append(dest, refs.quoteTargets()...)
+		if err := rows.Scan(dest...); err != nil {
 			log.Printf("GetMessages: Error scanning row - %v", err)
 			return nil, err
 		}
@@ -164,6 +248,11 @@ func (r *PostgresRepository) GetMessages(ctx context.Context, userID uint, chatR
 			msg.Type = ""  // or any default value you prefer
 		}
 
+		if expiresAt.Valid {
+			msg.ExpiresAt = &expiresAt.Time
+		}
+		refs.apply(&msg)
+
 		messages = append(messages, msg)
 	}
 
@@ -189,9 +278,44 @@ func (r *PostgresRepository) FetchUserChatRooms(userID uint) ([]models.ChatRooms
 	var chatRooms []models.ChatRooms
 	for rows.Next() {
 		var room models.ChatRooms
-		if err := rows.Scan(&room.ID, &room.Name, &room.Description, &room.Type); err != nil {
+		var mutedUntil sql.NullTime
+		var categoryID sql.NullInt64
+		var retentionDays, messageTTL sql.NullInt32
+		if err := rows.Scan(
+			&room.ID,
+			&room.Name,
+			&room.Description,
+			&room.Type,
+			&room.SlowModeSeconds,
+			&retentionDays,
+			&messageTTL,
+			&room.AvatarPath,
+			&room.BannerPath,
+			&room.IsDM,
+			&mutedUntil,
+			&room.Settings.Favorite,
+			&room.Settings.Archived,
+			&room.Settings.NotificationLevel,
+			&categoryID,
+			&room.Settings.Position,
+		); err != nil {
 			return nil, err
 		}
+		if mutedUntil.Valid {
+			room.Settings.MutedUntil = &mutedUntil.Time
+		}
+		if categoryID.Valid {
+			id := uint(categoryID.Int64)
+			room.Settings.CategoryID = &id
+		}
+		if retentionDays.Valid {
+			days := int(retentionDays.Int32)
+			room.RetentionDays = &days
+		}
+		if messageTTL.Valid {
+			seconds := int(messageTTL.Int32)
+			room.MessageTTLSeconds = &seconds
+		}
 		chatRooms = append(chatRooms, room)
 	}
 
@@ -199,7 +323,7 @@ func (r *PostgresRepository) FetchUserChatRooms(userID uint) ([]models.ChatRooms
 }
 
 type RealAuth struct {
-	Store *sessions.CookieStore
+	Store sessions.Store
 }
 
 func (r *RealAuth) GetSession(req *http.Request) (map[string]interface{}, error) {
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+)
+
+func (r *PostgresRepository) GetChatRoomMembers(ctx context.Context, chatRoomID uint, limit, offset int) ([]models.ChatRoomMemberProfile, error) {
+	rows, err := r.DB.Query(ctx, GetChatRoomMembersQuery, chatRoomID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []models.ChatRoomMemberProfile
+	for rows.Next() {
+		member, err := scanChatRoomMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		member.ChatRoomID = chatRoomID
+		members = append(members, *member)
+	}
+
+	return members, rows.Err()
+}
+
+// GetChatRoomMember returns a single member of the chat room, or pgx.ErrNoRows if the user is not in it.
+func (r *PostgresRepository) GetChatRoomMember(ctx context.Context, chatRoomID, userID uint) (*models.ChatRoomMemberProfile, error) {
+	member, err := scanChatRoomMember(r.DB.QueryRow(ctx, GetChatRoomMemberQuery, chatRoomID, userID))
+	if err != nil {
+		return nil, err
+	}
+	member.ChatRoomID = chatRoomID
+	return member, nil
+}
+
+func (r *PostgresRepository) CountChatRoomMembers(ctx context.Context, chatRoomID uint) (int, error) {
+	var count int
+	err := r.DB.QueryRow(ctx, CountChatRoomMembersQuery, chatRoomID).Scan(&count)
+	return count, err
+}
+
+func scanChatRoomMember(row pgx.Row) (*models.ChatRoomMemberProfile, error) {
+	var member models.ChatRoomMemberProfile
+	var joinedAt, lastSeen sql.NullTime
+
+	if err := row.Scan(
+		&member.UserID,
+		&member.Username,
+		&member.Name,
+		&member.ProfilePicture,
+		&member.Role,
+		&joinedAt,
+		&lastSeen,
+	); err != nil {
+		return nil, err
+	}
+
+	if joinedAt.Valid {
+		member.JoinedAt = joinedAt.Time
+	}
+	if lastSeen.Valid {
+		member.LastSeen = lastSeen.Time
+	}
+	return &member, nil
+}
@@ -6,7 +6,10 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"sync"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/kontentski/chat/internal/models"
 	"github.com/stretchr/testify/mock"
 )
@@ -82,6 +85,510 @@ func (m *MockUser) FetchUserChatRooms(userID uint) ([]models.ChatRooms, error) {
 	return nil, fmt.Errorf("database error")
 }
 
+func (m *MockUser) GetChatRoomMembers(ctx context.Context, chatRoomID uint, limit, offset int) ([]models.ChatRoomMemberProfile, error) {
+	args := m.Called(ctx, chatRoomID, limit, offset)
+	if members, ok := args.Get(0).([]models.ChatRoomMemberProfile); ok {
+		return members, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) GetChatRoomMember(ctx context.Context, chatRoomID, userID uint) (*models.ChatRoomMemberProfile, error) {
+	args := m.Called(ctx, chatRoomID, userID)
+	if member, ok := args.Get(0).(*models.ChatRoomMemberProfile); ok {
+		return member, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) CountChatRoomMembers(ctx context.Context, chatRoomID uint) (int, error) {
+	args := m.Called(ctx, chatRoomID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUser) ApplyModerationAction(ctx context.Context, action *models.ModerationAction) error {
+	args := m.Called(ctx, action)
+	return args.Error(0)
+}
+
+func (m *MockUser) IsUserBanned(ctx context.Context, chatRoomID, userID uint) (bool, error) {
+	args := m.Called(ctx, chatRoomID, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUser) GetMutedUntil(ctx context.Context, chatRoomID, userID uint) (time.Time, error) {
+	args := m.Called(ctx, chatRoomID, userID)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *MockUser) GetModerationActions(ctx context.Context, chatRoomID uint, limit, offset int) ([]models.ModerationAction, error) {
+	args := m.Called(ctx, chatRoomID, limit, offset)
+	if actions, ok := args.Get(0).([]models.ModerationAction); ok {
+		return actions, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) SetSlowMode(ctx context.Context, chatRoomID uint, seconds int) error {
+	args := m.Called(ctx, chatRoomID, seconds)
+	return args.Error(0)
+}
+
+func (m *MockUser) GetSlowModeState(ctx context.Context, chatRoomID, userID uint) (time.Duration, time.Duration, error) {
+	args := m.Called(ctx, chatRoomID, userID)
+	return args.Get(0).(time.Duration), args.Get(1).(time.Duration), args.Error(2)
+}
+
+func (m *MockUser) GetMessage(ctx context.Context, chatRoomID, messageID uint) (*models.Messages, error) {
+	args := m.Called(ctx, chatRoomID, messageID)
+	if msg, ok := args.Get(0).(*models.Messages); ok {
+		return msg, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) PinMessage(ctx context.Context, chatRoomID, messageID, pinnedBy uint) (*models.PinnedMessage, error) {
+	args := m.Called(ctx, chatRoomID, messageID, pinnedBy)
+	if pin, ok := args.Get(0).(*models.PinnedMessage); ok {
+		return pin, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) UnpinMessage(ctx context.Context, chatRoomID, messageID uint) (bool, error) {
+	args := m.Called(ctx, chatRoomID, messageID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUser) GetPinnedMessages(ctx context.Context, chatRoomID uint) ([]models.PinnedMessage, error) {
+	args := m.Called(ctx, chatRoomID)
+	if pins, ok := args.Get(0).([]models.PinnedMessage); ok {
+		return pins, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) GetChatRoomSettings(ctx context.Context, chatRoomID, userID uint) (*models.ChatRoomMemberSettings, error) {
+	args := m.Called(ctx, chatRoomID, userID)
+	if settings, ok := args.Get(0).(*models.ChatRoomMemberSettings); ok {
+		return settings, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) UpsertChatRoomSettings(ctx context.Context, chatRoomID, userID uint, settings *models.ChatRoomMemberSettings) error {
+	args := m.Called(ctx, chatRoomID, userID, settings)
+	return args.Error(0)
+}
+
+func (m *MockUser) GetRoomCategories(ctx context.Context, userID uint) ([]models.RoomCategory, error) {
+	args := m.Called(ctx, userID)
+	if categories, ok := args.Get(0).([]models.RoomCategory); ok {
+		return categories, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) CreateRoomCategory(ctx context.Context, category *models.RoomCategory) error {
+	args := m.Called(ctx, category)
+	return args.Error(0)
+}
+
+func (m *MockUser) RenameRoomCategory(ctx context.Context, userID, categoryID uint, name string) (bool, error) {
+	args := m.Called(ctx, userID, categoryID, name)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUser) DeleteRoomCategory(ctx context.Context, userID, categoryID uint) (bool, error) {
+	args := m.Called(ctx, userID, categoryID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUser) ReorderRoomCategories(ctx context.Context, userID uint, categoryIDs []uint) error {
+	args := m.Called(ctx, userID, categoryIDs)
+	return args.Error(0)
+}
+
+func (m *MockUser) MoveChatRoomsToCategory(ctx context.Context, userID uint, categoryID *uint, chatRoomIDs []uint) error {
+	args := m.Called(ctx, userID, categoryID, chatRoomIDs)
+	return args.Error(0)
+}
+
+func (m *MockUser) GetChatRoom(ctx context.Context, chatRoomID uint) (*models.ChatRooms, error) {
+	args := m.Called(ctx, chatRoomID)
+	if room, ok := args.Get(0).(*models.ChatRooms); ok {
+		return room, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) SetChatRoomImage(ctx context.Context, chatRoomID uint, kind, filePath string) (string, error) {
+	args := m.Called(ctx, chatRoomID, kind, filePath)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockUser) SetRetentionDays(ctx context.Context, chatRoomID uint, days *int) error {
+	args := m.Called(ctx, chatRoomID, days)
+	return args.Error(0)
+}
+
+func (m *MockUser) GetRetentionPolicies(ctx context.Context) (map[uint]*int, error) {
+	args := m.Called(ctx)
+	if policies, ok := args.Get(0).(map[uint]*int); ok {
+		return policies, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) PurgeMessagesOlderThan(ctx context.Context, chatRoomID uint, days int) (*models.PurgedMessages, error) {
+	args := m.Called(ctx, chatRoomID, days)
+	if purged, ok := args.Get(0).(*models.PurgedMessages); ok {
+		return purged, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) CreateScheduledMessage(ctx context.Context, scheduled *models.ScheduledMessage) error {
+	args := m.Called(ctx, scheduled)
+	return args.Error(0)
+}
+
+func (m *MockUser) GetScheduledMessages(ctx context.Context, senderID, chatRoomID uint) ([]models.ScheduledMessage, error) {
+	args := m.Called(ctx, senderID, chatRoomID)
+	if scheduled, ok := args.Get(0).([]models.ScheduledMessage); ok {
+		return scheduled, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) CancelScheduledMessage(ctx context.Context, scheduledID, senderID uint) (*models.ScheduledMessage, error) {
+	args := m.Called(ctx, scheduledID, senderID)
+	if scheduled, ok := args.Get(0).(*models.ScheduledMessage); ok {
+		return scheduled, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) ClaimDueScheduledMessages(ctx context.Context, limit int, staleAfter time.Duration) ([]models.ScheduledMessage, error) {
+	args := m.Called(ctx, limit, staleAfter)
+	if scheduled, ok := args.Get(0).([]models.ScheduledMessage); ok {
+		return scheduled, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) MarkScheduledMessageFailed(ctx context.Context, scheduledID uint, reason string) error {
+	args := m.Called(ctx, scheduledID, reason)
+	return args.Error(0)
+}
+
+func (m *MockUser) SetMessageTTL(ctx context.Context, chatRoomID uint, seconds *int) error {
+	args := m.Called(ctx, chatRoomID, seconds)
+	return args.Error(0)
+}
+
+func (m *MockUser) EraseExpiredMessages(ctx context.Context, limit int) ([]models.Messages, error) {
+	args := m.Called(ctx, limit)
+	if messages, ok := args.Get(0).([]models.Messages); ok {
+		return messages, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) GetPoll(ctx context.Context, chatRoomID, pollID, userID uint) (*models.Poll, error) {
+	args := m.Called(ctx, chatRoomID, pollID, userID)
+	if poll, ok := args.Get(0).(*models.Poll); ok {
+		return poll, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) GetPollByMessage(ctx context.Context, chatRoomID, messageID, userID uint) (*models.Poll, error) {
+	args := m.Called(ctx, chatRoomID, messageID, userID)
+	if poll, ok := args.Get(0).(*models.Poll); ok {
+		return poll, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) SetPollVotes(ctx context.Context, pollID, userID uint, optionIDs []uint) error {
+	args := m.Called(ctx, pollID, userID, optionIDs)
+	return args.Error(0)
+}
+
+func (m *MockUser) ClosePoll(ctx context.Context, pollID uint) error {
+	args := m.Called(ctx, pollID)
+	return args.Error(0)
+}
+
+func (m *MockUser) SearchMessages(ctx context.Context, userID uint, filter models.MessageSearchFilter) ([]models.MessageSearchResult, error) {
+	args := m.Called(ctx, userID, filter)
+	if results, ok := args.Get(0).([]models.MessageSearchResult); ok {
+		return results, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) SaveBookmark(ctx context.Context, userID uint, bookmark *models.Bookmark) error {
+	args := m.Called(ctx, userID, bookmark)
+	return args.Error(0)
+}
+
+func (m *MockUser) DeleteBookmark(ctx context.Context, userID, bookmarkID uint) (bool, error) {
+	args := m.Called(ctx, userID, bookmarkID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUser) GetBookmarks(ctx context.Context, userID uint, limit, offset int) ([]models.Bookmark, error) {
+	args := m.Called(ctx, userID, limit, offset)
+	if bookmarks, ok := args.Get(0).([]models.Bookmark); ok {
+		return bookmarks, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) GetDraft(ctx context.Context, chatRoomID, userID uint) (*models.Draft, error) {
+	args := m.Called(ctx, chatRoomID, userID)
+	if draft, ok := args.Get(0).(*models.Draft); ok {
+		return draft, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) SaveDraft(ctx context.Context, chatRoomID, userID uint, content string) (*models.Draft, error) {
+	args := m.Called(ctx, chatRoomID, userID, content)
+	if draft, ok := args.Get(0).(*models.Draft); ok {
+		return draft, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) GetUserByUsername(ctx context.Context, username string) (*models.Users, error) {
+	args := m.Called(ctx, username)
+	if user, ok := args.Get(0).(*models.Users); ok {
+		return user, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) UpdateLastSeen(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockUser) GetUserByIdentity(ctx context.Context, provider, providerUserID string) (*models.Users, error) {
+	args := m.Called(ctx, provider, providerUserID)
+	if user, ok := args.Get(0).(*models.Users); ok {
+		return user, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) GetUnlinkedUserByEmail(ctx context.Context, email string) (*models.Users, error) {
+	args := m.Called(ctx, email)
+	if user, ok := args.Get(0).(*models.Users); ok {
+		return user, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) CreateOAuthUser(ctx context.Context, user *models.Users, identity *models.UserIdentity) error {
+	args := m.Called(ctx, user, identity)
+	return args.Error(0)
+}
+
+func (m *MockUser) LinkIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	args := m.Called(ctx, identity)
+	return args.Error(0)
+}
+
+func (m *MockUser) GetIdentities(ctx context.Context, userID uint) ([]models.UserIdentity, error) {
+	args := m.Called(ctx, userID)
+	if identities, ok := args.Get(0).([]models.UserIdentity); ok {
+		return identities, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) UnlinkIdentity(ctx context.Context, userID uint, provider string) (bool, error) {
+	args := m.Called(ctx, userID, provider)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUser) GetSessions(ctx context.Context, userID uint) ([]models.Session, error) {
+	args := m.Called(ctx, userID)
+	if userSessions, ok := args.Get(0).([]models.Session); ok {
+		return userSessions, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) RevokeSession(ctx context.Context, userID, sessionID uint) (bool, error) {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUser) RevokeSessions(ctx context.Context, userID, exceptID uint) ([]uint, error) {
+	args := m.Called(ctx, userID, exceptID)
+	if revoked, ok := args.Get(0).([]uint); ok {
+		return revoked, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) CreateAccessToken(ctx context.Context, token *models.AccessToken, secret string, expiresInDays int) error {
+	args := m.Called(ctx, token, secret, expiresInDays)
+	return args.Error(0)
+}
+
+func (m *MockUser) GetAccessTokens(ctx context.Context, userID uint) ([]models.AccessToken, error) {
+	args := m.Called(ctx, userID)
+	if tokens, ok := args.Get(0).([]models.AccessToken); ok {
+		return tokens, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) UseAccessToken(ctx context.Context, secret string) (*models.AccessToken, error) {
+	args := m.Called(ctx, secret)
+	if token, ok := args.Get(0).(*models.AccessToken); ok {
+		return token, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) RevokeAccessToken(ctx context.Context, userID, tokenID uint) (bool, error) {
+	args := m.Called(ctx, userID, tokenID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUser) RevokeAccessTokens(ctx context.Context, userID uint) ([]uint, error) {
+	args := m.Called(ctx, userID)
+	if revoked, ok := args.Get(0).([]uint); ok {
+		return revoked, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) GetTOTP(ctx context.Context, userID uint) (*models.TOTP, error) {
+	args := m.Called(ctx, userID)
+	if totp, ok := args.Get(0).(*models.TOTP); ok {
+		return totp, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) SaveTOTPSecret(ctx context.Context, userID uint, secret string) error {
+	args := m.Called(ctx, userID, secret)
+	return args.Error(0)
+}
+
+func (m *MockUser) UseTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	args := m.Called(ctx, userID, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUser) ConfirmTOTP(ctx context.Context, userID uint, step int64, recoveryCodes []string) (bool, error) {
+	args := m.Called(ctx, userID, step, recoveryCodes)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUser) ReplaceRecoveryCodes(ctx context.Context, userID uint, recoveryCodes []string) error {
+	args := m.Called(ctx, userID, recoveryCodes)
+	return args.Error(0)
+}
+
+func (m *MockUser) UseRecoveryCode(ctx context.Context, userID uint, code string) (bool, error) {
+	args := m.Called(ctx, userID, code)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUser) CountRecoveryCodes(ctx context.Context, userID uint) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUser) DeleteTOTP(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockUser) GetUserByID(ctx context.Context, userID uint) (*models.Users, error) {
+	args := m.Called(ctx, userID)
+	if user, ok := args.Get(0).(*models.Users); ok {
+		return user, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) GetUserByEmail(ctx context.Context, email string) (*models.Users, error) {
+	args := m.Called(ctx, email)
+	if user, ok := args.Get(0).(*models.Users); ok {
+		return user, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) UpdatePassword(ctx context.Context, userID uint, oldHash, newHash string) (bool, error) {
+	args := m.Called(ctx, userID, oldHash, newHash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUser) MarkEmailVerified(ctx context.Context, userID uint, email string) error {
+	args := m.Called(ctx, userID, email)
+	return args.Error(0)
+}
+
+func (m *MockUser) UpdateProfile(ctx context.Context, userID uint, name, username, email string) (*models.Users, error) {
+	args := m.Called(ctx, userID, name, username, email)
+	if user, ok := args.Get(0).(*models.Users); ok {
+		return user, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) SetProfilePicture(ctx context.Context, userID uint, picture string) (*models.Users, error) {
+	args := m.Called(ctx, userID, picture)
+	if user, ok := args.Get(0).(*models.Users); ok {
+		return user, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) GetStatusSettings(ctx context.Context, userID uint) (*models.StatusSettings, error) {
+	args := m.Called(ctx, userID)
+	if settings, ok := args.Get(0).(*models.StatusSettings); ok {
+		return settings, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) GetStatusSettingsForUsers(ctx context.Context, userIDs []uint) ([]models.StatusSettings, error) {
+	args := m.Called(ctx, userIDs)
+	if statuses, ok := args.Get(0).([]models.StatusSettings); ok {
+		return statuses, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUser) SetStatus(ctx context.Context, userID uint, text, emoji string, expiresAt *time.Time) error {
+	args := m.Called(ctx, userID, text, emoji, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockUser) SetDoNotDisturb(ctx context.Context, userID uint, dnd models.DoNotDisturb) error {
+	args := m.Called(ctx, userID, dnd)
+	return args.Error(0)
+}
+
+func (m *MockUser) GetNotificationTargets(ctx context.Context, chatRoomID uint) ([]models.NotificationTarget, error) {
+	args := m.Called(ctx, chatRoomID)
+	if targets, ok := args.Get(0).([]models.NotificationTarget); ok {
+		return targets, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 /*
 func (m *MockUser) UploadFileToBucket(file multipart.File, originalFileName, filePath string, c context.Context) (string, error){
 	if m.Called(file, originalFileName, filePath, c).Error(1)!= nil {
@@ -126,3 +633,71 @@ func (m *MockBucketStorage) GenerateSignedURL(filePath string) (string, error) {
 	args := m.Called(filePath)
 	return args.String(0), args.Error(1)
 }
+
+func (m *MockBucketStorage) DeleteFile(ctx context.Context, filePath string) error {
+	args := m.Called(ctx, filePath)
+	return args.Error(0)
+}
+
+func (m *MockBucketStorage) CopyFile(ctx context.Context, srcPath, dstPath string) error {
+	args := m.Called(ctx, srcPath, dstPath)
+	return args.Error(0)
+}
+
+// MockSessionBackend keeps sessions in memory, for tests that sign users in through a SessionStore.
+type MockSessionBackend struct {
+	mu       sync.Mutex
+	nextID   uint
+	Sessions map[string]*MockSession
+}
+
+type MockSession struct {
+	ID     uint
+	UserID *uint
+	Data   []byte
+	IP     string
+}
+
+func NewMockSessionBackend() *MockSessionBackend {
+	return &MockSessionBackend{Sessions: make(map[string]*MockSession)}
+}
+
+func (m *MockSessionBackend) LoadSession(ctx context.Context, tokenHash []byte, touchAfter time.Duration) (uint, []byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, ok := m.Sessions[string(tokenHash)]
+	if !ok {
+		return 0, nil, false, pgx.ErrNoRows
+	}
+	return session.ID, session.Data, false, nil
+}
+
+func (m *MockSessionBackend) TouchSession(ctx context.Context, id uint, ip string) error {
+	return nil
+}
+
+func (m *MockSessionBackend) CreateSession(ctx context.Context, tokenHash []byte, userID *uint, data []byte, userAgent, ip string, maxAge int) (uint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	m.Sessions[string(tokenHash)] = &MockSession{ID: m.nextID, UserID: userID, Data: data, IP: ip}
+	return m.nextID, nil
+}
+
+func (m *MockSessionBackend) UpdateSession(ctx context.Context, tokenHash []byte, userID *uint, data []byte, maxAge int) (uint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, ok := m.Sessions[string(tokenHash)]
+	if !ok {
+		return 0, pgx.ErrNoRows
+	}
+	session.UserID, session.Data = userID, data
+	return session.ID, nil
+}
+
+func (m *MockSessionBackend) DeleteSession(ctx context.Context, tokenHash []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.Sessions, string(tokenHash))
+	return nil
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+)
+
+// ApplyModerationAction performs a kick, ban, unban, mute, unmute, promote or demote and records it in
+// moderation_actions within the same transaction.
+func (r *PostgresRepository) ApplyModerationAction(ctx context.Context, action *models.ModerationAction) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error: Failed to start transaction %w ", err)
+	}
+	defer tx.Rollback(ctx)
+
+	switch action.Action {
+	case models.ModerationKick:
+		_, err = tx.Exec(ctx, DeleteUserFromChatRoomQuery, action.TargetUserID, action.ChatRoomID)
+	case models.ModerationBan:
+		if _, err = tx.Exec(ctx, InsertChatRoomBanQuery, action.ChatRoomID, action.TargetUserID, action.ModeratorID, action.Reason); err == nil {
+			_, err = tx.Exec(ctx, DeleteUserFromChatRoomQuery, action.TargetUserID, action.ChatRoomID)
+		}
+	case models.ModerationUnban:
+		_, err = tx.Exec(ctx, DeleteChatRoomBanQuery, action.ChatRoomID, action.TargetUserID)
+	case models.ModerationMute:
+		_, err = tx.Exec(ctx, SetMutedUntilQuery, action.ChatRoomID, action.TargetUserID, action.ExpiresAt)
+	case models.ModerationUnmute:
+		_, err = tx.Exec(ctx, SetMutedUntilQuery, action.ChatRoomID, action.TargetUserID, nil)
+	case models.ModerationPromote:
+		_, err = tx.Exec(ctx, SetMemberRoleQuery, action.ChatRoomID, action.TargetUserID, models.RoleAdmin)
+	case models.ModerationDemote:
+		_, err = tx.Exec(ctx, SetMemberRoleQuery, action.ChatRoomID, action.TargetUserID, models.RoleMember)
+	default:
+		return fmt.Errorf("unknown moderation action: %s", action.Action)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(ctx, InsertModerationActionQuery,
+		action.ChatRoomID,
+		action.ModeratorID,
+		action.TargetUserID,
+		action.Action,
+		action.Reason,
+		action.ExpiresAt,
+	).Scan(&action.ID, &action.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error: Failed to commit transaction %w ", err)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) IsUserBanned(ctx context.Context, chatRoomID, userID uint) (bool, error) {
+	var count int
+	err := r.DB.QueryRow(ctx, IsUserBannedQuery, chatRoomID, userID).Scan(&count)
+	return count > 0, err
+}
+
+// GetMutedUntil returns the time the user's mute in the chat room ends, or the zero time if they are not muted.
+func (r *PostgresRepository) GetMutedUntil(ctx context.Context, chatRoomID, userID uint) (time.Time, error) {
+	var mutedUntil sql.NullTime
+	err := r.DB.QueryRow(ctx, GetMutedUntilQuery, chatRoomID, userID).Scan(&mutedUntil)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return mutedUntil.Time, nil
+}
+
+func (r *PostgresRepository) GetModerationActions(ctx context.Context, chatRoomID uint, limit, offset int) ([]models.ModerationAction, error) {
+	rows, err := r.DB.Query(ctx, GetModerationActionsQuery, chatRoomID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var actions []models.ModerationAction
+	for rows.Next() {
+		var action models.ModerationAction
+		var expiresAt sql.NullTime
+		if err := rows.Scan(
+			&action.ID,
+			&action.ChatRoomID,
+			&action.ModeratorID,
+			&action.TargetUserID,
+			&action.Action,
+			&action.Reason,
+			&expiresAt,
+			&action.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			action.ExpiresAt = &expiresAt.Time
+		}
+		actions = append(actions, action)
+	}
+
+	return actions, rows.Err()
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/kontentski/chat/internal/models"
+)
+
+// GetMessage returns a single message with its sender, or pgx.ErrNoRows if it does not exist.
+func (r *PostgresRepository) GetMessage(ctx context.Context, chatRoomID, messageID uint) (*models.Messages, error) {
+	var msg models.Messages
+	var refs messageReferenceColumns
+	dest := append([]interface{}{
+		&msg.MessageID,
+		&msg.SenderID,
+		&msg.Sender.Username,
+		&msg.Sender.Name,
+		&msg.Content,
+		&msg.Timestamp,
+		&msg.ChatRoomID,
+		&msg.IsDM,
+		&msg.Type,
+	}, refs.forwardedTargets()...)
+	if err := r.DB.QueryRow(ctx, GetMessageQuery, chatRoomID, messageID).Scan(dest...); err != nil {
+		return nil, err
+	}
+	msg.Sender.ID = msg.SenderID
+	refs.apply(&msg)
+	return &msg, nil
+}
+
+// PinMessage pins a message in its chat room and returns the stored pin, without the message.
+// Pinning an already pinned message keeps and returns the original pin.
+func (r *PostgresRepository) PinMessage(ctx context.Context, chatRoomID, messageID, pinnedBy uint) (*models.PinnedMessage, error) {
+	pin := models.PinnedMessage{ChatRoomID: chatRoomID, MessageID: messageID}
+	err := r.DB.QueryRow(ctx, PinMessageQuery, chatRoomID, messageID, pinnedBy).Scan(
+		&pin.PinnedBy.ID,
+		&pin.PinnedBy.Username,
+		&pin.PinnedBy.Name,
+		&pin.PinnedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &pin, nil
+}
+
+// UnpinMessage removes a pin and reports whether the message was pinned.
+func (r *PostgresRepository) UnpinMessage(ctx context.Context, chatRoomID, messageID uint) (bool, error) {
+	tag, err := r.DB.Exec(ctx, UnpinMessageQuery, chatRoomID, messageID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *PostgresRepository) GetPinnedMessages(ctx context.Context, chatRoomID uint) ([]models.PinnedMessage, error) {
+	rows, err := r.DB.Query(ctx, GetPinnedMessagesQuery, chatRoomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pins []models.PinnedMessage
+	for rows.Next() {
+		var pin models.PinnedMessage
+		if err := rows.Scan(
+			&pin.ChatRoomID,
+			&pin.MessageID,
+			&pin.PinnedBy.ID,
+			&pin.PinnedBy.Username,
+			&pin.PinnedBy.Name,
+			&pin.PinnedAt,
+			&pin.Message.SenderID,
+			&pin.Message.Sender.Username,
+			&pin.Message.Sender.Name,
+			&pin.Message.Content,
+			&pin.Message.Timestamp,
+			&pin.Message.IsDM,
+			&pin.Message.Type,
+		); err != nil {
+			return nil, err
+		}
+		pin.Message.MessageID = pin.MessageID
+		pin.Message.ChatRoomID = pin.ChatRoomID
+		pin.Message.Sender.ID = pin.Message.SenderID
+		pins = append(pins, pin)
+	}
+
+	return pins, rows.Err()
+}
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+)
+
+// CreatePollTx stores a poll and its options inside the transaction that saves the poll's message,
+// and fills in the IDs. poll.MessageID must already be set.
+func CreatePollTx(ctx context.Context, tx pgx.Tx, poll *models.Poll) error {
+	err := tx.QueryRow(ctx, CreatePollQuery,
+		poll.ChatRoomID,
+		poll.MessageID,
+		poll.CreatorID,
+		poll.Question,
+		poll.MultipleChoice,
+		poll.Anonymous,
+		poll.ClosesAt,
+	).Scan(&poll.ID, &poll.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create poll: %w", err)
+	}
+
+	for i := range poll.Options {
+		if err := tx.QueryRow(ctx, CreatePollOptionQuery, poll.ID, i, poll.Options[i].Text).Scan(&poll.Options[i].ID); err != nil {
+			return fmt.Errorf("failed to create poll option: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetPoll returns a poll with its tallies and the votes of userID, or pgx.ErrNoRows if it does not exist.
+func (r *PostgresRepository) GetPoll(ctx context.Context, chatRoomID, pollID, userID uint) (*models.Poll, error) {
+	return r.loadPoll(ctx, r.DB.QueryRow(ctx, GetPollQuery, chatRoomID, pollID), userID)
+}
+
+// GetPollByMessage returns the poll attached to a message, or pgx.ErrNoRows if there is none.
+func (r *PostgresRepository) GetPollByMessage(ctx context.Context, chatRoomID, messageID, userID uint) (*models.Poll, error) {
+	return r.loadPoll(ctx, r.DB.QueryRow(ctx, GetPollByMessageQuery, chatRoomID, messageID), userID)
+}
+
+// SetPollVotes replaces the user's votes on a poll. An empty optionIDs retracts them.
+func (r *PostgresRepository) SetPollVotes(ctx context.Context, pollID, userID uint, optionIDs []uint) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error: Failed to start transaction %w ", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, DeleteUserPollVotesQuery, pollID, userID); err != nil {
+		return err
+	}
+	for _, optionID := range optionIDs {
+		if _, err := tx.Exec(ctx, InsertPollVoteQuery, pollID, optionID, userID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error: Failed to commit transaction %w ", err)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ClosePoll(ctx context.Context, pollID uint) error {
+	_, err := r.DB.Exec(ctx, ClosePollQuery, pollID)
+	return err
+}
+
+func (r *PostgresRepository) loadPoll(ctx context.Context, row pgx.Row, userID uint) (*models.Poll, error) {
+	var poll models.Poll
+	if err := row.Scan(
+		&poll.ID,
+		&poll.ChatRoomID,
+		&poll.MessageID,
+		&poll.CreatorID,
+		&poll.Question,
+		&poll.MultipleChoice,
+		&poll.Anonymous,
+		&poll.ClosesAt,
+		&poll.ClosedAt,
+		&poll.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	poll.Closed = poll.IsClosed(time.Now())
+
+	rows, err := r.DB.Query(ctx, GetPollOptionsQuery, poll.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var option models.PollOption
+		var voters []int32
+		if err := rows.Scan(&option.ID, &option.Text, &option.Votes, &voters); err != nil {
+			return nil, err
+		}
+		if !poll.Anonymous {
+			for _, voter := range voters {
+				option.Voters = append(option.Voters, uint(voter))
+			}
+		}
+		poll.Options = append(poll.Options, option)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.DB.QueryRow(ctx, CountPollVotersQuery, poll.ID).Scan(&poll.TotalVoters); err != nil {
+		return nil, err
+	}
+
+	if userID != 0 {
+		voteRows, err := r.DB.Query(ctx, GetUserPollVotesQuery, poll.ID, userID)
+		if err != nil {
+			return nil, err
+		}
+		defer voteRows.Close()
+		for voteRows.Next() {
+			var optionID uint
+			if err := voteRows.Scan(&optionID); err != nil {
+				return nil, err
+			}
+			poll.MyVotes = append(poll.MyVotes, optionID)
+		}
+		if err := voteRows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &poll, nil
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/kontentski/chat/internal/models"
+)
+
+var (
+	ErrUsernameTaken = errors.New("username already taken")
+	ErrEmailInUse    = errors.New("an account with this email already exists")
+)
+
+// profileError translates unique violations on users into the errors above.
+func profileError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+		return err
+	}
+	switch pgErr.ConstraintName {
+	case "users_username_key":
+		return ErrUsernameTaken
+	case "users_email_key":
+		return ErrEmailInUse
+	default:
+		return err
+	}
+}
+
+// UpdateProfile sets the user's name, username and email and returns the updated user. Changing
+// the email clears its verification.
+func (r *PostgresRepository) UpdateProfile(ctx context.Context, userID uint, name, username, email string) (*models.Users, error) {
+	user, err := scanUser(r.DB.QueryRow(ctx, UpdateProfileQuery, userID, name, username, email))
+	if err != nil {
+		return nil, profileError(err)
+	}
+	return user, nil
+}
+
+// SetProfilePicture replaces the user's profile picture and returns the updated user.
+func (r *PostgresRepository) SetProfilePicture(ctx context.Context, userID uint, picture string) (*models.Users, error) {
+	return scanUser(r.DB.QueryRow(ctx, SetProfilePictureQuery, userID, picture))
+}
@@ -19,23 +19,34 @@ const (
 	FROM users
 	WHERE username = $1
 	`
+	// The first member of a chat room becomes its admin
 	AddUserToTheChatRoomQuery = `
-	INSERT INTO chat_room_members (user_id, chat_room_id) VALUES ($1, $2)
+	INSERT INTO chat_room_members (user_id, chat_room_id, role)
+	SELECT $1::int, $2::int,
+		CASE WHEN EXISTS (SELECT 1 FROM chat_room_members WHERE chat_room_id = $2::int) THEN 'member' ELSE 'admin' END
 	`
 	DeleteMessageQuery = `DELETE FROM messages WHERE message_id=$1 AND chat_room_id=$2`
 
 	GetMessagesQuery = `
-	SELECT m.message_id, m.sender_id, u.username, u.name, m.content, m.timestamp, m.chat_room_id, m.is_dm, m.type, COALESCE(r.read_at, '1970-01-01T00:00:00Z') AS read_at 
+	SELECT m.message_id, m.sender_id, u.username, u.name, m.content, m.timestamp, m.chat_room_id, m.is_dm, m.type, COALESCE(r.read_at, '1970-01-01T00:00:00Z') AS read_at, m.expires_at,
+		m.forwarded_from_room_id, m.forwarded_from_message_id, m.forwarded_from_sender_id,
+		m.quoted_message_id, q.sender_id, qu.name, q.content, q.type
 	FROM messages m 
 	JOIN users u ON m.sender_id = u.id 
 	LEFT JOIN read_messages r ON m.message_id = r.message_id AND r.user_id = $1 AND m.chat_room_id = r.chat_room_id 
+	LEFT JOIN messages q ON q.chat_room_id = m.chat_room_id AND q.message_id = m.quoted_message_id
+	LEFT JOIN users qu ON qu.id = q.sender_id
 	WHERE m.chat_room_id = $2 
 	ORDER BY m.timestamp ASC`
 
 	FetchUserChatRoomsQuery = `
-	SELECT cr.id, cr.name, cr.description, cr.type
+	SELECT cr.id, cr.name, cr.description, cr.type, cr.slow_mode_seconds, cr.retention_days, cr.message_ttl_seconds, COALESCE(cr.avatar_path, ''), COALESCE(cr.banner_path, ''),
+		EXISTS (SELECT 1 FROM messages m WHERE m.chat_room_id = cr.id AND m.is_dm),
+		s.muted_until, COALESCE(s.favorite, false), COALESCE(s.archived, false), COALESCE(s.notification_level, 'all'),
+		s.category_id, COALESCE(s.position, 0)
 	FROM chat_rooms cr
 	JOIN chat_room_members crm ON cr.id = crm.chat_room_id
+	LEFT JOIN chat_room_member_settings s ON s.chat_room_id = crm.chat_room_id AND s.user_id = crm.user_id
 	WHERE crm.user_id = $1
 	`
 
@@ -43,4 +54,550 @@ const (
 	DELETE FROM chat_room_members 
 	WHERE user_id = $1 AND chat_room_id = $2
 	`
+
+	GetChatRoomMembersQuery = `
+	SELECT u.id, u.username, COALESCE(u.name, ''), COALESCE(u.profile_picture, ''), crm.role, crm.joined_at, u.last_seen
+	FROM chat_room_members crm
+	JOIN users u ON u.id = crm.user_id
+	WHERE crm.chat_room_id = $1
+	ORDER BY crm.joined_at ASC, u.id ASC
+	LIMIT $2 OFFSET $3
+	`
+
+	GetChatRoomMemberQuery = `
+	SELECT u.id, u.username, COALESCE(u.name, ''), COALESCE(u.profile_picture, ''), crm.role, crm.joined_at, u.last_seen
+	FROM chat_room_members crm
+	JOIN users u ON u.id = crm.user_id
+	WHERE crm.chat_room_id = $1 AND crm.user_id = $2
+	`
+
+	CountChatRoomMembersQuery = `SELECT COUNT(*) FROM chat_room_members WHERE chat_room_id = $1`
+
+	IsUserBannedQuery = `SELECT COUNT(*) FROM chat_room_bans WHERE chat_room_id = $1 AND user_id = $2`
+
+	InsertChatRoomBanQuery = `
+	INSERT INTO chat_room_bans (chat_room_id, user_id, banned_by, reason, created_at)
+	VALUES ($1, $2, $3, $4, NOW())
+	ON CONFLICT (chat_room_id, user_id) DO UPDATE SET banned_by = $3, reason = $4, created_at = NOW()
+	`
+
+	DeleteChatRoomBanQuery = `DELETE FROM chat_room_bans WHERE chat_room_id = $1 AND user_id = $2`
+
+	SetMutedUntilQuery = `
+	UPDATE chat_room_members SET muted_until = $3
+	WHERE chat_room_id = $1 AND user_id = $2
+	`
+
+	SetMemberRoleQuery = `
+	UPDATE chat_room_members SET role = $3
+	WHERE chat_room_id = $1 AND user_id = $2
+	`
+
+	GetMutedUntilQuery = `
+	SELECT muted_until FROM chat_room_members
+	WHERE chat_room_id = $1 AND user_id = $2
+	`
+
+	InsertModerationActionQuery = `
+	INSERT INTO moderation_actions (chat_room_id, moderator_id, target_user_id, action, reason, expires_at, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, NOW())
+	RETURNING id, created_at
+	`
+
+	GetModerationActionsQuery = `
+	SELECT id, chat_room_id, COALESCE(moderator_id, 0), target_user_id, action, COALESCE(reason, ''), expires_at, created_at
+	FROM moderation_actions
+	WHERE chat_room_id = $1
+	ORDER BY created_at DESC, id DESC
+	LIMIT $2 OFFSET $3
+	`
+
+	SetSlowModeQuery = `UPDATE chat_rooms SET slow_mode_seconds = $2 WHERE id = $1`
+
+	// seconds_since_last is NULL when the user has not posted in the room yet
+	GetSlowModeStateQuery = `
+	SELECT cr.slow_mode_seconds,
+		(SELECT EXTRACT(EPOCH FROM (NOW()::timestamp - MAX(m.timestamp)))::float8
+		 FROM messages m
+		 WHERE m.chat_room_id = cr.id AND m.sender_id = $2) AS seconds_since_last
+	FROM chat_rooms cr
+	WHERE cr.id = $1
+	`
+
+	GetMessageQuery = `
+	SELECT m.message_id, m.sender_id, u.username, COALESCE(u.name, ''), m.content, m.timestamp, m.chat_room_id, COALESCE(m.is_dm, false), COALESCE(m.type, ''),
+		m.forwarded_from_room_id, m.forwarded_from_message_id, m.forwarded_from_sender_id, m.quoted_message_id
+	FROM messages m
+	JOIN users u ON m.sender_id = u.id
+	WHERE m.chat_room_id = $1 AND m.message_id = $2
+	`
+
+	// The no-op update makes RETURNING yield the existing pin of an already pinned message
+	PinMessageQuery = `
+	WITH pin AS (
+		INSERT INTO pinned_messages (chat_room_id, message_id, pinned_by, pinned_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (chat_room_id, message_id) DO UPDATE SET pinned_by = pinned_messages.pinned_by
+		RETURNING pinned_by, pinned_at
+	)
+	SELECT COALESCE(pin.pinned_by, 0), COALESCE(u.username, ''), COALESCE(u.name, ''), pin.pinned_at
+	FROM pin
+	LEFT JOIN users u ON u.id = pin.pinned_by
+	`
+
+	UnpinMessageQuery = `DELETE FROM pinned_messages WHERE chat_room_id = $1 AND message_id = $2`
+
+	GetPinnedMessagesQuery = `
+	SELECT p.chat_room_id, p.message_id, COALESCE(p.pinned_by, 0), COALESCE(pu.username, ''), COALESCE(pu.name, ''), p.pinned_at,
+		m.sender_id, su.username, COALESCE(su.name, ''), m.content, m.timestamp, COALESCE(m.is_dm, false), COALESCE(m.type, '')
+	FROM pinned_messages p
+	JOIN messages m ON m.message_id = p.message_id AND m.chat_room_id = p.chat_room_id
+	JOIN users su ON su.id = m.sender_id
+	LEFT JOIN users pu ON pu.id = p.pinned_by
+	WHERE p.chat_room_id = $1
+	ORDER BY p.pinned_at ASC, p.message_id ASC
+	`
+
+	GetChatRoomSettingsQuery = `
+	SELECT muted_until, favorite, archived, notification_level
+	FROM chat_room_member_settings
+	WHERE chat_room_id = $1 AND user_id = $2
+	`
+
+	UpsertChatRoomSettingsQuery = `
+	INSERT INTO chat_room_member_settings (chat_room_id, user_id, muted_until, favorite, archived, notification_level, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, NOW())
+	ON CONFLICT (chat_room_id, user_id) DO UPDATE
+	SET muted_until = $3, favorite = $4, archived = $5, notification_level = $6, updated_at = NOW()
+	`
+
+	UnarchiveChatRoomQuery = `
+	UPDATE chat_room_member_settings SET archived = false, updated_at = NOW()
+	WHERE chat_room_id = $1 AND archived
+	`
+
+	GetRoomCategoriesQuery = `
+	SELECT id, user_id, name, position, created_at
+	FROM room_categories
+	WHERE user_id = $1
+	ORDER BY position ASC, id ASC
+	`
+
+	CreateRoomCategoryQuery = `
+	INSERT INTO room_categories (user_id, name, position, created_at)
+	VALUES ($1, $2, (SELECT COALESCE(MAX(position), -1) + 1 FROM room_categories WHERE user_id = $1), NOW())
+	RETURNING id, position, created_at
+	`
+
+	RenameRoomCategoryQuery = `UPDATE room_categories SET name = $3 WHERE id = $2 AND user_id = $1`
+
+	DeleteRoomCategoryQuery = `DELETE FROM room_categories WHERE id = $2 AND user_id = $1`
+
+	SetRoomCategoryPositionQuery = `UPDATE room_categories SET position = $3 WHERE id = $2 AND user_id = $1`
+
+	SetChatRoomCategoryQuery = `
+	INSERT INTO chat_room_member_settings (chat_room_id, user_id, category_id, position, updated_at)
+	VALUES ($1, $2, $3, $4, NOW())
+	ON CONFLICT (chat_room_id, user_id) DO UPDATE
+	SET category_id = $3, position = $4, updated_at = NOW()
+	`
+
+	GetChatRoomQuery = `
+	SELECT cr.id, COALESCE(cr.name, ''), COALESCE(cr.description, ''), COALESCE(cr.type, ''), cr.slow_mode_seconds, cr.retention_days, cr.message_ttl_seconds,
+		COALESCE(cr.avatar_path, ''), COALESCE(cr.banner_path, ''),
+		EXISTS (SELECT 1 FROM messages m WHERE m.chat_room_id = cr.id AND m.is_dm)
+	FROM chat_rooms cr
+	WHERE cr.id = $1
+	`
+
+	SetChatRoomAvatarQuery = `
+	UPDATE chat_rooms cr SET avatar_path = $2
+	FROM (SELECT id, avatar_path FROM chat_rooms WHERE id = $1 FOR UPDATE) old
+	WHERE cr.id = old.id
+	RETURNING COALESCE(old.avatar_path, '')
+	`
+
+	SetChatRoomBannerQuery = `
+	UPDATE chat_rooms cr SET banner_path = $2
+	FROM (SELECT id, banner_path FROM chat_rooms WHERE id = $1 FOR UPDATE) old
+	WHERE cr.id = old.id
+	RETURNING COALESCE(old.banner_path, '')
+	`
+
+	SetRetentionDaysQuery = `UPDATE chat_rooms SET retention_days = $2 WHERE id = $1`
+
+	GetRetentionPoliciesQuery = `SELECT id, retention_days FROM chat_rooms ORDER BY id`
+
+	PurgeReadMessagesQuery = `
+	DELETE FROM read_messages r
+	USING messages m
+	WHERE r.message_id = m.message_id AND r.chat_room_id = m.chat_room_id
+		AND m.chat_room_id = $1 AND m.timestamp < NOW()::timestamp - make_interval(days => $2)
+	`
+
+	// PurgeMessagesQuery returns the bucket path of each purged media message and NULL for the others
+	PurgeMessagesQuery = `
+	DELETE FROM messages
+	WHERE chat_room_id = $1 AND timestamp < NOW()::timestamp - make_interval(days => $2)
+	RETURNING CASE WHEN type = 'media' AND content <> '' THEN content END
+	`
+
+	scheduledMessageColumns = `id, chat_room_id, sender_id, content, type, is_dm, send_at, status, message_id, COALESCE(failure_reason, ''), created_at`
+
+	CreateScheduledMessageQuery = `
+	INSERT INTO scheduled_messages (chat_room_id, sender_id, content, type, is_dm, send_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING ` + scheduledMessageColumns
+
+	GetScheduledMessagesQuery = `
+	SELECT ` + scheduledMessageColumns + `
+	FROM scheduled_messages
+	WHERE sender_id = $1 AND status = 'pending' AND ($2 = 0 OR chat_room_id = $2)
+	ORDER BY send_at, id
+	`
+
+	CancelScheduledMessageQuery = `
+	UPDATE scheduled_messages SET status = 'cancelled'
+	WHERE id = $1 AND sender_id = $2 AND status = 'pending'
+	RETURNING ` + scheduledMessageColumns
+
+	// ClaimDueScheduledMessagesQuery also takes back messages that have been 'sending' for longer than
+	// $2 seconds, since the dispatcher that claimed them stopped before delivering them.
+	ClaimDueScheduledMessagesQuery = `
+	UPDATE scheduled_messages SET status = 'sending', claimed_at = NOW()
+	WHERE id IN (
+		SELECT id FROM scheduled_messages
+		WHERE (status = 'pending' AND send_at <= NOW())
+			OR (status = 'sending' AND (claimed_at IS NULL OR claimed_at < NOW() - make_interval(secs => $2)))
+		ORDER BY send_at, id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING ` + scheduledMessageColumns
+
+	MarkScheduledMessageSentQuery = `UPDATE scheduled_messages SET status = 'sent', message_id = $2 WHERE id = $1`
+
+	MarkScheduledMessageFailedQuery = `UPDATE scheduled_messages SET status = 'failed', failure_reason = $2 WHERE id = $1`
+
+	SetMessageTTLQuery = `UPDATE chat_rooms SET message_ttl_seconds = $2 WHERE id = $1`
+
+	// EraseExpiredMessagesQuery blanks the content of due disappearing messages, drops their pins
+	// and returns them with the content they had, so attached media can be removed from the bucket.
+	EraseExpiredMessagesQuery = `
+	WITH due AS (
+		SELECT message_id, chat_room_id, sender_id, content, COALESCE(type, '') AS type, is_dm, expires_at
+		FROM messages
+		WHERE expires_at <= NOW() AND type IS DISTINCT FROM 'expired'
+		ORDER BY expires_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	), erased AS (
+		UPDATE messages m SET content = '', type = 'expired'
+		FROM due
+		WHERE m.message_id = due.message_id AND m.chat_room_id = due.chat_room_id
+	), unpinned AS (
+		DELETE FROM pinned_messages p
+		USING due
+		WHERE p.message_id = due.message_id AND p.chat_room_id = due.chat_room_id
+	)
+	SELECT message_id, chat_room_id, sender_id, content, type, is_dm, expires_at FROM due
+	`
+
+	CreatePollQuery = `
+	INSERT INTO polls (chat_room_id, message_id, creator_id, question, multiple_choice, anonymous, closes_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING id, created_at
+	`
+
+	CreatePollOptionQuery = `INSERT INTO poll_options (poll_id, position, text) VALUES ($1, $2, $3) RETURNING id`
+
+	pollColumns = `id, chat_room_id, message_id, COALESCE(creator_id, 0), question, multiple_choice, anonymous, closes_at, closed_at, created_at`
+
+	GetPollQuery = `SELECT ` + pollColumns + ` FROM polls WHERE chat_room_id = $1 AND id = $2`
+
+	GetPollByMessageQuery = `SELECT ` + pollColumns + ` FROM polls WHERE chat_room_id = $1 AND message_id = $2`
+
+	GetPollOptionsQuery = `
+	SELECT o.id, o.text, COUNT(v.user_id),
+		COALESCE(array_agg(v.user_id ORDER BY v.voted_at) FILTER (WHERE v.user_id IS NOT NULL), '{}')
+	FROM poll_options o
+	LEFT JOIN poll_votes v ON v.option_id = o.id
+	WHERE o.poll_id = $1
+	GROUP BY o.id
+	ORDER BY o.position
+	`
+
+	CountPollVotersQuery = `SELECT COUNT(DISTINCT user_id) FROM poll_votes WHERE poll_id = $1`
+
+	GetUserPollVotesQuery = `SELECT option_id FROM poll_votes WHERE poll_id = $1 AND user_id = $2 ORDER BY option_id`
+
+	DeleteUserPollVotesQuery = `DELETE FROM poll_votes WHERE poll_id = $1 AND user_id = $2`
+
+	InsertPollVoteQuery = `INSERT INTO poll_votes (poll_id, option_id, user_id, voted_at) VALUES ($1, $2, $3, NOW())`
+
+	ClosePollQuery = `UPDATE polls SET closed_at = NOW() WHERE id = $1 AND closed_at IS NULL`
+
+	// SearchMessagesQuery escapes the content before highlighting so snippets are safe to render as HTML.
+	SearchMessagesQuery = `
+	SELECT m.message_id, m.chat_room_id, COALESCE(cr.name, ''), m.sender_id, u.username, COALESCE(u.name, ''), m.timestamp, COALESCE(m.type, ''),
+		ts_headline('simple', replace(replace(replace(m.content, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), query,
+			'StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter=" … "'),
+		ts_rank(m.content_tsv, query)
+	FROM messages m
+	JOIN chat_room_members crm ON crm.chat_room_id = m.chat_room_id AND crm.user_id = $1
+	JOIN chat_rooms cr ON cr.id = m.chat_room_id
+	JOIN users u ON u.id = m.sender_id,
+		websearch_to_tsquery('simple', $2) query
+	WHERE m.content_tsv @@ query
+		AND ($3::int = 0 OR m.chat_room_id = $3)
+		AND ($4::int = 0 OR m.sender_id = $4)
+		AND ($5::timestamp IS NULL OR m.timestamp >= $5)
+		AND ($6::timestamp IS NULL OR m.timestamp < $6)
+		AND ($7 = '' OR COALESCE(m.type, '') = $7)
+		AND ($8::timestamp IS NULL OR (m.timestamp, m.chat_room_id, m.message_id) < ($8, $9::int, $10::int))
+	ORDER BY m.timestamp DESC, m.chat_room_id DESC, m.message_id DESC
+	LIMIT $11
+	`
+
+	SaveBookmarkQuery = `
+	INSERT INTO bookmarks (user_id, chat_room_id, message_id, note, created_at)
+	VALUES ($1, $2, $3, $4, NOW())
+	ON CONFLICT (user_id, chat_room_id, message_id) DO UPDATE SET note = $4
+	RETURNING id, created_at
+	`
+
+	DeleteBookmarkQuery = `DELETE FROM bookmarks WHERE id = $2 AND user_id = $1`
+
+	GetBookmarksQuery = `
+	SELECT b.id, b.chat_room_id, b.message_id, b.note, b.created_at,
+		COALESCE(cr.name, ''), COALESCE(cr.type, ''), crm.user_id IS NOT NULL,
+		m.message_id IS NOT NULL, m.sender_id, u.username, u.name, m.content, m.timestamp, m.is_dm, m.type
+	FROM bookmarks b
+	LEFT JOIN chat_rooms cr ON cr.id = b.chat_room_id
+	LEFT JOIN chat_room_members crm ON crm.chat_room_id = b.chat_room_id AND crm.user_id = b.user_id
+	LEFT JOIN messages m ON m.chat_room_id = b.chat_room_id AND m.message_id = b.message_id
+	LEFT JOIN users u ON u.id = m.sender_id
+	WHERE b.user_id = $1
+	ORDER BY b.created_at DESC, b.id DESC
+	LIMIT $2 OFFSET $3
+	`
+
+	GetDraftQuery = `SELECT content, updated_at FROM message_drafts WHERE chat_room_id = $1 AND user_id = $2`
+
+	UpsertDraftQuery = `
+	INSERT INTO message_drafts (chat_room_id, user_id, content, updated_at)
+	VALUES ($1, $2, $3, NOW())
+	ON CONFLICT (chat_room_id, user_id) DO UPDATE SET content = $3, updated_at = NOW()
+	RETURNING updated_at
+	`
+
+	DeleteDraftQuery = `DELETE FROM message_drafts WHERE chat_room_id = $1 AND user_id = $2`
+
+	GetUserByUsernameQuery = `
+	SELECT id, username, COALESCE(name, ''), email, COALESCE(profile_picture, ''), password, created_at, email_verified_at
+	FROM users WHERE username = $1
+	`
+
+	GetUserByIdentityQuery = `
+	SELECT u.id, u.username, COALESCE(u.name, ''), u.email, COALESCE(u.profile_picture, ''), u.password, u.created_at, u.email_verified_at
+	FROM user_identities ui
+	JOIN users u ON u.id = ui.user_id
+	WHERE ui.provider = $1 AND ui.provider_user_id = $2
+	`
+
+	GetUnlinkedUserByEmailQuery = `
+	SELECT u.id, u.username, COALESCE(u.name, ''), u.email, COALESCE(u.profile_picture, ''), u.password, u.created_at, u.email_verified_at
+	FROM users u
+	WHERE u.email = $1 AND NOT EXISTS (SELECT 1 FROM user_identities ui WHERE ui.user_id = u.id)
+	`
+
+	CreateOAuthUserQuery = `
+	INSERT INTO users (username, name, password, email, profile_picture, last_seen, created_at)
+	VALUES ('system_default', $1, 'system_default', $2, $3, NOW(), NOW())
+	RETURNING id, username, password, created_at
+	`
+
+	LinkIdentityQuery = `
+	INSERT INTO user_identities (user_id, provider, provider_user_id, email)
+	VALUES ($1, $2, $3, NULLIF($4, ''))
+	RETURNING id, linked_at
+	`
+
+	GetIdentitiesQuery = `
+	SELECT id, user_id, provider, provider_user_id, COALESCE(email, ''), linked_at
+	FROM user_identities
+	WHERE user_id = $1
+	ORDER BY linked_at, id
+	`
+
+	// An identity can only be unlinked while the user can still sign in some other way.
+	UnlinkIdentityQuery = `
+	DELETE FROM user_identities ui
+	WHERE ui.user_id = $1 AND ui.provider = $2
+	AND (
+		EXISTS (SELECT 1 FROM user_identities other WHERE other.user_id = ui.user_id AND other.id <> ui.id)
+		OR EXISTS (SELECT 1 FROM users u WHERE u.id = ui.user_id AND u.password <> 'system_default')
+	)
+	`
+
+	UpdateLastSeenQuery = `UPDATE users SET last_seen = NOW() WHERE id = $1`
+
+	LoadSessionQuery = `
+	SELECT id, data, last_seen_at < NOW() - make_interval(secs => $2)
+	FROM user_sessions
+	WHERE token_hash = $1 AND expires_at > NOW()
+	`
+
+	TouchSessionQuery = `UPDATE user_sessions SET last_seen_at = NOW(), ip = $2 WHERE id = $1`
+
+	CreateSessionQuery = `
+	INSERT INTO user_sessions (token_hash, user_id, data, user_agent, ip, expires_at)
+	VALUES ($1, $2, $3, $4, $5, NOW() + make_interval(secs => $6))
+	RETURNING id
+	`
+
+	UpdateSessionQuery = `
+	UPDATE user_sessions SET user_id = $2, data = $3, expires_at = NOW() + make_interval(secs => $4)
+	WHERE token_hash = $1 AND expires_at > NOW()
+	RETURNING id
+	`
+
+	DeleteSessionQuery = `DELETE FROM user_sessions WHERE token_hash = $1`
+
+	DeleteExpiredSessionsQuery = `DELETE FROM user_sessions WHERE expires_at <= NOW()`
+
+	GetSessionsQuery = `
+	SELECT id, user_agent, ip, created_at, last_seen_at, expires_at
+	FROM user_sessions
+	WHERE user_id = $1 AND expires_at > NOW()
+	ORDER BY last_seen_at DESC, id DESC
+	`
+
+	RevokeSessionQuery = `DELETE FROM user_sessions WHERE id = $1 AND user_id = $2`
+
+	RevokeSessionsQuery = `DELETE FROM user_sessions WHERE user_id = $1 AND id <> $2 RETURNING id`
+
+	CreateAccessTokenQuery = `
+	INSERT INTO access_tokens (user_id, name, token_hash, prefix, scopes, expires_at)
+	VALUES ($1, $2, $3, $4, $5, NOW() + make_interval(days => $6))
+	RETURNING id, created_at, expires_at
+	`
+
+	GetAccessTokensQuery = `
+	SELECT id, user_id, name, prefix, scopes, created_at, expires_at, last_used_at
+	FROM access_tokens
+	WHERE user_id = $1 AND expires_at > NOW()
+	ORDER BY created_at DESC, id DESC
+	`
+
+	GetActiveAccessTokenQuery = `
+	SELECT id, user_id, name, prefix, scopes, created_at, expires_at, last_used_at,
+		last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute'
+	FROM access_tokens
+	WHERE token_hash = $1 AND expires_at > NOW()
+	`
+
+	TouchAccessTokenQuery = `UPDATE access_tokens SET last_used_at = NOW() WHERE id = $1`
+
+	RevokeAccessTokenQuery = `DELETE FROM access_tokens WHERE id = $1 AND user_id = $2`
+
+	RevokeAccessTokensQuery = `DELETE FROM access_tokens WHERE user_id = $1 RETURNING id`
+
+	GetTOTPQuery = `
+	SELECT user_id, secret, last_used_step, confirmed_at
+	FROM user_totp
+	WHERE user_id = $1
+	`
+
+	SaveTOTPSecretQuery = `
+	INSERT INTO user_totp (user_id, secret)
+	VALUES ($1, $2)
+	ON CONFLICT (user_id) DO UPDATE
+	SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+	WHERE user_totp.confirmed_at IS NULL
+	`
+
+	UseTOTPStepQuery = `UPDATE user_totp SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2`
+
+	ConfirmTOTPQuery = `
+	UPDATE user_totp SET confirmed_at = NOW(), last_used_step = $2
+	WHERE user_id = $1 AND confirmed_at IS NULL AND last_used_step < $2
+	`
+
+	DeleteTOTPQuery = `DELETE FROM user_totp WHERE user_id = $1`
+
+	DeleteRecoveryCodesQuery = `DELETE FROM totp_recovery_codes WHERE user_id = $1`
+
+	CreateRecoveryCodeQuery = `INSERT INTO totp_recovery_codes (user_id, code_hash) VALUES ($1, $2)`
+
+	UseRecoveryCodeQuery = `
+	UPDATE totp_recovery_codes SET used_at = NOW()
+	WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	CountRecoveryCodesQuery = `SELECT COUNT(*) FROM totp_recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+
+	GetUserByIDQuery = `
+	SELECT id, username, COALESCE(name, ''), email, COALESCE(profile_picture, ''), password, created_at, email_verified_at
+	FROM users WHERE id = $1
+	`
+
+	GetUserByEmailQuery = `
+	SELECT id, username, COALESCE(name, ''), email, COALESCE(profile_picture, ''), password, created_at, email_verified_at
+	FROM users WHERE LOWER(email) = LOWER($1)
+	`
+
+	UpdatePasswordQuery = `UPDATE users SET password = $3 WHERE id = $1 AND password = $2`
+
+	MarkEmailVerifiedQuery = `
+	UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW())
+	WHERE id = $1 AND email = $2
+	`
+
+	UpdateProfileQuery = `
+	UPDATE users SET name = $2, username = $3, email = $4,
+		email_verified_at = CASE WHEN LOWER(email) = LOWER($4) THEN email_verified_at END
+	WHERE id = $1
+	RETURNING id, username, COALESCE(name, ''), email, COALESCE(profile_picture, ''), password, created_at, email_verified_at
+	`
+
+	SetProfilePictureQuery = `
+	UPDATE users SET profile_picture = $2
+	WHERE id = $1
+	RETURNING id, username, COALESCE(name, ''), email, COALESCE(profile_picture, ''), password, created_at, email_verified_at
+	`
+
+	GetStatusSettingsQuery = `
+	SELECT user_id, text, emoji, expires_at, dnd_enabled, dnd_until, dnd_start, dnd_end, dnd_time_zone
+	FROM user_status WHERE user_id = $1
+	`
+
+	GetStatusSettingsForUsersQuery = `
+	SELECT user_id, text, emoji, expires_at, dnd_enabled, dnd_until, dnd_start, dnd_end, dnd_time_zone
+	FROM user_status WHERE user_id = ANY($1)
+	`
+
+	SetStatusQuery = `
+	INSERT INTO user_status (user_id, text, emoji, expires_at, updated_at)
+	VALUES ($1, $2, $3, $4, NOW())
+	ON CONFLICT (user_id) DO UPDATE
+	SET text = $2, emoji = $3, expires_at = $4, updated_at = NOW()
+	`
+
+	SetDoNotDisturbQuery = `
+	INSERT INTO user_status (user_id, dnd_enabled, dnd_until, dnd_start, dnd_end, dnd_time_zone, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, NOW())
+	ON CONFLICT (user_id) DO UPDATE
+	SET dnd_enabled = $2, dnd_until = $3, dnd_start = $4, dnd_end = $5, dnd_time_zone = $6, updated_at = NOW()
+	`
+
+	GetNotificationTargetsQuery = `
+	SELECT u.id, u.username, COALESCE(s.notification_level, 'all'), s.muted_until,
+		COALESCE(st.dnd_enabled, false), st.dnd_until, COALESCE(st.dnd_start, ''), COALESCE(st.dnd_end, ''), COALESCE(st.dnd_time_zone, '')
+	FROM chat_room_members crm
+	JOIN users u ON u.id = crm.user_id
+	LEFT JOIN chat_room_member_settings s ON s.chat_room_id = crm.chat_room_id AND s.user_id = crm.user_id
+	LEFT JOIN user_status st ON st.user_id = crm.user_id
+	WHERE crm.chat_room_id = $1
+	`
 )
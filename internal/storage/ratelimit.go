@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+func (r *PostgresRepository) SetSlowMode(ctx context.Context, chatRoomID uint, seconds int) error {
+	_, err := r.DB.Exec(ctx, SetSlowModeQuery, chatRoomID, seconds)
+	return err
+}
+
+// GetSlowModeState returns the slow mode interval of the chat room and how long ago the user last
+// posted in it. sinceLastMessage is negative when the user has never posted in the room.
+func (r *PostgresRepository) GetSlowModeState(ctx context.Context, chatRoomID, userID uint) (time.Duration, time.Duration, error) {
+	var slowModeSeconds int
+	var secondsSinceLast sql.NullFloat64
+	err := r.DB.QueryRow(ctx, GetSlowModeStateQuery, chatRoomID, userID).Scan(&slowModeSeconds, &secondsSinceLast)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	sinceLastMessage := time.Duration(-1)
+	if secondsSinceLast.Valid {
+		sinceLastMessage = time.Duration(secondsSinceLast.Float64 * float64(time.Second))
+	}
+	return time.Duration(slowModeSeconds) * time.Second, sinceLastMessage, nil
+}
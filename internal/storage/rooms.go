@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/kontentski/chat/internal/models"
+)
+
+// GetChatRoom returns a chat room by ID, or pgx.ErrNoRows if it does not exist.
+func (r *PostgresRepository) GetChatRoom(ctx context.Context, chatRoomID uint) (*models.ChatRooms, error) {
+	var room models.ChatRooms
+	var retentionDays, messageTTL sql.NullInt32
+	err := r.DB.QueryRow(ctx, GetChatRoomQuery, chatRoomID).Scan(
+		&room.ID,
+		&room.Name,
+		&room.Description,
+		&room.Type,
+		&room.SlowModeSeconds,
+		&retentionDays,
+		&messageTTL,
+		&room.AvatarPath,
+		&room.BannerPath,
+		&room.IsDM,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if retentionDays.Valid {
+		days := int(retentionDays.Int32)
+		room.RetentionDays = &days
+	}
+	if messageTTL.Valid {
+		seconds := int(messageTTL.Int32)
+		room.MessageTTLSeconds = &seconds
+	}
+	return &room, nil
+}
+
+// SetChatRoomImage stores the bucket path of the room's avatar or banner and returns the path it
+// replaced, empty if the room had none.
+func (r *PostgresRepository) SetChatRoomImage(ctx context.Context, chatRoomID uint, kind, filePath string) (string, error) {
+	var query string
+	switch kind {
+	case models.RoomImageAvatar:
+		query = SetChatRoomAvatarQuery
+	case models.RoomImageBanner:
+		query = SetChatRoomBannerQuery
+	default:
+		return "", fmt.Errorf("unknown room image kind: %s", kind)
+	}
+
+	var previous string
+	err := r.DB.QueryRow(ctx, query, chatRoomID, filePath).Scan(&previous)
+	return previous, err
+}
+
+// SetRetentionDays sets how many days messages are kept in the room. nil falls back to the global default
+// and 0 keeps messages forever.
+func (r *PostgresRepository) SetRetentionDays(ctx context.Context, chatRoomID uint, days *int) error {
+	_, err := r.DB.Exec(ctx, SetRetentionDaysQuery, chatRoomID, days)
+	return err
+}
+
+// GetRetentionPolicies returns the retention setting of every chat room, nil where the room uses the default.
+func (r *PostgresRepository) GetRetentionPolicies(ctx context.Context) (map[uint]*int, error) {
+	rows, err := r.DB.Query(ctx, GetRetentionPoliciesQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := make(map[uint]*int)
+	for rows.Next() {
+		var chatRoomID uint
+		var retentionDays sql.NullInt32
+		if err := rows.Scan(&chatRoomID, &retentionDays); err != nil {
+			return nil, err
+		}
+		policies[chatRoomID] = nil
+		if retentionDays.Valid {
+			days := int(retentionDays.Int32)
+			policies[chatRoomID] = &days
+		}
+	}
+
+	return policies, rows.Err()
+}
+
+// PurgeMessagesOlderThan deletes the room's messages older than the given number of days together
+// with their read receipts, and returns the bucket paths of the deleted media messages. The cutoff is
+// computed by the database so it matches how timestamps are stored.
+func (r *PostgresRepository) PurgeMessagesOlderThan(ctx context.Context, chatRoomID uint, days int) (*models.PurgedMessages, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error: Failed to start transaction %w ", err)
+	}
+	defer tx.Rollback(ctx)
+
+	readTag, err := tx.Exec(ctx, PurgeReadMessagesQuery, chatRoomID, days)
+	if err != nil {
+		return nil, err
+	}
+
+	purged := &models.PurgedMessages{ReadReceipts: readTag.RowsAffected()}
+	rows, err := tx.Query(ctx, PurgeMessagesQuery, chatRoomID, days)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var media sql.NullString
+		if err := rows.Scan(&media); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		purged.Messages++
+		if media.Valid {
+			purged.Media = append(purged.Media, media.String)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("error: Failed to commit transaction %w ", err)
+	}
+	return purged, nil
+}
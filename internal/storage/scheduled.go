@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+)
+
+// CreateScheduledMessage stores a pending message and fills in its ID, status and creation time.
+func (r *PostgresRepository) CreateScheduledMessage(ctx context.Context, scheduled *models.ScheduledMessage) error {
+	created, err := scanScheduledMessage(r.DB.QueryRow(ctx, CreateScheduledMessageQuery,
+		scheduled.ChatRoomID,
+		scheduled.SenderID,
+		scheduled.Content,
+		scheduled.Type,
+		scheduled.IsDM,
+		scheduled.SendAt,
+	))
+	if err != nil {
+		return err
+	}
+	*scheduled = *created
+	return nil
+}
+
+// GetScheduledMessages returns the sender's pending messages, in the given chat room or in all rooms when chatRoomID is 0.
+func (r *PostgresRepository) GetScheduledMessages(ctx context.Context, senderID, chatRoomID uint) ([]models.ScheduledMessage, error) {
+	rows, err := r.DB.Query(ctx, GetScheduledMessagesQuery, senderID, chatRoomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return collectScheduledMessages(rows)
+}
+
+// CancelScheduledMessage cancels a pending message of the sender, or returns pgx.ErrNoRows if there is none.
+func (r *PostgresRepository) CancelScheduledMessage(ctx context.Context, scheduledID, senderID uint) (*models.ScheduledMessage, error) {
+	return scanScheduledMessage(r.DB.QueryRow(ctx, CancelScheduledMessageQuery, scheduledID, senderID))
+}
+
+// ClaimDueScheduledMessages marks up to limit due messages as being sent and returns them.
+// Rows locked by another dispatcher are skipped, so each message is claimed only once. Messages
+// claimed more than staleAfter ago and still not sent are claimed again.
+func (r *PostgresRepository) ClaimDueScheduledMessages(ctx context.Context, limit int, staleAfter time.Duration) ([]models.ScheduledMessage, error) {
+	rows, err := r.DB.Query(ctx, ClaimDueScheduledMessagesQuery, limit, staleAfter.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return collectScheduledMessages(rows)
+}
+
+func (r *PostgresRepository) MarkScheduledMessageFailed(ctx context.Context, scheduledID uint, reason string) error {
+	_, err := r.DB.Exec(ctx, MarkScheduledMessageFailedQuery, scheduledID, reason)
+	return err
+}
+
+func collectScheduledMessages(rows pgx.Rows) ([]models.ScheduledMessage, error) {
+	var messages []models.ScheduledMessage
+	for rows.Next() {
+		scheduled, err := scanScheduledMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, *scheduled)
+	}
+	return messages, rows.Err()
+}
+
+func scanScheduledMessage(row pgx.Row) (*models.ScheduledMessage, error) {
+	var scheduled models.ScheduledMessage
+	var messageID *int32
+
+	if err := row.Scan(
+		&scheduled.ID,
+		&scheduled.ChatRoomID,
+		&scheduled.SenderID,
+		&scheduled.Content,
+		&scheduled.Type,
+		&scheduled.IsDM,
+		&scheduled.SendAt,
+		&scheduled.Status,
+		&messageID,
+		&scheduled.FailureReason,
+		&scheduled.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	if messageID != nil {
+		id := uint(*messageID)
+		scheduled.MessageID = &id
+	}
+	return &scheduled, nil
+}
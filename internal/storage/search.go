@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/kontentski/chat/internal/models"
+)
+
+// SearchMessages runs a full-text search over the messages of the rooms userID belongs to, newest first.
+func (r *PostgresRepository) SearchMessages(ctx context.Context, userID uint, filter models.MessageSearchFilter) ([]models.MessageSearchResult, error) {
+	var afterTimestamp *time.Time
+	var afterChatRoomID, afterMessageID uint
+	if filter.After != nil {
+		afterTimestamp = &filter.After.Timestamp
+		afterChatRoomID = filter.After.ChatRoomID
+		afterMessageID = filter.After.MessageID
+	}
+
+	rows, err := r.DB.Query(ctx, SearchMessagesQuery,
+		userID,
+		filter.Query,
+		filter.ChatRoomID,
+		filter.SenderID,
+		filter.From,
+		filter.To,
+		filter.Type,
+		afterTimestamp,
+		afterChatRoomID,
+		afterMessageID,
+		filter.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.MessageSearchResult
+	for rows.Next() {
+		var result models.MessageSearchResult
+		if err := rows.Scan(
+			&result.MessageID,
+			&result.ChatRoomID,
+			&result.ChatRoomName,
+			&result.Sender.ID,
+			&result.Sender.Username,
+			&result.Sender.Name,
+			&result.Timestamp,
+			&result.Type,
+			&result.Snippet,
+			&result.Rank,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
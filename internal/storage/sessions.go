@@ -0,0 +1,315 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+)
+
+// SessionIDKey holds the database ID of a loaded session in its Values. It is not persisted.
+const SessionIDKey = "sessionID"
+
+// sessionUserIDKey is the session value the user of a session is read from.
+const sessionUserIDKey = "userID"
+
+// defaultSessionAge is how long sessions without a MaxAge are kept on the server.
+const defaultSessionAge = 86400
+
+var ErrSessionRevoked = errors.New("session was revoked or has expired")
+
+// SessionBackend persists the sessions of a SessionStore by the SHA-256 hash of their token.
+type SessionBackend interface {
+	// LoadSession returns an unexpired session, or pgx.ErrNoRows. Stale reports that its last
+	// activity is older than touchAfter.
+	LoadSession(ctx context.Context, tokenHash []byte, touchAfter time.Duration) (id uint, data []byte, stale bool, err error)
+	TouchSession(ctx context.Context, id uint, ip string) error
+	CreateSession(ctx context.Context, tokenHash []byte, userID *uint, data []byte, userAgent, ip string, maxAge int) (uint, error)
+	// UpdateSession returns pgx.ErrNoRows if the session was revoked or has expired.
+	UpdateSession(ctx context.Context, tokenHash []byte, userID *uint, data []byte, maxAge int) (uint, error)
+	DeleteSession(ctx context.Context, tokenHash []byte) error
+}
+
+// SessionStore is a gorilla sessions.Store that keeps session values on the server. The cookie
+// only carries a signed random token, and the backend stores the token's SHA-256 hash, so deleting
+// a session revokes it even if its cookie is still around.
+type SessionStore struct {
+	Backend SessionBackend
+	Codecs  []securecookie.Codec
+	Options *sessions.Options
+	// TouchInterval is how often the last activity of a session is written.
+	TouchInterval time.Duration
+}
+
+func NewSessionStore(backend SessionBackend, keyPairs ...[]byte) *SessionStore {
+	return &SessionStore{
+		Backend: backend,
+		Codecs:  securecookie.CodecsFromPairs(keyPairs...),
+		Options: &sessions.Options{
+			Path:     "/",
+			MaxAge:   86400 * 30,
+			HttpOnly: true,
+		},
+		TouchInterval: time.Minute,
+	}
+}
+
+// MaxAge sets the maximum age of the cookies and of the sessions stored on the server.
+func (s *SessionStore) MaxAge(age int) {
+	s.Options.MaxAge = age
+	for _, codec := range s.Codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(age)
+		}
+	}
+}
+
+func (s *SessionStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New loads the session named by the request cookie. A missing, invalid, expired or revoked
+// session gives a new empty session rather than an error.
+func (s *SessionStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	options := *s.Options
+	session.Options = &options
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	var token string
+	if err := securecookie.DecodeMulti(name, cookie.Value, &token, s.Codecs...); err != nil {
+		return session, nil
+	}
+
+	id, data, stale, err := s.Backend.LoadSession(r.Context(), hashToken(token), s.TouchInterval)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return session, nil
+	}
+	if err != nil {
+		return session, err
+	}
+	if err := (securecookie.GobEncoder{}).Deserialize(data, &session.Values); err != nil {
+		return session, err
+	}
+
+	session.ID = token
+	session.IsNew = false
+	session.Values[SessionIDKey] = id
+
+	if stale {
+		if err := s.Backend.TouchSession(r.Context(), id, requestIP(r)); err != nil {
+			return session, err
+		}
+	}
+	return session, nil
+}
+
+// Save stores the session and writes its cookie. A negative MaxAge deletes the session.
+func (s *SessionStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := s.Backend.DeleteSession(r.Context(), hashToken(session.ID)); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	values := make(map[interface{}]interface{}, len(session.Values))
+	for k, v := range session.Values {
+		if k != SessionIDKey {
+			values[k] = v
+		}
+	}
+	data, err := (securecookie.GobEncoder{}).Serialize(values)
+	if err != nil {
+		return err
+	}
+
+	var userID *uint
+	if id, ok := session.Values[sessionUserIDKey].(uint); ok {
+		userID = &id
+	}
+	maxAge := session.Options.MaxAge
+	if maxAge == 0 {
+		maxAge = defaultSessionAge
+	}
+
+	var id uint
+	if session.ID == "" {
+		token, err := newSessionToken()
+		if err != nil {
+			return err
+		}
+		id, err = s.Backend.CreateSession(r.Context(), hashToken(token), userID, data, r.UserAgent(), requestIP(r), maxAge)
+		if err != nil {
+			return err
+		}
+		session.ID = token
+	} else {
+		id, err = s.Backend.UpdateSession(r.Context(), hashToken(session.ID), userID, data, maxAge)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrSessionRevoked
+		}
+		if err != nil {
+			return err
+		}
+	}
+	session.Values[SessionIDKey] = id
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// Renew deletes the stored session and empties it, so that the next Save starts a session with a
+// new token. It is used on sign-in so a token known before signing in never becomes authenticated.
+func (s *SessionStore) Renew(ctx context.Context, session *sessions.Session) error {
+	if session.ID != "" {
+		if err := s.Backend.DeleteSession(ctx, hashToken(session.ID)); err != nil {
+			return err
+		}
+	}
+	session.ID = ""
+	session.IsNew = true
+	session.Values = make(map[interface{}]interface{})
+	return nil
+}
+
+func newSessionToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+type clientIPKey struct{}
+
+// WithClientIP records the client address sessions are created and touched from. The router sets
+// it from gin's ClientIP, which only believes X-Forwarded-For from trusted proxies.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// requestIP returns the client address recorded by WithClientIP, or the peer address of the request.
+func requestIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func (r *PostgresRepository) LoadSession(ctx context.Context, tokenHash []byte, touchAfter time.Duration) (uint, []byte, bool, error) {
+	var (
+		id    uint
+		data  []byte
+		stale bool
+	)
+	err := r.DB.QueryRow(ctx, LoadSessionQuery, tokenHash, touchAfter.Seconds()).Scan(&id, &data, &stale)
+	return id, data, stale, err
+}
+
+func (r *PostgresRepository) TouchSession(ctx context.Context, id uint, ip string) error {
+	_, err := r.DB.Exec(ctx, TouchSessionQuery, id, ip)
+	return err
+}
+
+func (r *PostgresRepository) CreateSession(ctx context.Context, tokenHash []byte, userID *uint, data []byte, userAgent, ip string, maxAge int) (uint, error) {
+	var id uint
+	err := r.DB.QueryRow(ctx, CreateSessionQuery, tokenHash, userID, data, userAgent, ip, float64(maxAge)).Scan(&id)
+	return id, err
+}
+
+func (r *PostgresRepository) UpdateSession(ctx context.Context, tokenHash []byte, userID *uint, data []byte, maxAge int) (uint, error) {
+	var id uint
+	err := r.DB.QueryRow(ctx, UpdateSessionQuery, tokenHash, userID, data, float64(maxAge)).Scan(&id)
+	return id, err
+}
+
+func (r *PostgresRepository) DeleteSession(ctx context.Context, tokenHash []byte) error {
+	_, err := r.DB.Exec(ctx, DeleteSessionQuery, tokenHash)
+	return err
+}
+
+// DeleteExpiredSessions removes the sessions that have expired and returns how many there were.
+func (r *PostgresRepository) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	tag, err := r.DB.Exec(ctx, DeleteExpiredSessionsQuery)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetSessions returns the user's active sessions, most recently used first.
+func (r *PostgresRepository) GetSessions(ctx context.Context, userID uint) ([]models.Session, error) {
+	rows, err := r.DB.Query(ctx, GetSessionsQuery, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userSessions := []models.Session{}
+	for rows.Next() {
+		var session models.Session
+		if err := rows.Scan(&session.ID, &session.Device, &session.IP, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt); err != nil {
+			return nil, err
+		}
+		userSessions = append(userSessions, session)
+	}
+	return userSessions, rows.Err()
+}
+
+// RevokeSession deletes one of the user's sessions and reports whether it existed.
+func (r *PostgresRepository) RevokeSession(ctx context.Context, userID, sessionID uint) (bool, error) {
+	tag, err := r.DB.Exec(ctx, RevokeSessionQuery, sessionID, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// RevokeSessions deletes every session of the user except exceptID, and returns the IDs of the deleted sessions.
+func (r *PostgresRepository) RevokeSessions(ctx context.Context, userID, exceptID uint) ([]uint, error) {
+	rows, err := r.DB.Query(ctx, RevokeSessionsQuery, userID, exceptID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revoked := []uint{}
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		revoked = append(revoked, id)
+	}
+	return revoked, rows.Err()
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+)
+
+// GetChatRoomSettings returns the member's settings for the chat room, or the defaults if they never changed any.
+func (r *PostgresRepository) GetChatRoomSettings(ctx context.Context, chatRoomID, userID uint) (*models.ChatRoomMemberSettings, error) {
+	settings := models.ChatRoomMemberSettings{NotificationLevel: models.NotificationAll}
+	var mutedUntil sql.NullTime
+
+	err := r.DB.QueryRow(ctx, GetChatRoomSettingsQuery, chatRoomID, userID).Scan(
+		&mutedUntil,
+		&settings.Favorite,
+		&settings.Archived,
+		&settings.NotificationLevel,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &settings, nil
+		}
+		return nil, err
+	}
+	if mutedUntil.Valid {
+		settings.MutedUntil = &mutedUntil.Time
+	}
+	return &settings, nil
+}
+
+func (r *PostgresRepository) UpsertChatRoomSettings(ctx context.Context, chatRoomID, userID uint, settings *models.ChatRoomMemberSettings) error {
+	_, err := r.DB.Exec(ctx, UpsertChatRoomSettingsQuery,
+		chatRoomID,
+		userID,
+		settings.MutedUntil,
+		settings.Favorite,
+		settings.Archived,
+		settings.NotificationLevel,
+	)
+	return err
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+)
+
+// dndColumns are scanned into a DoNotDisturb by doNotDisturb.
+type dndColumns struct {
+	enabled  bool
+	until    sql.NullTime
+	start    string
+	end      string
+	timeZone string
+}
+
+func (d *dndColumns) targets() []interface{} {
+	return []interface{}{&d.enabled, &d.until, &d.start, &d.end, &d.timeZone}
+}
+
+func (d *dndColumns) doNotDisturb() models.DoNotDisturb {
+	dnd := models.DoNotDisturb{Enabled: d.enabled}
+	if d.until.Valid {
+		dnd.Until = &d.until.Time
+	}
+	if d.start != "" {
+		dnd.Schedule = &models.DNDSchedule{Start: d.start, End: d.end, TimeZone: d.timeZone}
+	}
+	return dnd
+}
+
+func scanStatusSettings(row pgx.Row) (*models.StatusSettings, error) {
+	var settings models.StatusSettings
+	var expiresAt sql.NullTime
+	var dnd dndColumns
+	err := row.Scan(append([]interface{}{&settings.UserID, &settings.Text, &settings.Emoji, &expiresAt}, dnd.targets()...)...)
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		settings.ExpiresAt = &expiresAt.Time
+	}
+	settings.DoNotDisturb = dnd.doNotDisturb()
+	return &settings, nil
+}
+
+// GetStatusSettings returns the user's status and do-not-disturb settings, or empty ones if they never set any.
+func (r *PostgresRepository) GetStatusSettings(ctx context.Context, userID uint) (*models.StatusSettings, error) {
+	settings, err := scanStatusSettings(r.DB.QueryRow(ctx, GetStatusSettingsQuery, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &models.StatusSettings{UserID: userID}, nil
+	}
+	return settings, err
+}
+
+// GetStatusSettingsForUsers returns the stored statuses of those of the users who set one.
+func (r *PostgresRepository) GetStatusSettingsForUsers(ctx context.Context, userIDs []uint) ([]models.StatusSettings, error) {
+	ids := make([]int64, len(userIDs))
+	for i, id := range userIDs {
+		ids[i] = int64(id)
+	}
+	rows, err := r.DB.Query(ctx, GetStatusSettingsForUsersQuery, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statuses []models.StatusSettings
+	for rows.Next() {
+		settings, err := scanStatusSettings(rows)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, *settings)
+	}
+	return statuses, rows.Err()
+}
+
+// SetStatus replaces the user's status text, emoji and expiry, keeping their do-not-disturb settings.
+func (r *PostgresRepository) SetStatus(ctx context.Context, userID uint, text, emoji string, expiresAt *time.Time) error {
+	_, err := r.DB.Exec(ctx, SetStatusQuery, userID, text, emoji, expiresAt)
+	return err
+}
+
+// SetDoNotDisturb replaces the user's do-not-disturb settings, keeping their status.
+func (r *PostgresRepository) SetDoNotDisturb(ctx context.Context, userID uint, dnd models.DoNotDisturb) error {
+	var start, end, timeZone string
+	if dnd.Schedule != nil {
+		start, end, timeZone = dnd.Schedule.Start, dnd.Schedule.End, dnd.Schedule.TimeZone
+	}
+	_, err := r.DB.Exec(ctx, SetDoNotDisturbQuery, userID, dnd.Enabled, dnd.Until, start, end, timeZone)
+	return err
+}
+
+// GetNotificationTargets returns the members of the chat room with their notification settings for it
+// and their do-not-disturb settings.
+func (r *PostgresRepository) GetNotificationTargets(ctx context.Context, chatRoomID uint) ([]models.NotificationTarget, error) {
+	rows, err := r.DB.Query(ctx, GetNotificationTargetsQuery, chatRoomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var targets []models.NotificationTarget
+	for rows.Next() {
+		var target models.NotificationTarget
+		var mutedUntil sql.NullTime
+		var dnd dndColumns
+		err := rows.Scan(append([]interface{}{&target.UserID, &target.Username, &target.NotificationLevel, &mutedUntil}, dnd.targets()...)...)
+		if err != nil {
+			return nil, err
+		}
+		if mutedUntil.Valid {
+			target.MutedUntil = &mutedUntil.Time
+		}
+		target.DoNotDisturb = dnd.doNotDisturb()
+		targets = append(targets, target)
+	}
+	return targets, rows.Err()
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/kontentski/chat/internal/models"
+)
+
+// CreateAccessToken stores a new access token for token.UserID, keeping only the hash of secret.
+// The token expires after expiresInDays.
+func (r *PostgresRepository) CreateAccessToken(ctx context.Context, token *models.AccessToken, secret string, expiresInDays int) error {
+	return r.DB.QueryRow(ctx, CreateAccessTokenQuery, token.UserID, token.Name, hashToken(secret), token.Prefix, token.Scopes, expiresInDays).
+		Scan(&token.ID, &token.CreatedAt, &token.ExpiresAt)
+}
+
+// GetAccessTokens returns the user's unexpired access tokens, newest first.
+func (r *PostgresRepository) GetAccessTokens(ctx context.Context, userID uint) ([]models.AccessToken, error) {
+	rows, err := r.DB.Query(ctx, GetAccessTokensQuery, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.AccessToken{}
+	for rows.Next() {
+		var token models.AccessToken
+		if err := rows.Scan(&token.ID, &token.UserID, &token.Name, &token.Prefix, &token.Scopes, &token.CreatedAt, &token.ExpiresAt, &token.LastUsedAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// UseAccessToken returns the unexpired access token with the secret and records that it was used,
+// or pgx.ErrNoRows if there is none.
+func (r *PostgresRepository) UseAccessToken(ctx context.Context, secret string) (*models.AccessToken, error) {
+	var (
+		token models.AccessToken
+		stale bool
+	)
+	err := r.DB.QueryRow(ctx, GetActiveAccessTokenQuery, hashToken(secret)).Scan(
+		&token.ID, &token.UserID, &token.Name, &token.Prefix, &token.Scopes, &token.CreatedAt, &token.ExpiresAt, &token.LastUsedAt, &stale,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if stale {
+		if _, err := r.DB.Exec(ctx, TouchAccessTokenQuery, token.ID); err != nil {
+			return nil, err
+		}
+	}
+	return &token, nil
+}
+
+// RevokeAccessToken deletes one of the user's access tokens and reports whether it existed.
+func (r *PostgresRepository) RevokeAccessToken(ctx context.Context, userID, tokenID uint) (bool, error) {
+	tag, err := r.DB.Exec(ctx, RevokeAccessTokenQuery, tokenID, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// RevokeAccessTokens deletes all access tokens of the user and returns their IDs.
+func (r *PostgresRepository) RevokeAccessTokens(ctx context.Context, userID uint) ([]uint, error) {
+	rows, err := r.DB.Query(ctx, RevokeAccessTokensQuery, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revoked := []uint{}
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		revoked = append(revoked, id)
+	}
+	return revoked, rows.Err()
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kontentski/chat/internal/models"
+)
+
+// GetTOTP returns the user's authenticator secret, or pgx.ErrNoRows if they never started enrolling.
+func (r *PostgresRepository) GetTOTP(ctx context.Context, userID uint) (*models.TOTP, error) {
+	var totp models.TOTP
+	err := r.DB.QueryRow(ctx, GetTOTPQuery, userID).
+		Scan(&totp.UserID, &totp.Secret, &totp.LastUsedStep, &totp.ConfirmedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &totp, nil
+}
+
+// SaveTOTPSecret stores an unconfirmed secret for the user, replacing an earlier unconfirmed one.
+// It does not touch a confirmed secret.
+func (r *PostgresRepository) SaveTOTPSecret(ctx context.Context, userID uint, secret string) error {
+	_, err := r.DB.Exec(ctx, SaveTOTPSecretQuery, userID, secret)
+	return err
+}
+
+// UseTOTPStep records that a code of the time step was accepted. It returns false if that step or
+// a later one was already used.
+func (r *PostgresRepository) UseTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	tag, err := r.DB.Exec(ctx, UseTOTPStepQuery, userID, step)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ConfirmTOTP turns two-factor authentication on with the code of the time step and stores the
+// hashes of a fresh set of recovery codes. It returns false if the secret was already confirmed.
+func (r *PostgresRepository) ConfirmTOTP(ctx context.Context, userID uint, step int64, recoveryCodes []string) (bool, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, ConfirmTOTPQuery, userID, step)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryCodes); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// ReplaceRecoveryCodes discards the user's recovery codes and stores the hashes of new ones.
+func (r *PostgresRepository) ReplaceRecoveryCodes(ctx context.Context, userID uint, recoveryCodes []string) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryCodes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID uint, recoveryCodes []string) error {
+	if _, err := tx.Exec(ctx, DeleteRecoveryCodesQuery, userID); err != nil {
+		return err
+	}
+	for _, code := range recoveryCodes {
+		if _, err := tx.Exec(ctx, CreateRecoveryCodeQuery, userID, hashToken(code)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code of the user as used. It returns false if there is
+// no such code.
+func (r *PostgresRepository) UseRecoveryCode(ctx context.Context, userID uint, code string) (bool, error) {
+	tag, err := r.DB.Exec(ctx, UseRecoveryCodeQuery, userID, hashToken(code))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *PostgresRepository) CountRecoveryCodes(ctx context.Context, userID uint) (int, error) {
+	var count int
+	err := r.DB.QueryRow(ctx, CountRecoveryCodesQuery, userID).Scan(&count)
+	return count, err
+}
+
+// DeleteTOTP turns two-factor authentication off and discards the user's recovery codes.
+func (r *PostgresRepository) DeleteTOTP(ctx context.Context, userID uint) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, DeleteRecoveryCodesQuery, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, DeleteTOTPQuery, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
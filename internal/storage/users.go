@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/kontentski/chat/internal/models"
+)
+
+// GetUserByUsername returns the user with the exact username, including the password hash,
+// or pgx.ErrNoRows if there is none.
+func (r *PostgresRepository) GetUserByUsername(ctx context.Context, username string) (*models.Users, error) {
+	return scanUser(r.DB.QueryRow(ctx, GetUserByUsernameQuery, username))
+}
+
+// GetUserByID returns the user with the ID, including the password hash, or pgx.ErrNoRows if there is none.
+func (r *PostgresRepository) GetUserByID(ctx context.Context, userID uint) (*models.Users, error) {
+	return scanUser(r.DB.QueryRow(ctx, GetUserByIDQuery, userID))
+}
+
+// GetUserByEmail returns the user with the email, compared case-insensitively, or pgx.ErrNoRows if there is none.
+func (r *PostgresRepository) GetUserByEmail(ctx context.Context, email string) (*models.Users, error) {
+	return scanUser(r.DB.QueryRow(ctx, GetUserByEmailQuery, email))
+}
+
+// UpdatePassword replaces the user's password hash if it still is oldHash, and reports whether it did.
+func (r *PostgresRepository) UpdatePassword(ctx context.Context, userID uint, oldHash, newHash string) (bool, error) {
+	tag, err := r.DB.Exec(ctx, UpdatePasswordQuery, userID, oldHash, newHash)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// MarkEmailVerified records that the user controls email, if it still is their address.
+func (r *PostgresRepository) MarkEmailVerified(ctx context.Context, userID uint, email string) error {
+	_, err := r.DB.Exec(ctx, MarkEmailVerifiedQuery, userID, email)
+	return err
+}
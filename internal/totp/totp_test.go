@@ -0,0 +1,70 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rfcSecret is the SHA1 key of the RFC 6238 test vectors.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeMatchesRFC6238(t *testing.T) {
+	// The RFC lists eight digit codes, these are their last six digits.
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range vectors {
+		got, err := Code(rfcSecret, time.Unix(unix, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, "time %d", unix)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	current := Step(now)
+
+	step, ok := Verify(rfcSecret, "081804", now, 0)
+	assert.True(t, ok)
+	assert.Equal(t, current, step)
+
+	// The previous and next periods are accepted for clock drift, older ones are not.
+	previous, _ := Code(rfcSecret, now.Add(-Period))
+	_, ok = Verify(rfcSecret, previous, now, 0)
+	assert.True(t, ok)
+	stale, _ := Code(rfcSecret, now.Add(-2*Period))
+	_, ok = Verify(rfcSecret, stale, now, 0)
+	assert.False(t, ok)
+
+	// A code cannot be replayed once its step was used.
+	_, ok = Verify(rfcSecret, "081804", now, current)
+	assert.False(t, ok)
+
+	_, ok = Verify(rfcSecret, "081 804", now, 0)
+	assert.True(t, ok)
+	_, ok = Verify(rfcSecret, "000000", now, 0)
+	assert.False(t, ok)
+	_, ok = Verify("not base32!", "081804", now, 0)
+	assert.False(t, ok)
+}
+
+func TestProvisioningURI(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	uri := ProvisioningURI("Chat", "alice smith", secret)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Chat:alice%20smith?"))
+	assert.Contains(t, uri, "secret="+secret)
+	assert.Contains(t, uri, "issuer=Chat")
+	assert.Contains(t, uri, "period=30")
+}
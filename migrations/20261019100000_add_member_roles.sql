@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE chat_room_members ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'member';
+ALTER TABLE chat_room_members ADD COLUMN joined_at TIMESTAMP DEFAULT current_timestamp;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE chat_room_members DROP COLUMN joined_at;
+ALTER TABLE chat_room_members DROP COLUMN role;
+-- +goose StatementEnd
@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE chat_room_members ADD COLUMN muted_until TIMESTAMP NULL;
+
+CREATE TABLE IF NOT EXISTS chat_room_bans (
+    chat_room_id INT,
+    user_id INT,
+    banned_by INT,
+    reason TEXT,
+    created_at TIMESTAMP DEFAULT current_timestamp,
+    PRIMARY KEY (chat_room_id, user_id),
+    FOREIGN KEY (chat_room_id) REFERENCES chat_rooms(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (banned_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS moderation_actions (
+    id SERIAL PRIMARY KEY,
+    chat_room_id INT NOT NULL,
+    moderator_id INT,
+    target_user_id INT NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    reason TEXT,
+    expires_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT current_timestamp,
+    FOREIGN KEY (chat_room_id) REFERENCES chat_rooms(id) ON DELETE CASCADE,
+    FOREIGN KEY (moderator_id) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (target_user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_room ON moderation_actions (chat_room_id, created_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE moderation_actions;
+
+DROP TABLE chat_room_bans;
+
+ALTER TABLE chat_room_members DROP COLUMN muted_until;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE chat_rooms ADD COLUMN slow_mode_seconds INT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_messages_room_sender ON messages (chat_room_id, sender_id, timestamp DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_messages_room_sender;
+
+ALTER TABLE chat_rooms DROP COLUMN slow_mode_seconds;
+-- +goose StatementEnd
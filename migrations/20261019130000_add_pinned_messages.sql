@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS pinned_messages (
+    chat_room_id INT,
+    message_id INT,
+    pinned_by INT,
+    pinned_at TIMESTAMP DEFAULT current_timestamp,
+    PRIMARY KEY (chat_room_id, message_id),
+    FOREIGN KEY (message_id, chat_room_id) REFERENCES messages(message_id, chat_room_id) ON DELETE CASCADE,
+    FOREIGN KEY (chat_room_id) REFERENCES chat_rooms(id) ON DELETE CASCADE,
+    FOREIGN KEY (pinned_by) REFERENCES users(id) ON DELETE SET NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE pinned_messages;
+-- +goose StatementEnd
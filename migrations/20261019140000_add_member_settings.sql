@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS chat_room_member_settings (
+    chat_room_id INT,
+    user_id INT,
+    muted_until TIMESTAMP NULL,
+    favorite BOOLEAN NOT NULL DEFAULT false,
+    archived BOOLEAN NOT NULL DEFAULT false,
+    notification_level VARCHAR(10) NOT NULL DEFAULT 'all' CHECK (notification_level IN ('all', 'mentions', 'none')),
+    updated_at TIMESTAMP DEFAULT current_timestamp,
+    PRIMARY KEY (chat_room_id, user_id),
+    FOREIGN KEY (chat_room_id, user_id) REFERENCES chat_room_members(chat_room_id, user_id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE chat_room_member_settings;
+-- +goose StatementEnd
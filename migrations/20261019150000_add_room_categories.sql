@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS room_categories (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT current_timestamp,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_room_categories_user ON room_categories (user_id, position);
+
+ALTER TABLE chat_room_member_settings ADD COLUMN category_id INT NULL REFERENCES room_categories(id) ON DELETE SET NULL;
+ALTER TABLE chat_room_member_settings ADD COLUMN position INT NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE chat_room_member_settings DROP COLUMN position;
+ALTER TABLE chat_room_member_settings DROP COLUMN category_id;
+
+DROP TABLE room_categories;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE chat_rooms ADD COLUMN avatar_path TEXT;
+ALTER TABLE chat_rooms ADD COLUMN banner_path TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE chat_rooms DROP COLUMN banner_path;
+ALTER TABLE chat_rooms DROP COLUMN avatar_path;
+-- +goose StatementEnd
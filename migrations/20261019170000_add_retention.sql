@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE chat_rooms ADD COLUMN retention_days INT NULL CHECK (retention_days >= 0);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE chat_rooms DROP COLUMN retention_days;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE chat_rooms ADD COLUMN message_ttl_seconds INT NULL CHECK (message_ttl_seconds > 0);
+ALTER TABLE messages ADD COLUMN expires_at TIMESTAMP NULL;
+CREATE INDEX IF NOT EXISTS idx_messages_expires_at ON messages (expires_at) WHERE expires_at IS NOT NULL AND type IS DISTINCT FROM 'expired';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_messages_expires_at;
+ALTER TABLE messages DROP COLUMN expires_at;
+ALTER TABLE chat_rooms DROP COLUMN message_ttl_seconds;
+-- +goose StatementEnd
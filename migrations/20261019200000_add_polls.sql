@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS polls (
+    id SERIAL PRIMARY KEY,
+    chat_room_id INT NOT NULL,
+    message_id INT NOT NULL,
+    creator_id INT,
+    question TEXT NOT NULL,
+    multiple_choice BOOLEAN NOT NULL DEFAULT FALSE,
+    anonymous BOOLEAN NOT NULL DEFAULT FALSE,
+    closes_at TIMESTAMPTZ NULL,
+    closed_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMP DEFAULT current_timestamp,
+    UNIQUE (chat_room_id, message_id),
+    FOREIGN KEY (message_id, chat_room_id) REFERENCES messages(message_id, chat_room_id) ON DELETE CASCADE,
+    FOREIGN KEY (creator_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS poll_options (
+    id SERIAL PRIMARY KEY,
+    poll_id INT NOT NULL,
+    position INT NOT NULL,
+    text TEXT NOT NULL,
+    FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_poll_options_poll ON poll_options (poll_id, position);
+
+CREATE TABLE IF NOT EXISTS poll_votes (
+    poll_id INT NOT NULL,
+    option_id INT NOT NULL,
+    user_id INT NOT NULL,
+    voted_at TIMESTAMP DEFAULT current_timestamp,
+    PRIMARY KEY (poll_id, option_id, user_id),
+    FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE,
+    FOREIGN KEY (option_id) REFERENCES poll_options(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_poll_votes_user ON poll_votes (poll_id, user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE poll_votes;
+DROP TABLE poll_options;
+DROP TABLE polls;
+-- +goose StatementEnd
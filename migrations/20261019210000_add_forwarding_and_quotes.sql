@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE messages ADD COLUMN forwarded_from_room_id INT NULL;
+ALTER TABLE messages ADD COLUMN forwarded_from_message_id INT NULL;
+ALTER TABLE messages ADD COLUMN forwarded_from_sender_id INT NULL;
+ALTER TABLE messages ADD COLUMN quoted_message_id INT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE messages DROP COLUMN quoted_message_id;
+ALTER TABLE messages DROP COLUMN forwarded_from_sender_id;
+ALTER TABLE messages DROP COLUMN forwarded_from_message_id;
+ALTER TABLE messages DROP COLUMN forwarded_from_room_id;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Media messages hold a bucket path as content, so they are not indexed
+ALTER TABLE messages ADD COLUMN content_tsv tsvector GENERATED ALWAYS AS (
+    CASE WHEN type = 'media' THEN NULL ELSE to_tsvector('simple', COALESCE(content, '')) END
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_messages_content_tsv ON messages USING GIN (content_tsv);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_messages_content_tsv;
+ALTER TABLE messages DROP COLUMN content_tsv;
+-- +goose StatementEnd
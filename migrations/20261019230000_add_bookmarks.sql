@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Bookmarks do not reference messages with a foreign key, so they outlive deleted messages
+CREATE TABLE IF NOT EXISTS bookmarks (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    chat_room_id INT NOT NULL,
+    message_id INT NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT current_timestamp,
+    UNIQUE (user_id, chat_room_id, message_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_bookmarks_user ON bookmarks (user_id, created_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE bookmarks;
+-- +goose StatementEnd
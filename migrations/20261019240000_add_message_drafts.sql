@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS message_drafts (
+    chat_room_id INT,
+    user_id INT,
+    content TEXT NOT NULL,
+    updated_at TIMESTAMP DEFAULT current_timestamp,
+    PRIMARY KEY (chat_room_id, user_id),
+    FOREIGN KEY (chat_room_id, user_id) REFERENCES chat_room_members(chat_room_id, user_id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE message_drafts;
+-- +goose StatementEnd
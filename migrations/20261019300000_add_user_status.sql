@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS user_status (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    text VARCHAR(100) NOT NULL DEFAULT '',
+    emoji VARCHAR(64) NOT NULL DEFAULT '',
+    expires_at TIMESTAMP,
+    dnd_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    dnd_until TIMESTAMP,
+    dnd_start VARCHAR(5) NOT NULL DEFAULT '',
+    dnd_end VARCHAR(5) NOT NULL DEFAULT '',
+    dnd_time_zone VARCHAR(64) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP DEFAULT current_timestamp
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE user_status;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Rooms created before member roles existed have no admin; promote their longest-standing member.
+-- Direct message rooms are left without one.
+UPDATE chat_room_members crm SET role = 'admin'
+FROM (
+    SELECT DISTINCT ON (chat_room_id) chat_room_id, user_id
+    FROM chat_room_members
+    ORDER BY chat_room_id, joined_at, user_id
+) first_member
+WHERE crm.chat_room_id = first_member.chat_room_id
+  AND crm.user_id = first_member.user_id
+  AND NOT EXISTS (SELECT 1 FROM chat_room_members a WHERE a.chat_room_id = crm.chat_room_id AND a.role = 'admin')
+  AND NOT EXISTS (SELECT 1 FROM messages m WHERE m.chat_room_id = crm.chat_room_id AND m.is_dm);
+-- +goose StatementEnd
+
+-- +goose Down
+-- Promoted admins cannot be told apart from ones promoted later, so they are kept.
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A room is a direct message conversation when its messages are marked is_dm
+CREATE INDEX IF NOT EXISTS idx_messages_dm_rooms ON messages (chat_room_id) WHERE is_dm;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_messages_dm_rooms;
+-- +goose StatementEnd
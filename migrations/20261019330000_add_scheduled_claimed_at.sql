@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- claimed_at records when a dispatcher took a message, so a claim left behind by a crash can be retried
+ALTER TABLE scheduled_messages ADD COLUMN IF NOT EXISTS claimed_at TIMESTAMPTZ;
+UPDATE scheduled_messages SET claimed_at = NOW() WHERE status = 'sending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE scheduled_messages DROP COLUMN IF EXISTS claimed_at;
+-- +goose StatementEnd